@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/config"
+	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/repository"
+	services "go/payment-processor/pkg/service"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/joho/godotenv"
+	"go.uber.org/zap"
+)
+
+// apikey issues a merchant API key from the command line, which is how the first key of a merchant is created.
+//
+//	go run ./bin/apikey -merchant 1 -mode test -name "checkout"
+func main() {
+	merchantID := flag.Uint("merchant", 0, "ID of the merchant the key belongs to")
+	mode := flag.String("mode", "test", "key mode, test or live")
+	name := flag.String("name", "", "optional label for the key")
+	flag.Parse()
+
+	config.InitializeLogger()
+	log := config.GetLogger()
+	if err := godotenv.Load(); err != nil {
+		log.Info("Error loading .env file")
+	}
+
+	req := dto.CreateAPIKeyRequest{MerchantID: uint(*merchantID), Name: *name, Mode: *mode}
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil || req.MerchantID == 0 {
+		log.Fatal("Usage: apikey -merchant <id> -mode test|live [-name <label>]", zap.Error(err))
+	}
+
+	config.ConnectDB()
+	db := config.GetDb()
+
+	apiKeyService := services.NewAPIKeyService(log, repository.NewRepository(db, log), validate)
+	apiKey, err := apiKeyService.CreateAPIKey(auth.ContextWithPrincipal(context.Background(), auth.SystemPrincipal("apikey-cli")), &req)
+	if err != nil {
+		log.Fatal("Failed to create API key", zap.Error(err))
+	}
+
+	fmt.Printf("API key for merchant %d (%s): %s\nStore it now, it cannot be shown again.\n", apiKey.MerchantID, apiKey.Mode, apiKey.Key)
+}
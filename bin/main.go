@@ -1,14 +1,11 @@
 package main
 
 import (
-	"github.com/go-playground/validator/v10"
+	"go/payment-processor/pkg/api"
 	"go/payment-processor/pkg/config"
-	handler "go/payment-processor/pkg/handler"
 
 	"github.com/joho/godotenv"
-	"github.com/labstack/echo/v4"
-
-	"github.com/labstack/echo/v4/middleware"
+	"go.uber.org/zap"
 )
 
 func main() {
@@ -22,18 +19,13 @@ func main() {
 	}
 	config.ConnectDB()
 
-	// Initialize Echo
-	e := echo.New()
-
-	e.Use(middleware.Logger())
-	e.Use(middleware.Recover())
-
-	priv := e.Group("/payment-process")
-
-	db := config.GetDb()
-
-	handler.RegisterRoutes(priv, db, log, validator.New())
+	cfg, err := api.ConfigFromEnv(log)
+	if err != nil {
+		log.Fatal("Invalid configuration", zap.Error(err))
+	}
+	cfg.DB = config.GetDb()
 
-	log.Info("Server starting on :8080")
-	e.Logger.Fatal(e.Start(":8080"))
+	if err := api.ListenAndServe(cfg); err != nil {
+		log.Fatal("Server failed", zap.Error(err))
+	}
 }
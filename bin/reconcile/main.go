@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"go/payment-processor/pkg/config"
+	"go/payment-processor/pkg/reconciliation"
+	"go/payment-processor/pkg/repository"
+	services "go/payment-processor/pkg/service"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+	"go.uber.org/zap"
+)
+
+// reconcile matches stored payments against a provider settlement report (CSV) and writes the report as JSON.
+//
+//	go run ./bin/reconcile -report settlement.csv -from 2025-01-01 -to 2025-01-02 [-out report.json] [-auto-correct]
+func main() {
+	reportPath := flag.String("report", "", "path to the provider settlement report (CSV)")
+	fromFlag := flag.String("from", time.Now().AddDate(0, 0, -1).Format(time.DateOnly), "start of the window (inclusive, YYYY-MM-DD)")
+	toFlag := flag.String("to", time.Now().Format(time.DateOnly), "end of the window (exclusive, YYYY-MM-DD)")
+	outPath := flag.String("out", "", "where to write the JSON report (defaults to stdout)")
+	autoCorrect := flag.Bool("auto-correct", false, "update stored statuses that disagree with the provider")
+	flag.Parse()
+
+	config.InitializeLogger()
+	log := config.GetLogger()
+	if err := godotenv.Load(); err != nil {
+		log.Info("Error loading .env file")
+	}
+
+	if *reportPath == "" {
+		log.Fatal("A settlement report is required, pass it with -report")
+	}
+	from, err := time.Parse(time.DateOnly, *fromFlag)
+	if err != nil {
+		log.Fatal("Invalid -from date", zap.Error(err))
+	}
+	to, err := time.Parse(time.DateOnly, *toFlag)
+	if err != nil {
+		log.Fatal("Invalid -to date", zap.Error(err))
+	}
+
+	file, err := os.Open(*reportPath)
+	if err != nil {
+		log.Fatal("Failed to open settlement report", zap.Error(err))
+	}
+	defer file.Close()
+
+	records, err := reconciliation.ParseSettlementCSV(file)
+	if err != nil {
+		log.Fatal("Failed to parse settlement report", zap.Error(err))
+	}
+
+	config.ConnectDB()
+	db := config.GetDb()
+
+	// Settlement reports are matched by provider payment ID, so no live provider is needed here
+	reconciliationService := services.NewReconciliationService(log, repository.NewRepository(db, log), nil)
+	report, err := reconciliationService.ReconcileSettlementReport(context.Background(), records, from, to, *autoCorrect)
+	if err != nil {
+		log.Fatal("Reconciliation failed", zap.Error(err))
+	}
+
+	out := os.Stdout
+	if *outPath != "" {
+		out, err = os.Create(*outPath)
+		if err != nil {
+			log.Fatal("Failed to create report file", zap.Error(err))
+		}
+		defer out.Close()
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal("Failed to write reconciliation report", zap.Error(err))
+	}
+}
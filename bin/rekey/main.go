@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go/payment-processor/pkg/config"
+	"go/payment-processor/pkg/repository"
+	"go/payment-processor/pkg/vault"
+	"os"
+
+	"github.com/joho/godotenv"
+	"go.uber.org/zap"
+)
+
+// rekey re-encrypts the sources of all saved payment methods after the encryption key is rotated. It
+// reads the retired key from PAYMENT_METHOD_PREVIOUS_ENCRYPTION_KEY and the new one from
+// PAYMENT_METHOD_ENCRYPTION_KEY. Run it while the service is stopped, then start the service with the
+// new key only; running it twice is harmless.
+//
+//	PAYMENT_METHOD_PREVIOUS_ENCRYPTION_KEY=<old> PAYMENT_METHOD_ENCRYPTION_KEY=<new> go run ./bin/rekey
+func main() {
+	config.InitializeLogger()
+	log := config.GetLogger()
+	if err := godotenv.Load(); err != nil {
+		log.Info("Error loading .env file")
+	}
+
+	previous, err := vault.NewFromHex(os.Getenv("PAYMENT_METHOD_PREVIOUS_ENCRYPTION_KEY"))
+	if err != nil {
+		log.Fatal("PAYMENT_METHOD_PREVIOUS_ENCRYPTION_KEY must hold the retired key", zap.Error(err))
+	}
+	current, err := vault.NewFromHex(os.Getenv("PAYMENT_METHOD_ENCRYPTION_KEY"))
+	if err != nil {
+		log.Fatal("PAYMENT_METHOD_ENCRYPTION_KEY must hold the new key", zap.Error(err))
+	}
+
+	config.ConnectDB()
+	db := config.GetDb()
+
+	repo := repository.NewRepository(db, log)
+	resealed, err := repo.ReencryptPaymentMethods(context.Background(), func(encryptedSource string) (string, error) {
+		return current.Reseal(previous, encryptedSource)
+	})
+	if err != nil {
+		log.Fatal("Failed to re-encrypt payment methods, nothing was changed", zap.Error(err))
+	}
+
+	fmt.Printf("Re-encrypted %d saved payment methods under the new key.\n", resealed)
+}
@@ -1,9 +1,342 @@
+// Package api builds and runs the payment processor server: the REST API, the gRPC API and the
+// background jobs, wired from a Config.
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	handler "go/payment-processor/pkg/handler"
+	"go/payment-processor/pkg/health"
+	"go/payment-processor/pkg/logging"
+	"go/payment-processor/pkg/metrics"
+	"go/payment-processor/pkg/payments/inflight"
+	"go/payment-processor/pkg/repository"
+	"go/payment-processor/pkg/requestid"
+	"go/payment-processor/pkg/rpc"
+	services "go/payment-processor/pkg/service"
+	"go/payment-processor/pkg/tracing"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
-func ListenAndServe(port string) error {
-	return fmt.Errorf("not implemented")
+// Server is the payment processor with all of its endpoints.
+type Server struct {
+	cfg            Config
+	echo           *echo.Echo
+	grpc           *grpc.Server // nil when gRPC is disabled
+	health         *health.Registry
+	reconciliation services.ReconciliationService // nil without a database
+	stopJobs       context.CancelFunc             // stops the background jobs once they run
+	stopping       chan struct{}                  // closed when the shutdown starts
+	stopOnce       sync.Once
+}
+
+// New builds the server without starting it.
+func New(cfg Config) (*Server, error) {
+	if cfg.Logger == nil {
+		return nil, errors.New("api: a logger is required")
+	}
+	if cfg.DB == nil && cfg.Services == nil {
+		return nil, errors.New("api: a database is required")
+	}
+	if cfg.Addr == "" {
+		cfg.Addr = defaultAddr
+	}
+	if cfg.BasePath == "" {
+		cfg.BasePath = defaultBasePath
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = defaultShutdownTimeout
+	}
+	if cfg.Dependencies.InFlight == nil {
+		cfg.Dependencies.InFlight = inflight.NewTracker()
+	}
+
+	validate := validator.New()
+	svc := cfg.Services
+	if svc == nil {
+		built := handler.NewServices(cfg.DB, cfg.Logger, validate, cfg.Dependencies)
+		svc = &built
+	}
+
+	// Subsystems register what the server needs to be ready
+	registry := health.NewRegistry(0)
+	if cfg.DB != nil {
+		repository.RegisterHealthChecks(registry, cfg.DB)
+	}
+	if pinger, ok := cfg.Dependencies.Gateway.(health.Pinger); ok {
+		registry.Register("provider", health.PingCheck(pinger))
+	}
+
+	s := &Server{cfg: cfg, echo: newEcho(cfg, validate, *svc, registry), health: registry, stopping: make(chan struct{})}
+	if cfg.GRPCAddr != "" {
+		// The REST handlers and the gRPC server share the same services
+		s.grpc = rpc.NewServer(cfg.Logger, validate, rpc.Dependencies{Invoices: svc.Invoices, Payments: svc.Payments,
+			APIKeys: svc.APIKeys, JWTValidator: cfg.Dependencies.JWTValidator, Stopping: s.stopping})
+	}
+	if cfg.DB != nil {
+		s.reconciliation = services.NewReconciliationService(cfg.Logger, repository.NewRepository(cfg.DB, cfg.Logger), cfg.Dependencies.Gateway)
+	}
+	return s, nil
+}
+
+// NewHandler returns the REST API of a server built from cfg.
+func NewHandler(cfg Config) (http.Handler, error) {
+	s, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return s.Handler(), nil
+}
+
+// Handler serves the REST API.
+func (s *Server) Handler() http.Handler {
+	return s.echo
+}
+
+// Health returns the checks behind the readiness and status endpoints, so more can be registered.
+func (s *Server) Health() *health.Registry {
+	return s.health
+}
+
+// ListenAndServe builds a server from cfg and runs it until it fails or is told to stop.
+func ListenAndServe(cfg Config) error {
+	s, err := New(cfg)
+	if err != nil {
+		return err
+	}
+	return s.ListenAndServe()
+}
+
+// ListenAndServe runs the server until it fails or the process receives SIGTERM or SIGINT, then shuts
+// it down gracefully.
+func (s *Server) ListenAndServe() error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	return s.Run(ctx)
+}
+
+// Run starts the background jobs and the gRPC API and serves the REST API until ctx is done or a
+// server fails. Either way the server is then shut down within the shutdown timeout.
+func (s *Server) Run(ctx context.Context) error {
+	log := s.cfg.Logger
+
+	jobs, stopJobs := context.WithCancel(context.Background())
+	s.stopJobs = stopJobs
+	if s.cfg.ReconciliationInterval > 0 && s.reconciliation != nil {
+		go s.reconciliation.RunScheduled(jobs, s.cfg.ReconciliationInterval, s.cfg.ReconciliationAutoCorrect)
+		log.Info("Reconciliation job scheduled", zap.Duration("interval", s.cfg.ReconciliationInterval),
+			zap.Bool("auto_correct", s.cfg.ReconciliationAutoCorrect))
+	}
+
+	failed := make(chan error, 2)
+	if s.grpc != nil {
+		listener, err := net.Listen("tcp", s.cfg.GRPCAddr)
+		if err != nil {
+			stopJobs()
+			return fmt.Errorf("api: listen for gRPC on %s: %w", s.cfg.GRPCAddr, err)
+		}
+		go func() {
+			if err := s.grpc.Serve(listener); err != nil {
+				failed <- fmt.Errorf("api: serve gRPC: %w", err)
+			}
+		}()
+		log.Info("gRPC server starting on " + s.cfg.GRPCAddr)
+	}
+
+	log.Info("Server starting on " + s.cfg.Addr)
+	go func() {
+		if err := s.echo.Start(s.cfg.Addr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			failed <- err
+		}
+	}()
+
+	var err error
+	select {
+	case <-ctx.Done():
+		log.Info("Shutdown requested")
+	case err = <-failed:
+		log.Error("Server failed, shutting down", zap.Error(err))
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	return errors.Join(err, s.Shutdown(shutdownCtx))
+}
+
+// Shutdown stops accepting requests and waits until ctx is done for the requests and payments in
+// flight. Payments still waiting for the provider are then marked for reconciliation, because the
+// provider may have charged them. Finally the database is closed and the logs are flushed.
+func (s *Server) Shutdown(ctx context.Context) error {
+	log := s.cfg.Logger
+	inFlight := s.cfg.Dependencies.InFlight
+	log.Info("Shutting down", zap.Int("payments_in_flight", inFlight.Len()))
+	s.stopOnce.Do(func() { close(s.stopping) })
+
+	var errs []error
+	grpcStopped := make(chan struct{})
+	if s.grpc != nil {
+		go func() {
+			s.grpc.GracefulStop()
+			close(grpcStopped)
+		}()
+	} else {
+		close(grpcStopped)
+	}
+	if err := s.echo.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("api: shut down REST API: %w", err))
+	}
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		// Cancels the calls still running
+		s.grpc.Stop()
+		<-grpcStopped
+	}
+	if s.stopJobs != nil {
+		s.stopJobs()
+	}
+
+	if interrupted := inFlight.Wait(ctx); len(interrupted) > 0 {
+		log.Warn("Payments still in flight at the shutdown deadline", zap.Int("payments", len(interrupted)))
+		// The deadline has passed, but the payments must still be flagged before the database closes
+		if s.reconciliation == nil {
+			errs = append(errs, fmt.Errorf("api: interrupted payments not marked for reconciliation without a database: %d", len(interrupted)))
+		} else if err := s.reconciliation.MarkInterrupted(context.WithoutCancel(ctx), interrupted); err != nil {
+			errs = append(errs, fmt.Errorf("api: mark interrupted payments: %w", err))
+		}
+	}
+
+	if s.cfg.DB != nil {
+		sqlDB, err := s.cfg.DB.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("api: close database: %w", err))
+		}
+	}
+
+	if err := s.cfg.Tracing.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("api: flush spans: %w", err))
+	}
+
+	log.Info("Shutdown complete")
+	// Syncing a terminal fails on some platforms, there is nothing left to report it to
+	_ = log.Sync()
+	return errors.Join(errs...)
+}
+
+// newEcho registers the middleware and routes of the REST API
+func newEcho(cfg Config, validate *validator.Validate, svc handler.Services, registry *health.Registry) *echo.Echo {
+	e := echo.New()
+	// Only trust X-Forwarded-For from proxies on private networks, so clients cannot pick their rate limit IP
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
+	// Render every error as problem details with a machine-readable code
+	e.HTTPErrorHandler = handler.ErrorHandler(cfg.Logger)
+
+	// Tag every request with an ID and make it available to the audit log and the logs
+	e.Use(correlateRequests())
+	e.Use(traceRequests())
+	if m := cfg.Dependencies.Metrics; m != nil {
+		e.Use(observeRequests(m))
+		e.GET("/metrics", echo.WrapHandler(m.Handler()))
+	}
+	// Probes and scrapes run every few seconds and would drown the access log
+	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{Skipper: func(c echo.Context) bool {
+		return c.Path() == "/healthz" || c.Path() == "/readyz" || c.Path() == "/metrics"
+	}}))
+	e.Use(middleware.Recover())
+
+	// Probes for the orchestrator and a detailed status for operators, outside the authenticated API
+	e.GET("/healthz", handler.ServeLiveness())
+	e.GET("/readyz", handler.ServeReadiness(registry))
+	e.GET("/status", handler.ServeStatus(registry, health.ReadBuildInfo(), time.Now()))
+
+	// Machine-readable contract of the API, generated from the routes and DTOs
+	e.GET("/openapi.json", handler.ServeOpenAPI(cfg.BasePath))
+	handler.Register(e.Group(cfg.BasePath), handler.NewHandlerWithServices(cfg.Logger, validate, svc, cfg.Dependencies))
+	return e
+}
+
+// correlateRequests tags every request with the X-Request-ID sent by the client, or a new ID when it
+// sent none or an unusable one. The ID is returned in the response header and carried by the request
+// context, for the audit log, the error bodies and as the request_id field of the request's logs.
+func correlateRequests() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := req.Header.Get(requestid.Header)
+			if !requestid.Valid(id) {
+				id = requestid.New()
+			}
+			c.Response().Header().Set(requestid.Header, id)
+			ctx := logging.With(requestid.NewContext(req.Context(), id), zap.String("request_id", id))
+			c.SetRequest(req.WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// observeRequests records the latency of every request under its route pattern. Errors are rendered
+// here so the recorded status is the one sent to the client.
+func observeRequests(m *metrics.Metrics) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+			m.ObserveHTTPRequest(c.Request().Method, c.Path(), c.Response().Status, time.Since(start))
+			return nil
+		}
+	}
+}
+
+// traceRequests starts a server span for every request, continuing the trace of the caller's W3C
+// traceparent header. It is the parent of the spans of the services, the database and the provider.
+func traceRequests() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			name := req.Method
+			if c.Path() != "" {
+				name += " " + c.Path()
+			}
+			ctx, span := tracing.Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(req.Method), semconv.HTTPRoute(c.Path()), semconv.URLPath(req.URL.Path),
+				attribute.String("request.id", requestid.FromContext(ctx))))
+			defer span.End()
+			c.SetRequest(req.WithContext(ctx))
+
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+			status := c.Response().Status
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			return nil
+		}
+	}
 }
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/entities"
+	handler "go/payment-processor/pkg/handler"
+	"go/payment-processor/pkg/health"
+	"go/payment-processor/pkg/metrics"
+	"go/payment-processor/pkg/payments/inflight"
+	"go/payment-processor/pkg/payments/provider"
+	"go/payment-processor/pkg/requestid"
+	services "go/payment-processor/pkg/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type fakeAPIKeyService struct{ services.APIKeyService }
+
+func (fakeAPIKeyService) Authenticate(context.Context, string) (*auth.Principal, error) {
+	return nil, services.ErrInvalidAPIKey
+}
+
+func newTestHandler(t *testing.T) http.Handler {
+	h, err := NewHandler(Config{Logger: zap.NewNop(), Services: &handler.Services{APIKeys: fakeAPIKeyService{}}})
+	assert.NoError(t, err)
+	return h
+}
+
+func TestNewRequiresADatabase(t *testing.T) {
+	_, err := New(Config{Logger: zap.NewNop()})
+	assert.Error(t, err)
+}
+
+func TestHandlerServesTheAPI(t *testing.T) {
+	h := newTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get(echo.HeaderXRequestID))
+
+	tests := map[string]struct {
+		target string
+		key    string
+		status int
+		code   string
+	}{
+		"unknown route":   {"/payment-process/unknown", "", http.StatusNotFound, apperror.ErrNotFound.Code},
+		"missing API key": {"/payment-process/invoices/1", "", http.StatusUnauthorized, apperror.ErrUnauthorized.Code},
+		"invalid API key": {"/payment-process/invoices/1", "sk_test_unknown", http.StatusUnauthorized, apperror.ErrUnauthorized.Code},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			var problem apperror.Problem
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, apperror.ProblemContentType, rec.Header().Get(echo.HeaderContentType))
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			assert.Equal(t, tt.code, problem.Code)
+			assert.Equal(t, rec.Header().Get(echo.HeaderXRequestID), problem.RequestID)
+		})
+	}
+}
+
+func TestRunShutsDownWhenCancelled(t *testing.T) {
+	tracker := inflight.NewTracker()
+	s, err := New(Config{Addr: "127.0.0.1:0", GRPCAddr: "127.0.0.1:0", Logger: zap.NewNop(), ShutdownTimeout: 50 * time.Millisecond,
+		Services: &handler.Services{APIKeys: fakeAPIKeyService{}}, Dependencies: handler.Dependencies{InFlight: tracker}})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+}
+
+func TestShutdownReportsInterruptedPayments(t *testing.T) {
+	tracker := inflight.NewTracker()
+	s, err := New(Config{Logger: zap.NewNop(), Services: &handler.Services{APIKeys: fakeAPIKeyService{}},
+		Dependencies: handler.Dependencies{InFlight: tracker}})
+	assert.NoError(t, err)
+
+	// The payment never finishes, so it is still in flight at the deadline
+	tracker.Begin(entities.Payment{ReferenceID: "ref-1"})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// Without a database the payment cannot be marked for reconciliation
+	assert.ErrorContains(t, s.Shutdown(ctx), "interrupted payments not marked")
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	s, err := New(Config{Logger: zap.NewNop(), Services: &handler.Services{APIKeys: fakeAPIKeyService{}},
+		Dependencies: handler.Dependencies{Gateway: provider.New()}})
+	assert.NoError(t, err)
+
+	get := func(target string) (int, map[string]any) {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return rec.Code, body
+	}
+
+	code, body := get("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusOK, body["status"])
+
+	code, body = get("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, body["checks"], 1) // the provider
+
+	s.Health().Register("cache", func(context.Context) error { return errors.New("unreachable") })
+	code, body = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusFailing, body["status"])
+
+	// Liveness does not depend on the checks
+	code, _ = get("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, body = get("/status")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusFailing, body["status"])
+	assert.Equal(t, health.Version, body["build"].(map[string]any)["version"])
+	assert.Len(t, body["checks"], 2)
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	h, err := NewHandler(Config{Logger: zap.NewNop(), Services: &handler.Services{APIKeys: fakeAPIKeyService{}},
+		Dependencies: handler.Dependencies{Metrics: metrics.New()}})
+	assert.NoError(t, err)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/payment-process/invoices/1", nil))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	// Requests are recorded under their route with the status the error handler sent
+	assert.Contains(t, rec.Body.String(), `route="/payment-process/invoices/:id",status="401"`)
+}
+
+func TestRequestsContinueTheCallersTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	h := newTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/payment-process/invoices/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		span := spans[0]
+		assert.Equal(t, "GET /payment-process/invoices/:id", span.Name())
+		assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	}
+}
+
+func TestRequestIDIsReturnedAndLogged(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	h, err := NewHandler(Config{Logger: zap.New(core), Services: &handler.Services{APIKeys: fakeAPIKeyService{}}})
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		sent     string
+		accepted bool
+	}{
+		"sent by the client": {"req-01HZX", true},
+		"none sent":          {"", false},
+		"unusable":           {"req 1\n", false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/payment-process/invoices/1", nil)
+			req.Header.Set(echo.HeaderXRequestID, tt.sent)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(echo.HeaderXRequestID)
+			if tt.accepted {
+				assert.Equal(t, tt.sent, id)
+			} else {
+				assert.True(t, requestid.Valid(id))
+				assert.NotEqual(t, tt.sent, id)
+			}
+			var problem apperror.Problem
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			assert.Equal(t, id, problem.RequestID)
+			rejected := logs.FilterMessage("Request rejected").FilterField(zap.String("request_id", id)).Len()
+			assert.Equal(t, 1, rejected)
+		})
+	}
+}
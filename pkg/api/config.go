@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/config"
+	handler "go/payment-processor/pkg/handler"
+	"go/payment-processor/pkg/idempotency"
+	"go/payment-processor/pkg/metrics"
+	"go/payment-processor/pkg/payments/inflight"
+	"go/payment-processor/pkg/payments/provider"
+	"go/payment-processor/pkg/ratelimit"
+	"go/payment-processor/pkg/risk"
+	"go/payment-processor/pkg/tracing"
+	"go/payment-processor/pkg/vault"
+	"go/payment-processor/pkg/velocity"
+	"os"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAddr            = ":8080"
+	defaultBasePath        = "/payment-process"
+	defaultShutdownTimeout = 30 * time.Second
+)
+
+// Config configures the server. DB and Logger are required; the collaborators in Dependencies
+// that are left nil disable their feature, as documented on handler.Dependencies.
+type Config struct {
+	Addr     string // address of the REST API, defaults to ":8080"
+	GRPCAddr string // address of the gRPC API, empty disables it
+	BasePath string // prefix of the REST routes, defaults to "/payment-process"
+
+	DB           *gorm.DB
+	Logger       *zap.Logger
+	Dependencies handler.Dependencies
+	// Services replace the services built on top of DB, so tests can serve the API without a database
+	Services *handler.Services
+	// Tracing exports the spans of the server and is flushed on shutdown; nil records no spans
+	Tracing *tracing.Provider
+
+	ReconciliationInterval    time.Duration // zero disables the scheduled reconciliation
+	ReconciliationAutoCorrect bool
+
+	// ShutdownTimeout bounds how long a shutdown waits for in-flight requests and payments, defaults to 30s.
+	// Payments still running after it are marked for reconciliation.
+	ShutdownTimeout time.Duration
+}
+
+// ConfigFromEnv reads the configuration from the environment. Features that are not configured
+// are disabled with a warning; invalid settings are errors.
+func ConfigFromEnv(logger *zap.Logger) (Config, error) {
+	cfg := Config{
+		Addr:     getEnvOrDefault("HTTP_ADDR", defaultAddr),
+		GRPCAddr: os.Getenv("GRPC_ADDR"),
+		BasePath: defaultBasePath,
+		Logger:   logger,
+	}
+	deps := &cfg.Dependencies
+	deps.Gateway = provider.New()
+	deps.InFlight = inflight.NewTracker()
+
+	var err error
+	// Saved payment methods are only available when an encryption key is configured
+	if key := os.Getenv("PAYMENT_METHOD_ENCRYPTION_KEY"); key != "" {
+		if deps.Vault, err = vault.NewFromHex(key); err != nil {
+			return cfg, fmt.Errorf("PAYMENT_METHOD_ENCRYPTION_KEY: %w", err)
+		}
+	} else {
+		logger.Warn("PAYMENT_METHOD_ENCRYPTION_KEY is not set, saved payment methods are disabled")
+	}
+	// Payment sources are fingerprinted with a key of their own, kept in the secret store with the
+	// encryption key
+	if key := os.Getenv("PAYMENT_SOURCE_FINGERPRINT_KEY"); key != "" {
+		if deps.Fingerprints, err = vault.NewFingerprinterFromHex(key); err != nil {
+			return cfg, fmt.Errorf("PAYMENT_SOURCE_FINGERPRINT_KEY: %w", err)
+		}
+	} else {
+		logger.Warn("PAYMENT_SOURCE_FINGERPRINT_KEY is not set, payment sources are not recognised across restarts")
+		deps.Fingerprints = vault.NewEphemeralFingerprinter()
+	}
+
+	// Internal services authenticate with JWTs when an issuer is configured
+	if jwtConfig, ok := config.GetJWTConfig(); ok {
+		if deps.JWTValidator, err = auth.NewJWTValidator(jwtConfig); err != nil {
+			return cfg, fmt.Errorf("invalid JWT configuration: %w", err)
+		}
+		if issuerConfig, ok := config.GetTokenIssuerConfig(); ok {
+			if deps.TokenIssuer, err = auth.NewTokenIssuer(issuerConfig); err != nil {
+				return cfg, fmt.Errorf("invalid token issuer configuration: %w", err)
+			}
+			deps.JWTValidator.TrustKey(deps.TokenIssuer.KeyID(), deps.TokenIssuer.PublicKey())
+			logger.Warn("Local token issuer enabled, do not use outside test environments")
+		}
+	}
+
+	riskConfig, ok, err := config.GetRiskConfig()
+	if err != nil {
+		return cfg, fmt.Errorf("invalid risk configuration: %w", err)
+	}
+	if ok {
+		deps.RiskEngine = risk.NewEngine(riskConfig)
+	} else {
+		logger.Warn("Risk engine is disabled, payments are sent to the provider unchecked")
+	}
+
+	velocityConfig, ok, err := config.GetVelocityConfig()
+	if err != nil {
+		return cfg, fmt.Errorf("invalid velocity configuration: %w", err)
+	}
+	if ok {
+		deps.Velocity = velocity.NewGuard(velocity.NewMemoryStore(), velocityConfig)
+	} else {
+		logger.Warn("Velocity limits are disabled, repeated declines are not throttled")
+	}
+
+	rateLimitConfig, ok, err := config.GetRateLimitConfig()
+	if err != nil {
+		return cfg, fmt.Errorf("invalid rate limit configuration: %w", err)
+	}
+	if ok {
+		deps.RateLimiter = ratelimit.NewLimiter(ratelimit.NewMemoryStore(), rateLimitConfig)
+	} else {
+		logger.Warn("Rate limiting is disabled")
+	}
+
+	idempotencyKeyTTL, ok, err := config.GetIdempotencyKeyTTL()
+	if err != nil {
+		return cfg, fmt.Errorf("invalid idempotency configuration: %w", err)
+	}
+	if ok {
+		deps.Idempotency = idempotency.NewMemoryStore(idempotencyKeyTTL)
+	} else {
+		logger.Warn("Idempotency keys are disabled, retried requests are processed again")
+	}
+
+	// Metrics are served on /metrics unless disabled; database queries are observed through the GORM logger
+	if disabled, _ := strconv.ParseBool(os.Getenv("METRICS_DISABLED")); disabled {
+		logger.Warn("Metrics are disabled")
+	} else {
+		deps.Metrics = metrics.New()
+		config.SetQueryMetrics(deps.Metrics)
+	}
+
+	// Spans are exported when an exporter is configured; the OTLP exporter reads its endpoint from
+	// the standard OTEL_EXPORTER_OTLP_* variables
+	exporter, ok, err := config.GetTraceExporter()
+	if err != nil {
+		return cfg, err
+	}
+	if ok {
+		if cfg.Tracing, err = tracing.New(context.Background(), exporter); err != nil {
+			return cfg, fmt.Errorf("invalid tracing configuration: %w", err)
+		}
+	} else {
+		logger.Warn("Tracing is disabled, set OTEL_TRACES_EXPORTER to export spans")
+	}
+
+	// Reconcile stored payments against the provider in the background when an interval is configured
+	if raw := os.Getenv("RECONCILIATION_INTERVAL"); raw != "" {
+		if cfg.ReconciliationInterval, err = time.ParseDuration(raw); err != nil {
+			return cfg, fmt.Errorf("RECONCILIATION_INTERVAL: %w", err)
+		}
+		cfg.ReconciliationAutoCorrect, _ = strconv.ParseBool(os.Getenv("RECONCILIATION_AUTO_CORRECT"))
+	}
+
+	cfg.ShutdownTimeout = defaultShutdownTimeout
+	if raw := os.Getenv("SHUTDOWN_TIMEOUT"); raw != "" {
+		if cfg.ShutdownTimeout, err = time.ParseDuration(raw); err != nil || cfg.ShutdownTimeout <= 0 {
+			return cfg, fmt.Errorf("SHUTDOWN_TIMEOUT: must be a positive duration, got %q", raw)
+		}
+	}
+	return cfg, nil
+}
+
+func getEnvOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,177 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// problemTypeBase prefixes the code of an error to form its problem type URI
+const problemTypeBase = "urn:payment-processor:problem:"
+
+// Error is an error with a machine-readable code and the HTTP status it maps to. Errors with the
+// same code match with errors.Is, so sentinel errors can be returned with a more specific message,
+// a cause or details.
+type Error struct {
+	Status      int
+	Code        string
+	Message     string
+	DeclineCode string       // normalized reason a payment was declined
+	Fields      []FieldError // invalid request fields
+	RetryAfter  time.Duration
+	cause       error
+}
+
+// FieldError describes a single invalid request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Generic errors, used when nothing more specific applies
+var (
+	ErrInvalidRequest   = New(http.StatusBadRequest, "invalid_request", "Invalid request")
+	ErrValidation       = New(http.StatusBadRequest, "validation_failed", "Validation failed")
+	ErrUnauthorized     = New(http.StatusUnauthorized, "unauthorized", "Missing or invalid credentials")
+	ErrForbidden        = New(http.StatusForbidden, "forbidden", "Forbidden")
+	ErrNotFound         = New(http.StatusNotFound, "not_found", "Not found")
+	ErrMethodNotAllowed = New(http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+	ErrConflict         = New(http.StatusConflict, "conflict", "Conflict")
+	ErrTooLarge         = New(http.StatusRequestEntityTooLarge, "payload_too_large", "Payload too large")
+	ErrRateLimited      = New(http.StatusTooManyRequests, "rate_limited", "Rate limit exceeded")
+	ErrInternal         = New(http.StatusInternalServerError, "internal_error", "Internal server error")
+	ErrUnavailable      = New(http.StatusServiceUnavailable, "service_unavailable", "Service unavailable")
+)
+
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches errors by code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithMessage returns a copy of the error with another message.
+func (e *Error) WithMessage(message string) *Error {
+	copied := *e
+	copied.Message = message
+	return &copied
+}
+
+// WithCause returns a copy of the error wrapping cause. The cause is logged but never sent to clients.
+func (e *Error) WithCause(cause error) *Error {
+	copied := *e
+	copied.cause = cause
+	return &copied
+}
+
+// WithDeclineCode returns a copy of the error carrying a decline code.
+func (e *Error) WithDeclineCode(declineCode string) *Error {
+	copied := *e
+	copied.DeclineCode = declineCode
+	return &copied
+}
+
+// WithFields returns a copy of the error listing invalid fields.
+func (e *Error) WithFields(fields ...FieldError) *Error {
+	copied := *e
+	copied.Fields = append(append([]FieldError(nil), e.Fields...), fields...)
+	return &copied
+}
+
+// WithRetryAfter returns a copy of the error telling clients when to retry.
+func (e *Error) WithRetryAfter(retryAfter time.Duration) *Error {
+	copied := *e
+	copied.RetryAfter = retryAfter
+	return &copied
+}
+
+// From returns err as an *Error. Errors without a code become ErrInternal wrapping err.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return Validation(err)
+	}
+	return ErrInternal.WithCause(err)
+}
+
+// Validation converts the errors of go-playground/validator into ErrValidation with one field error
+// per failed rule. Other errors become a plain ErrValidation.
+func Validation(err error) *Error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return ErrValidation.WithMessage(err.Error()).WithCause(err)
+	}
+
+	fields := make([]FieldError, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		fields = append(fields, FieldError{Field: fieldErr.Field(), Code: fieldErr.Tag(), Message: fieldErr.Error()})
+	}
+	return ErrValidation.WithFields(fields...).WithCause(err)
+}
+
+// Problem is an RFC 7807 problem details body, extended with the error code, decline code,
+// invalid fields and the ID of the request.
+type Problem struct {
+	Type        string       `json:"type"`
+	Title       string       `json:"title"`
+	Status      int          `json:"status"`
+	Detail      string       `json:"detail,omitempty"`
+	Instance    string       `json:"instance,omitempty"`
+	Code        string       `json:"code"`
+	DeclineCode string       `json:"decline_code,omitempty"`
+	Errors      []FieldError `json:"errors,omitempty"`
+	RequestID   string       `json:"request_id,omitempty"`
+}
+
+// Problem describes the error as problem details for the request path and ID.
+func (e *Error) Problem(instance, requestID string) Problem {
+	return Problem{
+		Type:        problemTypeBase + e.Code,
+		Title:       http.StatusText(e.Status),
+		Status:      e.Status,
+		Detail:      e.Message,
+		Instance:    instance,
+		Code:        e.Code,
+		DeclineCode: e.DeclineCode,
+		Errors:      e.Fields,
+		RequestID:   requestID,
+	}
+}
+
+// FromStatus returns the generic error for an HTTP status.
+func FromStatus(status int) *Error {
+	for _, generic := range []*Error{ErrInvalidRequest, ErrUnauthorized, ErrForbidden, ErrNotFound, ErrMethodNotAllowed,
+		ErrConflict, ErrTooLarge, ErrRateLimited, ErrUnavailable} {
+		if generic.Status == status {
+			return generic
+		}
+	}
+	if status >= http.StatusInternalServerError {
+		return New(status, ErrInternal.Code, http.StatusText(status))
+	}
+	return New(status, ErrInvalidRequest.Code, http.StatusText(status))
+}
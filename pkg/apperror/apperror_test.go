@@ -0,0 +1,59 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorsMatchByCode(t *testing.T) {
+	errAlreadyPaid := New(http.StatusConflict, "invoice_already_paid", "invoice is already paid")
+	cause := errors.New("row locked")
+
+	err := fmt.Errorf("paying invoice: %w", errAlreadyPaid.WithMessage("invoice 7 is already paid").WithCause(cause))
+	assert.ErrorIs(t, err, errAlreadyPaid)
+	assert.ErrorIs(t, err, cause)
+	assert.NotErrorIs(t, err, ErrConflict)
+	assert.Equal(t, "invoice is already paid", errAlreadyPaid.Message, "sentinels are not modified")
+
+	appErr := From(err)
+	assert.Equal(t, http.StatusConflict, appErr.Status)
+	assert.Equal(t, "invoice 7 is already paid", appErr.Message)
+}
+
+func TestFromWrapsUnknownErrors(t *testing.T) {
+	appErr := From(errors.New("connection refused"))
+	assert.Equal(t, http.StatusInternalServerError, appErr.Status)
+	assert.Equal(t, "internal_error", appErr.Code)
+	assert.Equal(t, "Internal server error", appErr.Problem("/x", "").Detail)
+}
+
+func TestValidationFields(t *testing.T) {
+	type request struct {
+		Currency string `validate:"required,len=3"`
+		Amount   int    `validate:"gt=0"`
+	}
+	appErr := From(validator.New().Struct(request{Currency: "EURO"}))
+
+	assert.Equal(t, http.StatusBadRequest, appErr.Status)
+	assert.Equal(t, "validation_failed", appErr.Code)
+	assert.Equal(t, []string{"Currency", "Amount"}, []string{appErr.Fields[0].Field, appErr.Fields[1].Field})
+	assert.Equal(t, "len", appErr.Fields[0].Code)
+}
+
+func TestProblem(t *testing.T) {
+	problem := ErrNotFound.WithMessage("Invoice not found").Problem("/invoices/9", "req-1")
+	assert.Equal(t, Problem{
+		Type:      "urn:payment-processor:problem:not_found",
+		Title:     "Not Found",
+		Status:    http.StatusNotFound,
+		Detail:    "Invoice not found",
+		Instance:  "/invoices/9",
+		Code:      "not_found",
+		RequestID: "req-1",
+	}, problem)
+}
@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/utils"
+	"time"
+)
+
+// Change is the value of one column before and after a write. Creates have no Before, deletes no After.
+type Change struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// Snapshot holds the JSON encoded column values of one row
+type Snapshot map[string]json.RawMessage
+
+// redactedColumns never reach the audit log in clear
+var redactedColumns = map[string]bool{"payment_source": true}
+
+// EncodeValue encodes a column value for a Snapshot, masking sensitive columns.
+func EncodeValue(column string, value interface{}) (json.RawMessage, error) {
+	if source, ok := value.(string); ok && redactedColumns[column] {
+		value = utils.MaskPaymentSource(source)
+	}
+	return json.Marshal(value)
+}
+
+// Diff returns the columns whose values differ between before and after. Either side may be nil.
+func Diff(before, after Snapshot) map[string]Change {
+	changes := make(map[string]Change)
+	for column, value := range after {
+		if previous, ok := before[column]; !ok || !bytes.Equal(previous, value) {
+			changes[column] = Change{Before: before[column], After: value}
+		}
+	}
+	for column, value := range before {
+		if _, ok := after[column]; !ok {
+			changes[column] = Change{Before: value}
+		}
+	}
+	return changes
+}
+
+// Hash chains the entry to the hash of the entry before it. The database stores timestamps with
+// microsecond precision, so CreatedAt is hashed at that precision.
+func Hash(prevHash string, entry *entities.AuditLog) string {
+	var createdAt string
+	if entry.CreatedAt != nil {
+		createdAt = entry.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+	}
+	payload, _ := json.Marshal([]interface{}{
+		prevHash, entry.EntityType, entry.EntityID, entry.Action, entry.Actor, entry.RequestID, entry.Changes, createdAt,
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify walks entries, ordered by ID, from the hash of the entry preceding them. It returns the
+// hash of the last entry and the first entry that is not correctly chained, or nil if all are.
+func Verify(prevHash string, entries []entities.AuditLog) (string, *entities.AuditLog) {
+	for i := range entries {
+		entry := &entries[i]
+		if entry.PrevHash != prevHash || entry.Hash != Hash(prevHash, entry) {
+			return prevHash, entry
+		}
+		prevHash = entry.Hash
+	}
+	return prevHash, nil
+}
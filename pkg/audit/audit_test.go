@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"encoding/json"
+	"go/payment-processor/pkg/entities"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	before := Snapshot{"invoice_status": json.RawMessage(`"PENDING"`), "amount": json.RawMessage(`"10"`)}
+	after := Snapshot{"invoice_status": json.RawMessage(`"PAID"`), "amount": json.RawMessage(`"10"`)}
+
+	changes := Diff(before, after)
+	assert.Len(t, changes, 1)
+	assert.JSONEq(t, `"PENDING"`, string(changes["invoice_status"].Before))
+	assert.JSONEq(t, `"PAID"`, string(changes["invoice_status"].After))
+
+	created := Diff(nil, after)
+	assert.Len(t, created, 2)
+	assert.Nil(t, created["amount"].Before)
+}
+
+func TestEncodeValueRedactsPaymentSource(t *testing.T) {
+	encoded, err := EncodeValue("payment_source", "4111111111111111")
+	assert.Nil(t, err)
+	assert.NotContains(t, string(encoded), "4111111111111111")
+	assert.Contains(t, string(encoded), "1111")
+}
+
+func chain(n int) []entities.AuditLog {
+	entries := make([]entities.AuditLog, n)
+	prevHash := ""
+	for i := range entries {
+		createdAt := time.Date(2025, 1, 2, 3, 4, 5, 123456789, time.UTC).Add(time.Duration(i) * time.Second)
+		entries[i] = entities.AuditLog{ID: uint(i + 1), EntityType: "invoice", EntityID: 7, Action: "UPDATE",
+			Actor: "api_key:1", Changes: `{"invoice_status":{"before":"PENDING","after":"PAID"}}`, CreatedAt: &createdAt, PrevHash: prevHash}
+		entries[i].Hash = Hash(prevHash, &entries[i])
+		prevHash = entries[i].Hash
+	}
+	return entries
+}
+
+func TestVerify(t *testing.T) {
+	entries := chain(3)
+	last, broken := Verify("", entries)
+	assert.Nil(t, broken)
+	assert.Equal(t, entries[2].Hash, last)
+
+	// Hashing is stable across the microsecond truncation of a database round trip
+	roundTripped := entries[1].CreatedAt.Truncate(time.Microsecond).In(time.FixedZone("CET", 3600))
+	entries[1].CreatedAt = &roundTripped
+	_, broken = Verify("", entries)
+	assert.Nil(t, broken)
+
+	entries[1].Changes = `{"invoice_status":{"before":"PENDING","after":"CHARGED_BACK"}}`
+	_, broken = Verify("", entries)
+	assert.Equal(t, uint(2), broken.ID)
+
+	_, broken = Verify("", append(chain(3)[:1], chain(3)[2:]...))
+	assert.Equal(t, uint(3), broken.ID)
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"go/payment-processor/pkg/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTConfig configures bearer token validation for internal callers.
+type JWTConfig struct {
+	Issuer   string
+	Audience string
+	JWKSFile string // Local JSON Web Key Set holding the RSA keys tokens may be signed with
+}
+
+// Claims are the JWT claims the API reads. Scope is the space separated OAuth2 scope list;
+// MerchantID optionally restricts an internal token to a single merchant.
+type Claims struct {
+	jwt.RegisteredClaims
+	Scope      string   `json:"scope"`
+	Roles      []string `json:"roles,omitempty"`
+	MerchantID uint     `json:"merchant_id,omitempty"`
+	Mode       string   `json:"mode,omitempty"`
+}
+
+// JWTValidator verifies RS256 bearer tokens against a fixed key set.
+type JWTValidator struct {
+	issuer   string
+	audience string
+	keys     map[string]*rsa.PublicKey
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// NewJWTValidator loads the key set from cfg.JWKSFile. Extra keys, such as the one of the
+// local token issuer, can be trusted with TrustKey.
+func NewJWTValidator(cfg JWTConfig) (*JWTValidator, error) {
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, errors.New("jwt issuer and audience must be configured")
+	}
+	validator := &JWTValidator{issuer: cfg.Issuer, audience: cfg.Audience, keys: make(map[string]*rsa.PublicKey)}
+	if cfg.JWKSFile == "" {
+		return validator, nil
+	}
+
+	raw, err := os.ReadFile(cfg.JWKSFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading jwks file: %w", err)
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("parsing jwks file: %w", err)
+	}
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		publicKey, err := parseRSAJWK(key)
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %q: %w", key.Kid, err)
+		}
+		validator.keys[key.Kid] = publicKey
+	}
+	if len(validator.keys) == 0 {
+		return nil, errors.New("jwks file contains no RSA signing keys")
+	}
+	return validator, nil
+}
+
+// TrustKey adds a verification key under the given key ID.
+func (v *JWTValidator) TrustKey(kid string, key *rsa.PublicKey) {
+	v.keys[kid] = key
+}
+
+// Validate verifies the token signature, issuer, audience and lifetime and returns the caller it describes.
+func (v *JWTValidator) Validate(tokenString string) (*Principal, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := v.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		return key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithAudience(v.audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+
+	principal := &Principal{
+		Subject:    claims.Subject,
+		MerchantID: claims.MerchantID,
+		Mode:       claims.Mode,
+		Scopes:     strings.Fields(claims.Scope),
+		Roles:      claims.Roles,
+	}
+	if principal.Mode == "" {
+		principal.Mode = utils.APIKeyModeLive
+	}
+	return principal, nil
+}
+
+func parseRSAJWK(key jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(key.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(key.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 {
+		return nil, errors.New("invalid exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
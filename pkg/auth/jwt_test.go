@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testClients = []OAuthClient{
+	{ClientID: "billing", ClientSecret: "s3cret", Scopes: []string{ScopeRead, ScopeInvoicesWrite}},
+}
+
+func newTestIssuer(t *testing.T, cfg IssuerConfig) (*TokenIssuer, *JWTValidator) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	issuer := NewTokenIssuerWithKey(cfg, key, testClients)
+	validator, err := NewJWTValidator(JWTConfig{Issuer: "https://auth.test", Audience: "payment-processor"})
+	assert.Nil(t, err)
+	validator.TrustKey(issuer.KeyID(), issuer.PublicKey())
+	return issuer, validator
+}
+
+func TestIssueAndValidate(t *testing.T) {
+	issuer, validator := newTestIssuer(t, IssuerConfig{Issuer: "https://auth.test", Audience: "payment-processor", KeyID: "k1"})
+
+	token, ttl, scopes, err := issuer.Issue("billing", "s3cret", []string{ScopeInvoicesWrite})
+	assert.Nil(t, err)
+	assert.Equal(t, time.Hour, ttl)
+	assert.Equal(t, []string{ScopeInvoicesWrite}, scopes)
+
+	principal, err := validator.Validate(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "billing", principal.Subject)
+	assert.True(t, principal.ActsForAnyMerchant())
+	assert.True(t, principal.HasAnyScope(ScopeInvoicesWrite))
+	assert.False(t, principal.HasAnyScope(ScopeRead))
+}
+
+func TestIssueRejectsClient(t *testing.T) {
+	issuer, _ := newTestIssuer(t, IssuerConfig{Issuer: "https://auth.test", Audience: "payment-processor", KeyID: "k1"})
+
+	_, _, _, err := issuer.Issue("billing", "wrong", nil)
+	assert.ErrorIs(t, err, ErrInvalidClient)
+
+	_, _, _, err = issuer.Issue("billing", "s3cret", []string{ScopeRefundsWrite})
+	assert.ErrorIs(t, err, ErrScopeNotAllowed)
+}
+
+func TestValidateRejectsToken(t *testing.T) {
+	cases := map[string]IssuerConfig{
+		"wrong audience": {Issuer: "https://auth.test", Audience: "other-service", KeyID: "k1"},
+		"wrong issuer":   {Issuer: "https://evil.test", Audience: "payment-processor", KeyID: "k1"},
+		"expired":        {Issuer: "https://auth.test", Audience: "payment-processor", KeyID: "k1", TTL: -time.Minute},
+		"unknown key":    {Issuer: "https://auth.test", Audience: "payment-processor", KeyID: "k1"},
+	}
+	for name, cfg := range cases {
+		t.Run(name, func(t *testing.T) {
+			issuer, validator := newTestIssuer(t, cfg)
+			if name == "unknown key" {
+				other, _ := newTestIssuer(t, cfg)
+				issuer = other
+			}
+			token, _, _, err := issuer.Issue("billing", "s3cret", nil)
+			assert.Nil(t, err)
+
+			_, err = validator.Validate(token)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestNewJWTValidatorFromJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	jwks, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA", "kid": "k1", "use": "sig",
+		"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.Nil(t, os.WriteFile(path, jwks, 0o600))
+
+	cfg := IssuerConfig{Issuer: "https://auth.test", Audience: "payment-processor", KeyID: "k1"}
+	validator, err := NewJWTValidator(JWTConfig{Issuer: cfg.Issuer, Audience: cfg.Audience, JWKSFile: path})
+	assert.Nil(t, err)
+
+	token, _, _, err := NewTokenIssuerWithKey(cfg, key, testClients).Issue("billing", "s3cret", nil)
+	assert.Nil(t, err)
+	_, err = validator.Validate(token)
+	assert.Nil(t, err)
+
+	assert.Nil(t, os.WriteFile(path, []byte(`{"keys":[]}`), 0o600))
+	_, err = NewJWTValidator(JWTConfig{Issuer: cfg.Issuer, Audience: cfg.Audience, JWKSFile: path})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/labstack/echo/v4"
+)
+
+// principalKey is the echo.Context key the authentication middleware stores the caller under
+const principalKey = "principal"
+
+// Principal is the authenticated caller of a request: a merchant using an API key,
+// or an internal service presenting a JWT.
+type Principal struct {
+	MerchantID uint     `json:"merchant_id,omitempty"`
+	APIKeyID   uint     `json:"api_key_id,omitempty"`
+	Mode       string   `json:"mode"` // test or live
+	Subject    string   `json:"subject,omitempty"`
+	Scopes     []string `json:"scopes"`
+	Roles      []string `json:"roles"`
+}
+
+type contextKey struct{}
+
+// SetPrincipal stores the caller on the Echo context and on the request context.
+func SetPrincipal(c echo.Context, principal *Principal) {
+	c.Set(principalKey, principal)
+	c.SetRequest(c.Request().WithContext(ContextWithPrincipal(c.Request().Context(), principal)))
+}
+
+// GetPrincipal returns the caller stored by the authentication middleware, or nil.
+func GetPrincipal(c echo.Context) *Principal {
+	principal, _ := c.Get(principalKey).(*Principal)
+	return principal
+}
+
+// PrincipalFromContext returns the caller carried by a request context, or nil.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(contextKey{}).(*Principal)
+	return principal
+}
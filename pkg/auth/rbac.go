@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+)
+
+// Roles a caller can hold. Merchant API keys always act as RoleMerchant; internal services
+// receive their roles through the roles claim of their token.
+const (
+	RoleAdmin    = "admin"
+	RoleSupport  = "support"
+	RoleFinance  = "finance"
+	RoleMerchant = "merchant"
+)
+
+// Permission is a single operation a role may perform
+type Permission string
+
+const (
+	PermissionInvoicesRead    Permission = "invoices.read"
+	PermissionInvoicesWrite   Permission = "invoices.write"
+	PermissionPaymentsWrite   Permission = "payments.write"
+	PermissionPaymentsReview  Permission = "payments.review"
+	PermissionDisputesRead    Permission = "disputes.read"
+	PermissionDisputesRespond Permission = "disputes.respond"
+	PermissionDisputesOpen    Permission = "disputes.open"
+	PermissionDisputesResolve Permission = "disputes.resolve"
+	PermissionMerchantsRead   Permission = "merchants.read"
+	PermissionMerchantsWrite  Permission = "merchants.write"
+	PermissionCustomersRead   Permission = "customers.read"
+	PermissionCustomersWrite  Permission = "customers.write"
+	PermissionAPIKeysManage   Permission = "api_keys.manage"
+	PermissionAuditLogRead    Permission = "audit_log.read"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleAdmin: {
+		PermissionInvoicesRead, PermissionInvoicesWrite, PermissionPaymentsWrite, PermissionPaymentsReview,
+		PermissionDisputesRead, PermissionDisputesOpen, PermissionDisputesResolve,
+		PermissionMerchantsRead, PermissionMerchantsWrite, PermissionCustomersRead, PermissionCustomersWrite,
+		PermissionAPIKeysManage, PermissionAuditLogRead,
+	},
+	RoleSupport: {
+		PermissionInvoicesRead, PermissionPaymentsReview, PermissionDisputesRead, PermissionDisputesOpen,
+		PermissionMerchantsRead, PermissionCustomersRead, PermissionCustomersWrite,
+	},
+	RoleFinance: {
+		PermissionInvoicesRead, PermissionDisputesRead, PermissionDisputesOpen, PermissionDisputesResolve,
+		PermissionMerchantsRead, PermissionCustomersRead, PermissionAuditLogRead,
+	},
+	RoleMerchant: {
+		PermissionInvoicesRead, PermissionInvoicesWrite, PermissionPaymentsWrite,
+		PermissionDisputesRead, PermissionDisputesRespond, PermissionAPIKeysManage,
+	},
+}
+
+// Can reports whether any of the principal's roles grants the permission.
+func (p *Principal) Can(permission Permission) bool {
+	if p == nil {
+		return false
+	}
+	for _, role := range p.Roles {
+		for _, granted := range rolePermissions[role] {
+			if granted == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Actor identifies the principal in AuditTrail.CreatedBy and LastUpdatedBy.
+func (p *Principal) Actor() string {
+	if p.APIKeyID != 0 {
+		return fmt.Sprintf("api_key:%d", p.APIKeyID)
+	}
+	return p.Subject
+}
+
+// SystemPrincipal is the caller used by background jobs and command line tools.
+func SystemPrincipal(name string) *Principal {
+	return &Principal{Subject: "system:" + name, Roles: []string{RoleAdmin}}
+}
+
+// ContextWithPrincipal returns a copy of ctx carrying the principal.
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
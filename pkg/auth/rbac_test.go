@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRolePermissions(t *testing.T) {
+	merchant := &Principal{MerchantID: 1, APIKeyID: 2, Roles: []string{RoleMerchant}}
+	assert.True(t, merchant.Can(PermissionInvoicesWrite))
+	assert.True(t, merchant.Can(PermissionDisputesRespond))
+	assert.False(t, merchant.Can(PermissionDisputesResolve))
+	assert.False(t, merchant.Can(PermissionMerchantsWrite))
+
+	support := &Principal{Subject: "support-console", Roles: []string{RoleSupport}}
+	assert.True(t, support.Can(PermissionCustomersWrite))
+	assert.False(t, support.Can(PermissionDisputesResolve))
+
+	finance := &Principal{Subject: "ledger", Roles: []string{RoleFinance, RoleSupport}}
+	assert.True(t, finance.Can(PermissionDisputesResolve))
+	assert.True(t, finance.Can(PermissionCustomersWrite))
+
+	assert.False(t, (&Principal{Subject: "no-roles"}).Can(PermissionInvoicesRead))
+	assert.False(t, (*Principal)(nil).Can(PermissionInvoicesRead))
+	assert.True(t, SystemPrincipal("reconciliation").Can(PermissionMerchantsWrite))
+}
+
+func TestActor(t *testing.T) {
+	assert.Equal(t, "api_key:42", (&Principal{MerchantID: 1, APIKeyID: 42}).Actor())
+	assert.Equal(t, "support-console", (&Principal{Subject: "support-console"}).Actor())
+	assert.Equal(t, "system:apikey-cli", SystemPrincipal("apikey-cli").Actor())
+}
@@ -0,0 +1,36 @@
+package auth
+
+// OAuth2 scopes understood by the API
+const (
+	ScopeRead          = "read"
+	ScopeInvoicesWrite = "invoices:write"
+	ScopePaymentsWrite = "payments:write"
+	ScopeRefundsWrite  = "refunds:write"
+)
+
+// MerchantScopes are granted to every merchant API key
+var MerchantScopes = []string{ScopeRead, ScopeInvoicesWrite, ScopePaymentsWrite, ScopeRefundsWrite}
+
+// HasAnyScope reports whether the principal was granted at least one of the scopes.
+func (p *Principal) HasAnyScope(scopes ...string) bool {
+	for _, granted := range p.Scopes {
+		for _, scope := range scopes {
+			if granted == scope {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsInternal reports whether the principal is an internal service rather than a merchant.
+// Internal callers are not tied to a merchant and may act on any of them.
+func (p *Principal) IsInternal() bool {
+	return p.APIKeyID == 0 && p.Subject != ""
+}
+
+// ActsForAnyMerchant reports whether the principal is an internal service whose token is not
+// restricted to a single merchant with the merchant_id claim.
+func (p *Principal) ActsForAnyMerchant() bool {
+	return p.IsInternal() && p.MerchantID == 0
+}
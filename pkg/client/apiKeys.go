@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+	"go/payment-processor/pkg/dto"
+	"net/http"
+)
+
+func (c *Client) ListAPIKeys(ctx context.Context) ([]*dto.APIKeyResponse, error) {
+	var keys []*dto.APIKeyResponse
+	if err := c.call(ctx, http.MethodGet, "/api-keys", nil, nil, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// CreateAPIKey creates an API key for the calling merchant. The secret is only returned here.
+func (c *Client) CreateAPIKey(ctx context.Context, req *dto.CreateAPIKeyRequest, opts ...RequestOption) (*dto.APIKeyResponse, error) {
+	var key dto.APIKeyResponse
+	if err := c.call(ctx, http.MethodPost, "/api-keys", nil, req, &key, opts...); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (c *Client) RevokeAPIKey(ctx context.Context, id uint, opts ...RequestOption) error {
+	return c.call(ctx, http.MethodDelete, pathf("/api-keys/%d", id), nil, nil, nil, opts...)
+}
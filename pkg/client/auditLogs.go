@@ -0,0 +1,25 @@
+package client
+
+import (
+	"context"
+	"go/payment-processor/pkg/dto"
+	"net/http"
+)
+
+// ListAuditLogs returns a page of audit log entries. Pass NextCursor of the page as Cursor to get the next one.
+func (c *Client) ListAuditLogs(ctx context.Context, req *dto.ListAuditLogsRequest) (*dto.ListAuditLogsResponse, error) {
+	var page dto.ListAuditLogsResponse
+	if err := c.call(ctx, http.MethodGet, "/audit-logs", encodeQuery(req), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// VerifyAuditLog checks the hash chain of the audit log.
+func (c *Client) VerifyAuditLog(ctx context.Context) (*dto.AuditLogVerificationResponse, error) {
+	var verification dto.AuditLogVerificationResponse
+	if err := c.call(ctx, http.MethodGet, "/audit-logs/verify", nil, nil, &verification); err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
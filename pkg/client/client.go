@@ -0,0 +1,306 @@
+// Package client is a typed Go client for the payment processor API. Every endpoint takes and
+// returns the DTOs of the server. Requests that change state carry an Idempotency-Key, so the
+// client retries them after server errors, rate limiting and network failures without charging
+// twice: once a payment has reached the provider the server answers every retry with the stored
+// response of the first attempt.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/payment-processor/pkg/idempotency"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultTimeout    = 30 * time.Second
+	defaultUserAgent  = "payment-processor-go-client"
+	apiKeyHeader      = "X-API-Key"
+	mimeJSON          = "application/json"
+	maxResponseLength = 10 << 20
+)
+
+// DefaultRetryPolicy retries three times, waiting between 250ms and 8s.
+var DefaultRetryPolicy = RetryPolicy{MaxRetries: 3, MinBackoff: 250 * time.Millisecond, MaxBackoff: 8 * time.Second}
+
+// RetryPolicy controls how requests failing with 429, a 5xx status or a network error are retried.
+// The wait doubles after every attempt, starting at MinBackoff, and is randomized to spread retries
+// of many clients. A Retry-After sent by the server replaces the computed wait; when it exceeds
+// MaxBackoff the client gives up instead.
+type RetryPolicy struct {
+	MaxRetries int // zero disables retries
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Client calls the API under a base URL such as "https://payments.example.com/payment-process".
+// It is safe for concurrent use.
+type Client struct {
+	baseURL     *url.URL
+	httpClient  *http.Client
+	apiKey      string
+	bearerToken string
+	userAgent   string
+	retry       RetryPolicy
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithAPIKey authenticates as a merchant with an API key.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithBearerToken authenticates as an internal service with an access token.
+func WithBearerToken(token string) Option {
+	return func(c *Client) { c.bearerToken = token }
+}
+
+// WithHTTPClient sends requests with another HTTP client, for example one with custom transport.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) { c.retry = policy }
+}
+
+// WithUserAgent identifies the application in the User-Agent header.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) { c.userAgent = userAgent }
+}
+
+func New(baseURL string, opts ...Option) (*Client, error) {
+	parsed, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("client: base URL %q must use http or https", baseURL)
+	}
+
+	c := &Client{baseURL: parsed, httpClient: &http.Client{Timeout: defaultTimeout}, userAgent: defaultUserAgent,
+		retry: DefaultRetryPolicy}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// RequestOption changes a single request.
+type RequestOption func(*request)
+
+// WithIdempotencyKey sends key instead of a generated idempotency key, so the request can be
+// retried safely after the call returned, for example after a restart of the application.
+func WithIdempotencyKey(key string) RequestOption {
+	return func(r *request) { r.idempotencyKey = key }
+}
+
+type request struct {
+	method         string
+	path           string
+	query          url.Values
+	body           []byte
+	contentType    string
+	idempotencyKey string
+}
+
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// call sends a request with a JSON body and decodes a successful JSON response into out
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, in, out any, opts ...RequestOption) error {
+	req, err := newRequest(method, path, query, in, opts)
+	if err != nil {
+		return err
+	}
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	if resp.status >= http.StatusBadRequest {
+		return decodeError(resp)
+	}
+	return decode(resp, out)
+}
+
+func newRequest(method, path string, query url.Values, in any, opts []RequestOption) (*request, error) {
+	req := &request{method: method, path: path, query: query}
+	if in != nil {
+		body, err := json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("client: encode request: %w", err)
+		}
+		req.body, req.contentType = body, mimeJSON
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+	return req, nil
+}
+
+// send performs the request, retrying it according to the retry policy. Requests that change state
+// keep the same idempotency key across attempts.
+func (c *Client) send(ctx context.Context, req *request) (*response, error) {
+	if req.method != http.MethodGet && req.method != http.MethodHead && req.idempotencyKey == "" {
+		req.idempotencyKey = uuid.NewString()
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(ctx, req)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if attempt >= c.retry.MaxRetries || !retryable(resp, err) {
+			if err != nil {
+				return nil, fmt.Errorf("client: %s %s: %w", req.method, req.path, err)
+			}
+			return resp, nil
+		}
+
+		wait := c.backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.header); ok {
+				if retryAfter > c.retry.MaxBackoff {
+					return resp, nil
+				}
+				wait = retryAfter
+			}
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, req *request) (*response, error) {
+	target := c.baseURL.JoinPath(req.path)
+	if len(req.query) > 0 {
+		target.RawQuery = req.query.Encode()
+	}
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	header := httpReq.Header
+	header.Set("Accept", mimeJSON)
+	header.Set("User-Agent", c.userAgent)
+	if req.contentType != "" {
+		header.Set("Content-Type", req.contentType)
+	}
+	if req.idempotencyKey != "" {
+		header.Set(idempotency.Header, req.idempotencyKey)
+	}
+	switch {
+	case c.apiKey != "":
+		header.Set(apiKeyHeader, c.apiKey)
+	case c.bearerToken != "":
+		header.Set("Authorization", "Bearer "+c.bearerToken)
+	}
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(httpResp.Body, maxResponseLength))
+	if err != nil {
+		return nil, err
+	}
+	return &response{status: httpResp.StatusCode, header: httpResp.Header, body: respBody}, nil
+}
+
+// retryable reports whether another attempt may succeed. Network errors are retried as the request
+// may not have reached the server; idempotency keys make it safe if it did. A replayed response is
+// final, retrying would replay it again.
+func retryable(resp *response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch {
+	case resp.header.Get(idempotency.ReplayedHeader) != "":
+		return false
+	case resp.status == http.StatusTooManyRequests:
+		return true
+	case resp.status == http.StatusNotImplemented:
+		return false
+	case resp.status >= http.StatusInternalServerError:
+		return true
+	case resp.status == http.StatusConflict:
+		// The first request with the idempotency key is still running
+		var apiErr *Error
+		return errors.As(decodeError(resp), &apiErr) && apiErr.Code == codeIdempotencyKeyInUse
+	}
+	return false
+}
+
+// backoff is the wait before the retry following attempt, between half and all of the doubled wait
+func (c *Client) backoff(attempt int) time.Duration {
+	wait := c.retry.MinBackoff << attempt
+	if wait <= 0 || wait > c.retry.MaxBackoff {
+		wait = c.retry.MaxBackoff
+	}
+	if wait <= 1 {
+		return wait
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(header http.Header) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+func decode(resp *response, out any) error {
+	if out == nil || len(bytes.TrimSpace(resp.body)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.body, out); err != nil {
+		return fmt.Errorf("client: decode response: %w", err)
+	}
+	return nil
+}
+
+// pathf formats a path with numeric IDs
+func pathf(format string, ids ...uint) string {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,345 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/entities"
+	handler "go/payment-processor/pkg/handler"
+	"go/payment-processor/pkg/idempotency"
+	"go/payment-processor/pkg/payments/provider"
+	"go/payment-processor/pkg/repository"
+	services "go/payment-processor/pkg/service"
+	"go/payment-processor/pkg/utils"
+	"go/payment-processor/pkg/vault"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+const testAPIKey = "sk_test_client"
+
+var testRetryPolicy = RetryPolicy{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+
+// The fakes embed the service interfaces and only implement what the tests call
+type fakeAPIKeyService struct{ services.APIKeyService }
+
+func (fakeAPIKeyService) Authenticate(_ context.Context, rawKey string) (*auth.Principal, error) {
+	if rawKey != testAPIKey {
+		return nil, services.ErrInvalidAPIKey
+	}
+	return &auth.Principal{MerchantID: 1, APIKeyID: 1, Mode: utils.APIKeyModeTest, Scopes: auth.MerchantScopes,
+		Roles: []string{auth.RoleMerchant}}, nil
+}
+
+type fakeInvoiceService struct {
+	services.InvoiceService
+	lastList *dto.ListInvoicesRequest
+}
+
+func (fakeInvoiceService) GetInvoiceByID(_ context.Context, id uint) (*dto.InvoiceResponse, error) {
+	if id == 404 {
+		return nil, services.ErrInvoiceNotFound
+	}
+	return &dto.InvoiceResponse{ID: id, MerchantID: 1, CustomerID: 2, Amount: decimal.NewFromInt(25), Currency: "USD",
+		Status: utils.InvoiceStatusPending, Mode: utils.APIKeyModeTest}, nil
+}
+
+func (s *fakeInvoiceService) ListInvoices(ctx context.Context, req *dto.ListInvoicesRequest) (*dto.ListInvoicesResponse, error) {
+	s.lastList = req
+	invoice, _ := s.GetInvoiceByID(ctx, 1)
+	return &dto.ListInvoicesResponse{Data: []*dto.InvoiceResponse{invoice}, NextCursor: "next"}, nil
+}
+
+type fakePaymentService struct {
+	services.PaymentService
+	processed atomic.Int32
+}
+
+// ProcessPayment declines cards ending in 3434, like the simulated provider
+func (s *fakePaymentService) ProcessPayment(_ context.Context, req *dto.ProcessPaymentRequest) (*entities.Payment, error) {
+	status := utils.PaymentStatusSuccess
+	if strings.HasSuffix(req.PaymentSource, "3434") {
+		status = utils.PaymentStatusDeclined
+	}
+	payment := &entities.Payment{InvoiceID: req.InvoiceID, Amount: decimal.NewFromInt(25), PaymentStatus: status,
+		PaymentMethod: req.PaymentMethod, PaymentSource: req.PaymentSource}
+	payment.ID = uint(s.processed.Add(1))
+	return payment, nil
+}
+
+func (*fakePaymentService) GetPaymentStatus(context.Context, uint) (string, error) {
+	return utils.PaymentStatusSuccess, nil
+}
+
+type testServer struct {
+	*httptest.Server
+	invoices *fakeInvoiceService
+	payments *fakePaymentService
+}
+
+// newTestServer serves the real routes and middleware of the API on top of fake services. wrap can
+// intercept requests before they reach the API.
+func newTestServer(t *testing.T, wrap func(http.Handler) http.Handler) *testServer {
+	payments := &fakePaymentService{}
+	server := newTestServerWithPayments(t, payments, wrap)
+	server.payments = payments
+	return server
+}
+
+// newTestServerWithPayments is newTestServer with the given payment service
+func newTestServerWithPayments(t *testing.T, payments services.PaymentService, wrap func(http.Handler) http.Handler) *testServer {
+	invoices := &fakeInvoiceService{}
+	h := handler.NewHandlerWithServices(zap.NewNop(), validator.New(),
+		handler.Services{Invoices: invoices, Payments: payments, APIKeys: fakeAPIKeyService{}},
+		handler.Dependencies{Idempotency: idempotency.NewMemoryStore(time.Hour)})
+
+	e := echo.New()
+	e.HTTPErrorHandler = handler.ErrorHandler(zap.NewNop())
+	e.Use(middleware.RequestID())
+	handler.Register(e.Group("/payment-process"), h)
+
+	var api http.Handler = e
+	if wrap != nil {
+		api = wrap(e)
+	}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	return &testServer{Server: server, invoices: invoices}
+}
+
+func (s *testServer) client(t *testing.T, opts ...Option) *Client {
+	c, err := New(s.URL+"/payment-process", append([]Option{WithAPIKey(testAPIKey), WithRetryPolicy(testRetryPolicy)}, opts...)...)
+	assert.NoError(t, err)
+	return c
+}
+
+func TestClientDecodesResponses(t *testing.T) {
+	server := newTestServer(t, nil)
+	c := server.client(t)
+	ctx := context.Background()
+
+	invoice, err := c.GetInvoice(ctx, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), invoice.ID)
+	assert.True(t, decimal.NewFromInt(25).Equal(invoice.Amount))
+
+	minAmount := 10.5
+	page, err := c.ListInvoices(ctx, &dto.ListInvoicesRequest{Status: utils.InvoiceStatusPending, MinAmount: &minAmount, Limit: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, "next", page.NextCursor)
+	assert.Len(t, page.Data, 1)
+	assert.Equal(t, utils.InvoiceStatusPending, server.invoices.lastList.Status)
+	assert.Equal(t, 10.5, *server.invoices.lastList.MinAmount)
+	assert.Equal(t, 5, server.invoices.lastList.Limit)
+
+	status, err := c.GetPaymentStatus(ctx, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, utils.PaymentStatusSuccess, status.PaymentStatus)
+
+	payment, err := c.ProcessPayment(ctx, 7, &dto.ProcessPaymentRequest{PaymentMethod: "credit_card", PaymentSource: "4242424242424242"})
+	assert.NoError(t, err)
+	assert.Equal(t, utils.PaymentStatusSuccess, payment.PaymentStatus)
+	assert.Equal(t, "************4242", payment.PaymentSource)
+}
+
+func TestClientReturnsDeclinesWithThePayment(t *testing.T) {
+	c := newTestServer(t, nil).client(t)
+
+	payment, err := c.ProcessPayment(context.Background(), 7, &dto.ProcessPaymentRequest{PaymentMethod: "credit_card", PaymentSource: "4242424242423434"})
+	var decline *DeclineError
+	if assert.ErrorAs(t, err, &decline) {
+		assert.Equal(t, utils.DeclineCodeGeneric, decline.DeclineCode())
+	}
+	assert.Equal(t, utils.PaymentStatusDeclined, payment.PaymentStatus)
+}
+
+func TestClientReturnsTypedErrors(t *testing.T) {
+	server := newTestServer(t, nil)
+	ctx := context.Background()
+
+	_, err := server.client(t).GetInvoice(ctx, 404)
+	var apiErr *Error
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+		assert.Equal(t, "invoice_not_found", apiErr.Code)
+		assert.NotEmpty(t, apiErr.RequestID)
+		assert.False(t, apiErr.Retryable())
+	}
+	assert.ErrorIs(t, err, services.ErrInvoiceNotFound)
+
+	_, err = server.client(t, WithAPIKey("sk_test_unknown")).GetInvoice(ctx, 1)
+	assert.ErrorIs(t, err, apperror.ErrUnauthorized)
+
+	_, err = server.client(t).ProcessPayment(ctx, 7, &dto.ProcessPaymentRequest{PaymentMethod: "credit_card", PaymentSource: "4242",
+		BillingCountry: "usa"})
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, apperror.ErrValidation.Code, apiErr.Code)
+		assert.Equal(t, "BillingCountry", apiErr.Fields[0].Field)
+	}
+}
+
+func TestClientRetriesWithTheSameIdempotencyKey(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	server := newTestServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			keys = append(keys, r.Header.Get(idempotency.Header))
+			attempt := len(keys)
+			mu.Unlock()
+			switch attempt {
+			case 1:
+				w.WriteHeader(http.StatusBadGateway)
+			case 2:
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			default:
+				next.ServeHTTP(w, r)
+			}
+		})
+	})
+
+	payment, err := server.client(t).ProcessPayment(context.Background(), 7,
+		&dto.ProcessPaymentRequest{PaymentMethod: "credit_card", PaymentSource: "4242424242424242"})
+	assert.NoError(t, err)
+	assert.Equal(t, utils.PaymentStatusSuccess, payment.PaymentStatus)
+	assert.Len(t, keys, 3)
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[1])
+	assert.Equal(t, keys[0], keys[2])
+}
+
+func TestIdempotencyKeyReplaysThePayment(t *testing.T) {
+	server := newTestServer(t, nil)
+	c := server.client(t)
+	ctx := context.Background()
+	req := &dto.ProcessPaymentRequest{PaymentMethod: "credit_card", PaymentSource: "4242424242424242"}
+
+	first, err := c.ProcessPayment(ctx, 7, req, WithIdempotencyKey("order-1"))
+	assert.NoError(t, err)
+	second, err := c.ProcessPayment(ctx, 7, req, WithIdempotencyKey("order-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Equal(t, int32(1), server.payments.processed.Load())
+
+	_, err = c.ProcessPayment(ctx, 8, req, WithIdempotencyKey("order-1"))
+	var apiErr *Error
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+		assert.Equal(t, "idempotency_key_reused", apiErr.Code)
+	}
+}
+
+// failingPaymentRepository has a pending invoice but fails to store payments
+type failingPaymentRepository struct {
+	repository.Repository
+}
+
+func (failingPaymentRepository) DoesInvoiceExist(_ context.Context, invoiceID uint) (*entities.Invoice, error) {
+	invoice := &entities.Invoice{MerchantID: 1, Amount: decimal.NewFromInt(25), Currency: "USD", InvoiceStatus: utils.InvoiceStatusPending}
+	invoice.ID = invoiceID
+	return invoice, nil
+}
+
+func (failingPaymentRepository) ClaimInvoice(context.Context, uint, string, string) error {
+	return nil
+}
+
+func (failingPaymentRepository) ProcessPayment(context.Context, *entities.Payment) (*entities.Payment, error) {
+	return nil, errors.New("connection refused")
+}
+
+// countingGateway authorizes every payment and counts the charges
+type countingGateway struct {
+	services.PaymentGateway
+	charges atomic.Int32
+}
+
+func (g *countingGateway) Pay(context.Context, provider.PaymentDetails) (provider.Payment, error) {
+	g.charges.Add(1)
+	return provider.Payment{ID: uuid.New(), Status: provider.PaymentStatusSuccess}, nil
+}
+
+func TestRetryAfterChargeIsNotChargedAgain(t *testing.T) {
+	gateway := &countingGateway{}
+	payments := services.NewPaymentService(zap.NewNop(), failingPaymentRepository{}, validator.New(), gateway, nil,
+		vault.NewEphemeralFingerprinter(), nil, nil, nil, nil)
+	var attempts atomic.Int32
+	c := newTestServerWithPayments(t, payments, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			next.ServeHTTP(w, r)
+		})
+	}).client(t)
+	ctx := context.Background()
+	req := &dto.ProcessPaymentRequest{PaymentMethod: "credit_card", PaymentSource: "4242424242424242"}
+
+	// The provider charges the card, then storing the payment fails with a server error
+	_, err := c.ProcessPayment(ctx, 7, req, WithIdempotencyKey("order-1"))
+	var apiErr *Error
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	}
+	// The client retried once and got the stored response back instead of a second charge
+	assert.Equal(t, int32(2), attempts.Load())
+	assert.Equal(t, int32(1), gateway.charges.Load())
+
+	_, err = c.ProcessPayment(ctx, 7, req, WithIdempotencyKey("order-1"))
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), gateway.charges.Load())
+}
+
+func TestClientGivesUp(t *testing.T) {
+	var attempts atomic.Int32
+	server := newTestServer(t, func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+	})
+
+	_, err := server.client(t).GetInvoice(context.Background(), 1)
+	var apiErr *Error
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+		assert.True(t, apiErr.Retryable())
+	}
+	assert.Equal(t, int32(testRetryPolicy.MaxRetries+1), attempts.Load())
+
+	// A Retry-After beyond the longest backoff is returned instead of waited for
+	attempts.Store(0)
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	_, err = server.client(t).GetInvoice(context.Background(), 1)
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, time.Minute, apiErr.RetryAfter)
+	}
+	assert.Equal(t, int32(1), attempts.Load())
+
+	// Cancelling the context stops the retries
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = server.client(t, WithRetryPolicy(RetryPolicy{MaxRetries: 100, MinBackoff: time.Second, MaxBackoff: time.Second})).GetInvoice(ctx, 1)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+}
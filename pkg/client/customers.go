@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"go/payment-processor/pkg/dto"
+	"net/http"
+)
+
+func (c *Client) CreateCustomer(ctx context.Context, req *dto.CreateCustomerRequest, opts ...RequestOption) (*dto.CustomerResponse, error) {
+	var customer dto.CustomerResponse
+	if err := c.call(ctx, http.MethodPost, "/customers", nil, req, &customer, opts...); err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+func (c *Client) SearchCustomers(ctx context.Context, req *dto.SearchCustomersRequest) ([]*dto.CustomerResponse, error) {
+	var customers []*dto.CustomerResponse
+	if err := c.call(ctx, http.MethodGet, "/customers", encodeQuery(req), nil, &customers); err != nil {
+		return nil, err
+	}
+	return customers, nil
+}
+
+func (c *Client) GetCustomer(ctx context.Context, id uint) (*dto.CustomerResponse, error) {
+	var customer dto.CustomerResponse
+	if err := c.call(ctx, http.MethodGet, pathf("/customers/%d", id), nil, nil, &customer); err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// UpdateCustomer changes the fields of req that are set.
+func (c *Client) UpdateCustomer(ctx context.Context, id uint, req *dto.UpdateCustomerRequest, opts ...RequestOption) (*dto.CustomerResponse, error) {
+	var customer dto.CustomerResponse
+	if err := c.call(ctx, http.MethodPatch, pathf("/customers/%d", id), nil, req, &customer, opts...); err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+func (c *Client) DeleteCustomer(ctx context.Context, id uint, opts ...RequestOption) error {
+	return c.call(ctx, http.MethodDelete, pathf("/customers/%d", id), nil, nil, nil, opts...)
+}
+
+// AddPaymentMethod saves a payment method for the customer. Only the last four digits are returned.
+func (c *Client) AddPaymentMethod(ctx context.Context, customerID uint, req *dto.CreatePaymentMethodRequest, opts ...RequestOption) (*dto.PaymentMethodResponse, error) {
+	var method dto.PaymentMethodResponse
+	if err := c.call(ctx, http.MethodPost, pathf("/customers/%d/payment-methods", customerID), nil, req, &method, opts...); err != nil {
+		return nil, err
+	}
+	return &method, nil
+}
+
+func (c *Client) GetPaymentMethods(ctx context.Context, customerID uint) ([]*dto.PaymentMethodResponse, error) {
+	var methods []*dto.PaymentMethodResponse
+	if err := c.call(ctx, http.MethodGet, pathf("/customers/%d/payment-methods", customerID), nil, nil, &methods); err != nil {
+		return nil, err
+	}
+	return methods, nil
+}
+
+func (c *Client) DeletePaymentMethod(ctx context.Context, customerID, paymentMethodID uint, opts ...RequestOption) error {
+	return c.call(ctx, http.MethodDelete, pathf("/customers/%d/payment-methods/%d", customerID, paymentMethodID), nil, nil, nil, opts...)
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/payment-processor/pkg/dto"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// OpenDispute opens a dispute against a payment on behalf of the card network.
+func (c *Client) OpenDispute(ctx context.Context, paymentID uint, req *dto.CreateDisputeRequest, opts ...RequestOption) (*dto.DisputeResponse, error) {
+	var dispute dto.DisputeResponse
+	if err := c.call(ctx, http.MethodPost, pathf("/payments/%d/disputes", paymentID), nil, req, &dispute, opts...); err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+func (c *Client) GetDispute(ctx context.Context, id uint) (*dto.DisputeResponse, error) {
+	var dispute dto.DisputeResponse
+	if err := c.call(ctx, http.MethodGet, pathf("/disputes/%d", id), nil, nil, &dispute); err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+// SubmitDisputeEvidence uploads Content as the evidence file named FileName.
+func (c *Client) SubmitDisputeEvidence(ctx context.Context, disputeID uint, req *dto.SubmitDisputeEvidenceRequest, opts ...RequestOption) (*dto.DisputeEvidenceResponse, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err := form.WriteField("evidence_type", req.EvidenceType); err != nil {
+		return nil, err
+	}
+	if err := form.WriteField("description", req.Description); err != nil {
+		return nil, err
+	}
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, escapeQuotes(req.FileName)))
+	if req.ContentType != "" {
+		header.Set("Content-Type", req.ContentType)
+	}
+	file, err := form.CreatePart(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(req.Content); err != nil {
+		return nil, err
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	request, err := newRequest(http.MethodPost, pathf("/disputes/%d/evidence", disputeID), nil, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	request.body, request.contentType = body.Bytes(), form.FormDataContentType()
+	resp, err := c.send(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if resp.status >= http.StatusBadRequest {
+		return nil, decodeError(resp)
+	}
+	var evidence dto.DisputeEvidenceResponse
+	if err := decode(resp, &evidence); err != nil {
+		return nil, err
+	}
+	return &evidence, nil
+}
+
+// ResolveDispute records the outcome of a dispute.
+func (c *Client) ResolveDispute(ctx context.Context, id uint, req *dto.ResolveDisputeRequest, opts ...RequestOption) (*dto.DisputeResponse, error) {
+	var dispute dto.DisputeResponse
+	if err := c.call(ctx, http.MethodPost, pathf("/disputes/%d/resolve", id), nil, req, &dispute, opts...); err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
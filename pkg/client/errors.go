@@ -0,0 +1,102 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/dto"
+	"net/http"
+	"time"
+)
+
+// codeIdempotencyKeyInUse is reported while the first request with an idempotency key is running
+const codeIdempotencyKeyInUse = "idempotency_key_in_use"
+
+// Error is an error response of the API, decoded from its problem details.
+type Error struct {
+	StatusCode  int
+	Code        string // machine-readable error code, such as "invoice_not_found"
+	Message     string
+	DeclineCode string
+	Fields      []apperror.FieldError // invalid request fields
+	RequestID   string                // quote it when reporting a problem
+	RetryAfter  time.Duration
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("payment processor: %d %s", e.StatusCode, e.Code)
+	if e.Message != "" {
+		message += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		message += " (request " + e.RequestID + ")"
+	}
+	return message
+}
+
+// Is matches errors by code, including the sentinel errors of the server, so
+// errors.Is(err, apperror.ErrUnauthorized) holds for a rejected API key.
+func (e *Error) Is(target error) bool {
+	switch t := target.(type) {
+	case *Error:
+		return t.Code == e.Code
+	case *apperror.Error:
+		return t.Code == e.Code
+	}
+	return false
+}
+
+// Retryable reports whether the request may succeed when sent again later.
+func (e *Error) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError ||
+		e.Code == codeIdempotencyKeyInUse
+}
+
+// DeclineError is returned together with the payment when it was declined. Retrying the same
+// payment source is declined again.
+type DeclineError struct {
+	Payment *dto.ProcessPaymentResponse
+}
+
+func (e *DeclineError) Error() string {
+	return fmt.Sprintf("payment processor: payment %d declined: %s", e.Payment.ID, e.Payment.DeclineCode)
+}
+
+// DeclineCode is the normalized reason of the decline, such as "insufficient_funds".
+func (e *DeclineError) DeclineCode() string {
+	return e.Payment.DeclineCode
+}
+
+// decodeError turns an error response into an *Error. Bodies other than problem details, such as
+// the OAuth2 errors of the token endpoint, keep their status and error code.
+func decodeError(resp *response) error {
+	apiErr := &Error{StatusCode: resp.status, RequestID: resp.header.Get("X-Request-ID")}
+	if retryAfter, ok := parseRetryAfter(resp.header); ok {
+		apiErr.RetryAfter = retryAfter
+	}
+
+	var body struct {
+		apperror.Problem
+		OAuthError string `json:"error"`
+	}
+	if err := json.Unmarshal(resp.body, &body); err != nil {
+		apiErr.Code = apperror.FromStatus(resp.status).Code
+		apiErr.Message = http.StatusText(resp.status)
+		return apiErr
+	}
+
+	apiErr.Code = body.Code
+	apiErr.Message = body.Detail
+	apiErr.DeclineCode = body.DeclineCode
+	apiErr.Fields = body.Errors
+	if body.RequestID != "" {
+		apiErr.RequestID = body.RequestID
+	}
+	if apiErr.Code == "" {
+		apiErr.Code = body.OAuthError
+	}
+	if apiErr.Code == "" {
+		apiErr.Code = apperror.FromStatus(resp.status).Code
+	}
+	return apiErr
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"go/payment-processor/pkg/dto"
+	"net/http"
+)
+
+func (c *Client) CreateInvoice(ctx context.Context, req *dto.CreateInvoiceRequest, opts ...RequestOption) (*dto.InvoiceResponse, error) {
+	var invoice dto.InvoiceResponse
+	if err := c.call(ctx, http.MethodPost, "/invoices", nil, req, &invoice, opts...); err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func (c *Client) GetInvoice(ctx context.Context, id uint) (*dto.InvoiceResponse, error) {
+	var invoice dto.InvoiceResponse
+	if err := c.call(ctx, http.MethodGet, pathf("/invoices/%d", id), nil, nil, &invoice); err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// ListInvoices returns a page of invoices. Pass NextCursor of the page as Cursor to get the next one.
+func (c *Client) ListInvoices(ctx context.Context, req *dto.ListInvoicesRequest) (*dto.ListInvoicesResponse, error) {
+	var page dto.ListInvoicesResponse
+	if err := c.call(ctx, http.MethodGet, "/invoices", encodeQuery(req), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// ProcessPayment pays an invoice. A declined payment is returned together with a *DeclineError; a
+// payment with status REQUIRES_ACTION waits for CompletePaymentChallenge.
+func (c *Client) ProcessPayment(ctx context.Context, invoiceID uint, req *dto.ProcessPaymentRequest, opts ...RequestOption) (*dto.ProcessPaymentResponse, error) {
+	return c.paymentResult(ctx, pathf("/invoices/%d/payments", invoiceID), req, opts)
+}
+
+// CompletePaymentChallenge submits the result of the 3-D Secure challenge of a payment.
+func (c *Client) CompletePaymentChallenge(ctx context.Context, invoiceID, paymentID uint, req *dto.CompleteChallengeRequest, opts ...RequestOption) (*dto.ProcessPaymentResponse, error) {
+	return c.paymentResult(ctx, pathf("/invoices/%d/payments/%d/challenge", invoiceID, paymentID), req, opts)
+}
+
+func (c *Client) GetPaymentStatus(ctx context.Context, invoiceID uint) (*dto.PaymentStatusResponse, error) {
+	var status dto.PaymentStatusResponse
+	if err := c.call(ctx, http.MethodGet, pathf("/invoices/%d/payment-status", invoiceID), nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// paymentResult decodes the payment of 402 Payment Required responses, which report declines
+func (c *Client) paymentResult(ctx context.Context, path string, in any, opts []RequestOption) (*dto.ProcessPaymentResponse, error) {
+	req, err := newRequest(http.MethodPost, path, nil, in, opts)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.status >= http.StatusBadRequest && resp.status != http.StatusPaymentRequired {
+		return nil, decodeError(resp)
+	}
+
+	var payment dto.ProcessPaymentResponse
+	if err := decode(resp, &payment); err != nil {
+		return nil, err
+	}
+	if resp.status == http.StatusPaymentRequired {
+		return &payment, &DeclineError{Payment: &payment}
+	}
+	return &payment, nil
+}
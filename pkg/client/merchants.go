@@ -0,0 +1,36 @@
+package client
+
+import (
+	"context"
+	"go/payment-processor/pkg/dto"
+	"net/http"
+)
+
+func (c *Client) CreateMerchant(ctx context.Context, req *dto.CreateMerchantRequest, opts ...RequestOption) (*dto.MerchantResponse, error) {
+	var merchant dto.MerchantResponse
+	if err := c.call(ctx, http.MethodPost, "/merchants", nil, req, &merchant, opts...); err != nil {
+		return nil, err
+	}
+	return &merchant, nil
+}
+
+func (c *Client) GetMerchant(ctx context.Context, id uint) (*dto.MerchantResponse, error) {
+	var merchant dto.MerchantResponse
+	if err := c.call(ctx, http.MethodGet, pathf("/merchants/%d", id), nil, nil, &merchant); err != nil {
+		return nil, err
+	}
+	return &merchant, nil
+}
+
+// UpdateMerchant changes the fields of req that are set.
+func (c *Client) UpdateMerchant(ctx context.Context, id uint, req *dto.UpdateMerchantRequest, opts ...RequestOption) (*dto.MerchantResponse, error) {
+	var merchant dto.MerchantResponse
+	if err := c.call(ctx, http.MethodPatch, pathf("/merchants/%d", id), nil, req, &merchant, opts...); err != nil {
+		return nil, err
+	}
+	return &merchant, nil
+}
+
+func (c *Client) DeleteMerchant(ctx context.Context, id uint, opts ...RequestOption) error {
+	return c.call(ctx, http.MethodDelete, pathf("/merchants/%d", id), nil, nil, nil, opts...)
+}
@@ -0,0 +1,34 @@
+package client
+
+import (
+	"context"
+	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/velocity"
+	"net/http"
+)
+
+// ListPaymentReviews lists the payments the risk engine held for review.
+func (c *Client) ListPaymentReviews(ctx context.Context, req *dto.ListPaymentReviewsRequest) ([]*dto.PaymentReviewResponse, error) {
+	var reviews []*dto.PaymentReviewResponse
+	if err := c.call(ctx, http.MethodGet, "/payment-reviews", encodeQuery(req), nil, &reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// ReviewPayment approves or rejects a held payment.
+func (c *Client) ReviewPayment(ctx context.Context, paymentID uint, req *dto.ReviewPaymentRequest, opts ...RequestOption) (*dto.ProcessPaymentResponse, error) {
+	var payment dto.ProcessPaymentResponse
+	if err := c.call(ctx, http.MethodPost, pathf("/payments/%d/review", paymentID), nil, req, &payment, opts...); err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (c *Client) GetVelocityMetrics(ctx context.Context) (*velocity.Metrics, error) {
+	var metrics velocity.Metrics
+	if err := c.call(ctx, http.MethodGet, "/velocity/metrics", nil, nil, &metrics); err != nil {
+		return nil, err
+	}
+	return &metrics, nil
+}
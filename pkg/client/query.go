@@ -0,0 +1,47 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// encodeQuery encodes the fields of a request DTO carrying a query tag. Zero values are left out,
+// so the server applies its defaults.
+func encodeQuery(req any) url.Values {
+	values := url.Values{}
+	v := reflect.ValueOf(req)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return values
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return values
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("query"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		field := v.Field(i)
+		if field.IsZero() {
+			continue
+		}
+		if field.Kind() == reflect.Pointer {
+			field = field.Elem()
+		}
+		switch field.Kind() {
+		case reflect.Float32, reflect.Float64:
+			values.Set(name, strconv.FormatFloat(field.Float(), 'f', -1, 64))
+		default:
+			values.Set(name, fmt.Sprint(field.Interface()))
+		}
+	}
+	return values
+}
@@ -0,0 +1,34 @@
+package client
+
+import (
+	"context"
+	"go/payment-processor/pkg/dto"
+	"net/http"
+	"net/url"
+)
+
+// IssueToken obtains an access token with the client-credentials grant of the local token issuer.
+// Use it with WithBearerToken.
+func (c *Client) IssueToken(ctx context.Context, req *dto.TokenRequest) (*dto.TokenResponse, error) {
+	form := url.Values{"grant_type": {req.GrantType}, "client_id": {req.ClientID}, "client_secret": {req.ClientSecret}}
+	if form.Get("grant_type") == "" {
+		form.Set("grant_type", "client_credentials")
+	}
+	if req.Scope != "" {
+		form.Set("scope", req.Scope)
+	}
+
+	resp, err := c.send(ctx, &request{method: http.MethodPost, path: "/oauth/token", body: []byte(form.Encode()),
+		contentType: "application/x-www-form-urlencoded"})
+	if err != nil {
+		return nil, err
+	}
+	if resp.status >= http.StatusBadRequest {
+		return nil, decodeError(resp)
+	}
+	var token dto.TokenResponse
+	if err := decode(resp, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"go/payment-processor/pkg/auth"
+	"os"
+	"time"
+)
+
+// GetJWTConfig reads bearer token validation settings. Validation is disabled when JWT_ISSUER is not set.
+func GetJWTConfig() (auth.JWTConfig, bool) {
+	cfg := auth.JWTConfig{
+		Issuer:   os.Getenv("JWT_ISSUER"),
+		Audience: os.Getenv("JWT_AUDIENCE"),
+		JWKSFile: os.Getenv("JWT_JWKS_FILE"),
+	}
+	return cfg, cfg.Issuer != ""
+}
+
+// GetTokenIssuerConfig reads the local token issuer settings. The issuer is disabled when
+// JWT_SIGNING_KEY_FILE is not set, which should be the case outside test environments.
+func GetTokenIssuerConfig() (auth.IssuerConfig, bool) {
+	cfg := auth.IssuerConfig{
+		Issuer:         os.Getenv("JWT_ISSUER"),
+		Audience:       os.Getenv("JWT_AUDIENCE"),
+		SigningKeyFile: os.Getenv("JWT_SIGNING_KEY_FILE"),
+		KeyID:          os.Getenv("JWT_SIGNING_KEY_ID"),
+		ClientsFile:    os.Getenv("OAUTH_CLIENTS_FILE"),
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("JWT_TOKEN_TTL")); err == nil {
+		cfg.TTL = ttl
+	}
+	if cfg.KeyID == "" {
+		cfg.KeyID = "local"
+	}
+	return cfg, cfg.SigningKeyFile != ""
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// defaultIdempotencyKeyTTL covers clients retrying a payment for a day
+const defaultIdempotencyKeyTTL = 24 * time.Hour
+
+// GetIdempotencyKeyTTL reads how long responses are replayed for a repeated Idempotency-Key from
+// IDEMPOTENCY_KEY_TTL. Idempotency keys are ignored when IDEMPOTENCY_DISABLED is "true".
+func GetIdempotencyKeyTTL() (time.Duration, bool, error) {
+	if os.Getenv("IDEMPOTENCY_DISABLED") == "true" {
+		return 0, false, nil
+	}
+	raw := os.Getenv("IDEMPOTENCY_KEY_TTL")
+	if raw == "" {
+		return defaultIdempotencyKeyTTL, true, nil
+	}
+	ttl, err := time.ParseDuration(raw)
+	if err != nil || ttl <= 0 {
+		return 0, false, fmt.Errorf("IDEMPOTENCY_KEY_TTL: invalid duration %q", raw)
+	}
+	return ttl, true, nil
+}
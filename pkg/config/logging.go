@@ -2,16 +2,29 @@ package config
 
 import (
 	"context"
+	"errors"
+	"go/payment-processor/pkg/logging"
+	"go/payment-processor/pkg/metrics"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gorm.io/gorm"
 	logger2 "gorm.io/gorm/logger"
 )
 
 var logger *zap.Logger
 
+// queryMetrics records the duration of the queries traced by ZapLogger
+var queryMetrics atomic.Pointer[metrics.Metrics]
+
+// SetQueryMetrics makes ZapLogger record the duration of every query in m. nil stops recording.
+func SetQueryMetrics(m *metrics.Metrics) {
+	queryMetrics.Store(m)
+}
+
 // InitializeLogger sets up a global logger using the zap library.
 func InitializeLogger() {
 
@@ -58,26 +71,34 @@ func (l ZapLogger) LogMode(level logger2.LogLevel) logger2.Interface {
 
 // Info Logs informational messages with optional context and additional data.
 func (l ZapLogger) Info(ctx context.Context, msg string, data ...interface{}) {
-	logger.Info(msg, zap.Any("context", ctx), zap.Any("data", data))
+	logging.FromContext(ctx, logger).Info(msg, zap.Any("context", ctx), zap.Any("data", data))
 }
 
 // Warn Logs warning messages with optional context and additional data.
 func (l ZapLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
-	logger.Warn(msg, zap.Any("context", ctx), zap.Any("data", data))
+	logging.FromContext(ctx, logger).Warn(msg, zap.Any("context", ctx), zap.Any("data", data))
 }
 
 // Error Logs error messages with optional context and additional data.
 func (l ZapLogger) Error(ctx context.Context, msg string, data ...interface{}) {
-	logger.Error(msg, zap.Any("context", ctx), zap.Any("data", data))
+	logging.FromContext(ctx, logger).Error(msg, zap.Any("context", ctx), zap.Any("data", data))
 }
 
 // Trace Logs detailed SQL query information and handles logging errors related to SQL execution.
 func (l ZapLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
 
 	sql, rows := fc()
+	queryErr := err
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// A lookup that finds nothing is not a failed query
+		queryErr = nil
+	}
+	queryMetrics.Load().ObserveQuery(sql, time.Since(begin), queryErr)
+	// Queries are logged with the request ID and the merchant and invoice of the request that ran them
+	log := logging.FromContext(ctx, logger)
 	if err != nil {
-		logger.Error("Query error", zap.Error(err), zap.String("sql", sql), zap.Int64("rows", rows))
+		log.Error("Query error", zap.Error(err), zap.String("sql", sql), zap.Int64("rows", rows))
 	} else {
-		logger.Info("Query OK", zap.String("sql", sql), zap.Int64("rows", rows))
+		log.Info("Query OK", zap.String("sql", sql), zap.Int64("rows", rows))
 	}
 }
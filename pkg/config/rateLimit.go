@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"go/payment-processor/pkg/ratelimit"
+	"os"
+	"strings"
+)
+
+// Defaults keep a single script from card testing against the payment endpoint
+const (
+	defaultRateLimitPerKey   = "600/m"
+	defaultRateLimitPerIP    = "300/m"
+	defaultRateLimitPayments = "POST /payment-process/invoices/:id/payments=30/m"
+)
+
+// GetRateLimitConfig reads the rate limits. RATE_LIMIT_PER_KEY and RATE_LIMIT_PER_IP take limits like
+// "600/m"; RATE_LIMIT_ENDPOINTS is a semicolon separated list of "<METHOD> <route>=<limit>".
+// Rate limiting is disabled when RATE_LIMIT_DISABLED is "true".
+func GetRateLimitConfig() (ratelimit.Config, bool, error) {
+	if os.Getenv("RATE_LIMIT_DISABLED") == "true" {
+		return ratelimit.Config{}, false, nil
+	}
+
+	var cfg ratelimit.Config
+	var err error
+	if cfg.PerKey, err = ratelimit.ParseLimit(getEnvOrDefault("RATE_LIMIT_PER_KEY", defaultRateLimitPerKey)); err != nil {
+		return cfg, false, err
+	}
+	if cfg.PerIP, err = ratelimit.ParseLimit(getEnvOrDefault("RATE_LIMIT_PER_IP", defaultRateLimitPerIP)); err != nil {
+		return cfg, false, err
+	}
+
+	cfg.Endpoints = make(map[string]ratelimit.Limit)
+	for _, entry := range strings.Split(getEnvOrDefault("RATE_LIMIT_ENDPOINTS", defaultRateLimitPayments), ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		endpoint, rawLimit, found := strings.Cut(entry, "=")
+		if !found {
+			return cfg, false, fmt.Errorf("rate limit endpoint %q: expected <METHOD> <route>=<limit>", entry)
+		}
+		limit, err := ratelimit.ParseLimit(rawLimit)
+		if err != nil {
+			return cfg, false, err
+		}
+		cfg.Endpoints[strings.Join(strings.Fields(endpoint), " ")] = limit
+	}
+	return cfg, true, nil
+}
+
+func getEnvOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
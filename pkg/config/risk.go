@@ -0,0 +1,75 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/payment-processor/pkg/risk"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// GetRiskConfig reads the risk engine rules. The engine is disabled when RISK_DISABLED is "true".
+// RISK_BIN_FILE points to a JSON object mapping card BINs to their ISO 3166-1 alpha-2 issuing country.
+func GetRiskConfig() (risk.Config, bool, error) {
+	if os.Getenv("RISK_DISABLED") == "true" {
+		return risk.Config{}, false, nil
+	}
+
+	cfg := risk.Config{
+		Window:                 time.Hour,
+		MaxAttemptsPerCard:     5,
+		MaxAttemptsPerCustomer: 10,
+		MaxAttemptsPerIP:       20,
+		MaxDeclinesPerCard:     3,
+		ReviewAmount:           decimal.NewFromInt(5000),
+		BlockAmount:            decimal.NewFromInt(50000),
+	}
+	if raw := os.Getenv("RISK_WINDOW"); raw != "" {
+		window, err := time.ParseDuration(raw)
+		if err != nil {
+			return cfg, false, fmt.Errorf("RISK_WINDOW: %w", err)
+		}
+		cfg.Window = window
+	}
+	for name, target := range map[string]*int{
+		"RISK_MAX_ATTEMPTS_PER_CARD":     &cfg.MaxAttemptsPerCard,
+		"RISK_MAX_ATTEMPTS_PER_CUSTOMER": &cfg.MaxAttemptsPerCustomer,
+		"RISK_MAX_ATTEMPTS_PER_IP":       &cfg.MaxAttemptsPerIP,
+		"RISK_MAX_DECLINES_PER_CARD":     &cfg.MaxDeclinesPerCard,
+		"RISK_REVIEW_SCORE":              &cfg.ReviewScore,
+		"RISK_BLOCK_SCORE":               &cfg.BlockScore,
+	} {
+		if raw := os.Getenv(name); raw != "" {
+			value, err := strconv.Atoi(raw)
+			if err != nil {
+				return cfg, false, fmt.Errorf("%s: %w", name, err)
+			}
+			*target = value
+		}
+	}
+	for name, target := range map[string]*decimal.Decimal{
+		"RISK_REVIEW_AMOUNT": &cfg.ReviewAmount,
+		"RISK_BLOCK_AMOUNT":  &cfg.BlockAmount,
+	} {
+		if raw := os.Getenv(name); raw != "" {
+			value, err := decimal.NewFromString(raw)
+			if err != nil {
+				return cfg, false, fmt.Errorf("%s: %w", name, err)
+			}
+			*target = value
+		}
+	}
+	if path := os.Getenv("RISK_BIN_FILE"); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, false, fmt.Errorf("reading BIN file: %w", err)
+		}
+		if err := json.Unmarshal(raw, &cfg.BINCountries); err != nil {
+			return cfg, false, fmt.Errorf("parsing BIN file: %w", err)
+		}
+	}
+	return cfg, true, nil
+}
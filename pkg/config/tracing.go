@@ -0,0 +1,23 @@
+package config
+
+import (
+	"fmt"
+	"go/payment-processor/pkg/tracing"
+	"os"
+	"strings"
+)
+
+// GetTraceExporter reads where spans are sent from the standard OTEL_TRACES_EXPORTER variable:
+// "otlp" or "console" (also "stdout"). Tracing is disabled when it is unset or "none".
+func GetTraceExporter() (string, bool, error) {
+	switch exporter := strings.ToLower(os.Getenv("OTEL_TRACES_EXPORTER")); exporter {
+	case "", "none":
+		return "", false, nil
+	case tracing.ExporterOTLP, tracing.ExporterStdout:
+		return exporter, true, nil
+	case "stdout":
+		return tracing.ExporterStdout, true, nil
+	default:
+		return "", false, fmt.Errorf("OTEL_TRACES_EXPORTER: unsupported exporter %q", exporter)
+	}
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"go/payment-processor/pkg/velocity"
+	"os"
+	"strconv"
+	"time"
+)
+
+// GetVelocityConfig reads the decline velocity limits. They are disabled when VELOCITY_DISABLED is "true".
+func GetVelocityConfig() (velocity.Config, bool, error) {
+	if os.Getenv("VELOCITY_DISABLED") == "true" {
+		return velocity.Config{}, false, nil
+	}
+
+	cfg := velocity.Config{
+		Window:                 10 * time.Minute,
+		BlockDuration:          30 * time.Minute,
+		MaxDeclinesPerCard:     3,
+		MaxDeclinesPerCustomer: 5,
+		MaxDeclinesPerMerchant: 100,
+	}
+	for name, target := range map[string]*time.Duration{
+		"VELOCITY_WINDOW":         &cfg.Window,
+		"VELOCITY_BLOCK_DURATION": &cfg.BlockDuration,
+	} {
+		if raw := os.Getenv(name); raw != "" {
+			value, err := time.ParseDuration(raw)
+			if err != nil {
+				return cfg, false, fmt.Errorf("%s: %w", name, err)
+			}
+			*target = value
+		}
+	}
+	for name, target := range map[string]*int{
+		"VELOCITY_MAX_DECLINES_PER_CARD":     &cfg.MaxDeclinesPerCard,
+		"VELOCITY_MAX_DECLINES_PER_CUSTOMER": &cfg.MaxDeclinesPerCustomer,
+		"VELOCITY_MAX_DECLINES_PER_MERCHANT": &cfg.MaxDeclinesPerMerchant,
+	} {
+		if raw := os.Getenv(name); raw != "" {
+			value, err := strconv.Atoi(raw)
+			if err != nil {
+				return cfg, false, fmt.Errorf("%s: %w", name, err)
+			}
+			*target = value
+		}
+	}
+	return cfg, true, nil
+}
@@ -0,0 +1,15 @@
+package dto
+
+import "time"
+
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	MerchantID uint       `json:"merchant_id"`
+	Name       string     `json:"name,omitempty"`
+	Prefix     string     `json:"prefix"`
+	Mode       string     `json:"mode"`
+	Key        string     `json:"key,omitempty"` // Only returned when the key is created
+	CreatedAt  *time.Time `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
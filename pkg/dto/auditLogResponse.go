@@ -0,0 +1,32 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditLogResponse struct {
+	ID         uint            `json:"id"`
+	EntityType string          `json:"entity_type"`
+	EntityID   uint            `json:"entity_id"`
+	Action     string          `json:"action"`
+	Actor      string          `json:"actor"`
+	RequestID  string          `json:"request_id,omitempty"`
+	Changes    json.RawMessage `json:"changes"`
+	CreatedAt  *time.Time      `json:"created_at"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+}
+
+type ListAuditLogsResponse struct {
+	Data       []*AuditLogResponse `json:"data"`
+	NextCursor string              `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// AuditLogVerificationResponse is the result of recomputing the audit log hash chain
+type AuditLogVerificationResponse struct {
+	Valid    bool   `json:"valid"`
+	Checked  int    `json:"checked"`
+	LastHash string `json:"last_hash,omitempty"`
+	BrokenAt *uint  `json:"broken_at,omitempty"` // ID of the first entry that is not correctly chained
+}
@@ -0,0 +1,7 @@
+package dto
+
+type CompleteChallengeRequest struct {
+	InvoiceID uint   `json:"-"` // Set from the path
+	PaymentID uint   `json:"-"` // Set from the path
+	Result    string `json:"result" validate:"required,oneof=success failure"`
+}
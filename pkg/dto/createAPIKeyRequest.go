@@ -0,0 +1,7 @@
+package dto
+
+type CreateAPIKeyRequest struct {
+	MerchantID uint   `json:"-"`
+	Name       string `json:"name" validate:"max=255"`
+	Mode       string `json:"mode" validate:"required,oneof=test live"`
+}
@@ -0,0 +1,7 @@
+package dto
+
+type CreateCustomerRequest struct {
+	CustomerName    string `json:"customer_name" validate:"required,max=255"`
+	CustomerEmail   string `json:"customer_email" validate:"required,email,max=255"`
+	CustomerAddress string `json:"customer_address,omitempty" validate:"max=1000"`
+}
@@ -0,0 +1,10 @@
+package dto
+
+import "time"
+
+type CreateDisputeRequest struct {
+	PaymentID  uint       `json:"payment_id"`
+	ReasonCode string     `json:"reason_code" validate:"required,max=50"`
+	Amount     float64    `json:"amount,omitempty" validate:"gte=0"` // Defaults to the full payment amount
+	RespondBy  *time.Time `json:"respond_by,omitempty"`
+}
@@ -6,4 +6,5 @@ type CreateInvoiceRequest struct {
 	Amount              float64 `json:"amount" binding:"required"`
 	Currency            string  `json:"currency" binding:"required"`
 	OptionalDescription string  `json:"optional_description,omitempty"`
+	Mode                string  `json:"-"` // Set from the authenticated caller
 }
@@ -1,6 +1,9 @@
 package dto
 
-import "github.com/shopspring/decimal"
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
 
 type InvoiceResponse struct {
 	ID         uint            `json:"id"`
@@ -8,4 +11,7 @@ type InvoiceResponse struct {
 	CustomerID uint            `json:"customer_id"`
 	Amount     decimal.Decimal `json:"amount"`
 	Currency   string          `json:"currency"`
+	Status     string          `json:"invoice_status"`
+	Mode       string          `json:"mode"`
+	CreatedAt  *time.Time      `json:"created_at"`
 }
@@ -0,0 +1,8 @@
+package dto
+
+type CreateMerchantRequest struct {
+	MerchantName      string   `json:"merchant_name" validate:"required,max=255"`
+	MerchantCode      string   `json:"merchant_code" validate:"required,alphanum,max=50"`
+	AllowedCurrencies []string `json:"allowed_currencies" validate:"required,min=1,dive,iso4217"`
+	MerchantStatus    string   `json:"merchant_status,omitempty" validate:"omitempty,oneof=active suspended"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+type CreatePaymentMethodRequest struct {
+	CustomerID    uint   `json:"customer_id"`
+	MethodType    string `json:"method_type" validate:"required,oneof=credit_card bank_transfer"`
+	PaymentSource string `json:"payment_source" validate:"required,min=4,max=34"`
+	HolderName    string `json:"holder_name,omitempty" validate:"max=255"`
+	Expiry        string `json:"expiry,omitempty" validate:"omitempty,len=5"` // MM/YY
+}
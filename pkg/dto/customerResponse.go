@@ -0,0 +1,13 @@
+package dto
+
+import "time"
+
+type CustomerResponse struct {
+	ID              uint       `json:"id"`
+	CustomerName    string     `json:"customer_name"`
+	CustomerEmail   string     `json:"customer_email"`
+	CustomerAddress string     `json:"customer_address,omitempty"`
+	IsActive        bool       `json:"is_active"`
+	CreatedAt       *time.Time `json:"created_at"`
+	LastUpdatedAt   *time.Time `json:"last_updated_at"`
+}
@@ -0,0 +1,30 @@
+package dto
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type DisputeResponse struct {
+	ID            uint                      `json:"id"`
+	PaymentID     uint                      `json:"payment_id"`
+	InvoiceID     uint                      `json:"invoice_id"`
+	MerchantID    uint                      `json:"merchant_id"`
+	Amount        decimal.Decimal           `json:"amount"`
+	Currency      string                    `json:"currency"`
+	ReasonCode    string                    `json:"reason_code"`
+	DisputeStatus string                    `json:"dispute_status"`
+	RespondBy     *time.Time                `json:"respond_by"`
+	ResolvedAt    *time.Time                `json:"resolved_at,omitempty"`
+	Evidence      []DisputeEvidenceResponse `json:"evidence,omitempty"`
+}
+
+type DisputeEvidenceResponse struct {
+	ID           uint       `json:"id"`
+	EvidenceType string     `json:"evidence_type"`
+	Description  string     `json:"description,omitempty"`
+	FileName     string     `json:"file_name"`
+	ContentType  string     `json:"content_type"`
+	Size         int        `json:"size"`
+	CreatedAt    *time.Time `json:"created_at"`
+}
@@ -0,0 +1,12 @@
+package dto
+
+type ListAuditLogsRequest struct {
+	EntityType  string `query:"entity_type" validate:"omitempty,oneof=invoice payment merchant customer"`
+	EntityID    uint   `query:"entity_id"`
+	Actor       string `query:"actor"`
+	RequestID   string `query:"request_id"`
+	CreatedFrom string `query:"created_from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"` // RFC 3339
+	CreatedTo   string `query:"created_to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Limit       int    `query:"limit" validate:"gte=0,lte=100"`
+	Cursor      string `query:"cursor"`
+}
@@ -0,0 +1,17 @@
+package dto
+
+type ListInvoicesRequest struct {
+	MerchantID  uint     `query:"merchant_id"`
+	CustomerID  uint     `query:"customer_id"`
+	Status      string   `query:"status" validate:"omitempty,oneof=PENDING PROCESSING PAID DISPUTED CHARGED_BACK"`
+	Currency    string   `query:"currency" validate:"omitempty,iso4217"`
+	MinAmount   *float64 `query:"min_amount" validate:"omitempty,gte=0"`
+	MaxAmount   *float64 `query:"max_amount" validate:"omitempty,gte=0"`
+	CreatedFrom string   `query:"created_from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"` // RFC 3339
+	CreatedTo   string   `query:"created_to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Sort        string   `query:"sort" validate:"omitempty,oneof=created_at amount id"`
+	Order       string   `query:"order" validate:"omitempty,oneof=asc desc"`
+	Limit       int      `query:"limit" validate:"gte=0,lte=100"`
+	Cursor      string   `query:"cursor"`
+	Mode        string   // Set from the authenticated caller
+}
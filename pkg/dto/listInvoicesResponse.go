@@ -0,0 +1,6 @@
+package dto
+
+type ListInvoicesResponse struct {
+	Data       []*InvoiceResponse `json:"data"`
+	NextCursor string             `json:"next_cursor,omitempty"` // Empty on the last page
+}
@@ -0,0 +1,6 @@
+package dto
+
+type ListPaymentReviewsRequest struct {
+	MerchantID uint `query:"merchant_id"`
+	Limit      int  `query:"limit" validate:"gte=0,lte=100"`
+}
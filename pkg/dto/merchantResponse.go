@@ -0,0 +1,14 @@
+package dto
+
+import "time"
+
+type MerchantResponse struct {
+	ID                uint       `json:"id"`
+	MerchantName      string     `json:"merchant_name"`
+	MerchantCode      string     `json:"merchant_code"`
+	AllowedCurrencies []string   `json:"allowed_currencies"`
+	MerchantStatus    string     `json:"merchant_status"`
+	IsActive          bool       `json:"is_active"`
+	CreatedAt         *time.Time `json:"created_at"`
+	LastUpdatedAt     *time.Time `json:"last_updated_at"`
+}
@@ -0,0 +1,13 @@
+package dto
+
+import "time"
+
+type PaymentMethodResponse struct {
+	ID         uint       `json:"id"`
+	CustomerID uint       `json:"customer_id"`
+	MethodType string     `json:"method_type"`
+	Last4      string     `json:"last4"`
+	HolderName string     `json:"holder_name,omitempty"`
+	Expiry     string     `json:"expiry,omitempty"`
+	CreatedAt  *time.Time `json:"created_at"`
+}
@@ -0,0 +1,23 @@
+package dto
+
+import (
+	"go/payment-processor/pkg/risk"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PaymentReviewResponse is a payment held by the risk engine for manual review
+type PaymentReviewResponse struct {
+	PaymentID     uint            `json:"payment_id"`
+	InvoiceID     uint            `json:"invoice_id"`
+	MerchantID    uint            `json:"merchant_id"`
+	CustomerID    uint            `json:"customer_id"`
+	Amount        decimal.Decimal `json:"amount"`
+	PaymentMethod string          `json:"payment_method"`
+	PaymentStatus string          `json:"payment_status"`
+	RiskDecision  string          `json:"risk_decision"`
+	RiskScore     int             `json:"risk_score"`
+	RiskReasons   []risk.Reason   `json:"risk_reasons"`
+	CreatedAt     *time.Time      `json:"created_at"`
+}
@@ -0,0 +1,5 @@
+package dto
+
+type PaymentStatusResponse struct {
+	PaymentStatus string `json:"payment_status"`
+}
@@ -4,4 +4,9 @@ type ProcessPaymentRequest struct {
 	InvoiceID     uint   `json:"invoice_id" binding:"required"`
 	PaymentMethod string `json:"payment_method" binding:"required"`
 	PaymentSource string `json:"payment_source" binding:"required"`
+	// PaymentMethodID charges a payment method saved against the invoice's customer instead of PaymentSource
+	PaymentMethodID uint `json:"payment_method_id,omitempty"`
+	// BillingCountry is the ISO 3166-1 alpha-2 country of the payer, checked against the card's issuing country
+	BillingCountry string `json:"billing_country,omitempty" validate:"omitempty,iso3166_1_alpha2"`
+	ClientIP       string `json:"-"` // Set from the request
 }
@@ -2,11 +2,15 @@ package dto
 
 import "github.com/shopspring/decimal"
 
+// ProcessPaymentResponse is the outcome of a payment attempt. DeclineCode is only set for declined
+// payments; Message is safe to show to the payer.
 type ProcessPaymentResponse struct {
 	ID            uint            `json:"id"`
 	InvoiceID     uint            `json:"invoice_id"`
 	Amount        decimal.Decimal `json:"amount"`
 	PaymentStatus string          `json:"payment_status"`
+	DeclineCode   string          `json:"decline_code,omitempty"`
+	Message       string          `json:"message"`
 	PaymentMethod string          `json:"payment_method"`
 	PaymentSource string          `json:"payment_source"`
 }
@@ -0,0 +1,5 @@
+package dto
+
+type ResolveDisputeRequest struct {
+	Outcome string `json:"outcome" validate:"required,oneof=WON LOST"`
+}
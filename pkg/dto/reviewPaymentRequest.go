@@ -0,0 +1,7 @@
+package dto
+
+type ReviewPaymentRequest struct {
+	PaymentID uint   `json:"-"` // Set from the path
+	Decision  string `json:"decision" validate:"required,oneof=approve reject"`
+	Note      string `json:"note" validate:"max=500"`
+}
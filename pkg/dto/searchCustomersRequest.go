@@ -0,0 +1,7 @@
+package dto
+
+type SearchCustomersRequest struct {
+	Name  string `query:"name" validate:"max=255"`
+	Email string `query:"email" validate:"omitempty,max=255"`
+	Limit int    `query:"limit" validate:"gte=0,lte=100"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+// SubmitDisputeEvidenceRequest is bound from a multipart form; the file part is read separately by the handler.
+type SubmitDisputeEvidenceRequest struct {
+	DisputeID    uint   `form:"-"`
+	EvidenceType string `form:"evidence_type" validate:"required,oneof=receipt shipping_proof customer_communication refund_policy other"`
+	Description  string `form:"description" validate:"max=2000"`
+	FileName     string `form:"-" validate:"required"`
+	ContentType  string `form:"-"`
+	Content      []byte `form:"-" validate:"required"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+// TokenRequest is the OAuth2 client-credentials grant, bound from an url-encoded form.
+type TokenRequest struct {
+	GrantType    string `form:"grant_type" validate:"required,eq=client_credentials"`
+	ClientID     string `form:"client_id" validate:"required"`
+	ClientSecret string `form:"client_secret" validate:"required"`
+	Scope        string `form:"scope"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+// UpdateCustomerRequest is a partial update; nil fields are left unchanged.
+type UpdateCustomerRequest struct {
+	CustomerName    *string `json:"customer_name,omitempty" validate:"omitempty,min=1,max=255"`
+	CustomerEmail   *string `json:"customer_email,omitempty" validate:"omitempty,email,max=255"`
+	CustomerAddress *string `json:"customer_address,omitempty" validate:"omitempty,max=1000"`
+}
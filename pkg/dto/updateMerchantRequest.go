@@ -0,0 +1,9 @@
+package dto
+
+// UpdateMerchantRequest is a partial update; nil fields are left unchanged.
+type UpdateMerchantRequest struct {
+	MerchantName      *string  `json:"merchant_name,omitempty" validate:"omitempty,min=1,max=255"`
+	MerchantCode      *string  `json:"merchant_code,omitempty" validate:"omitempty,alphanum,max=50"`
+	AllowedCurrencies []string `json:"allowed_currencies,omitempty" validate:"omitempty,min=1,dive,iso4217"`
+	MerchantStatus    *string  `json:"merchant_status,omitempty" validate:"omitempty,oneof=active suspended"`
+}
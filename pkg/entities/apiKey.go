@@ -0,0 +1,20 @@
+package entities
+
+import "time"
+
+// ApiKey authenticates a merchant. Only the SHA-256 hash of the secret is stored;
+// the prefix identifies the key in logs and lookups without revealing it.
+type ApiKey struct {
+	AuditTrail
+	MerchantID uint       `gorm:"column:merchant_id" json:"merchant_id"`
+	Name       string     `gorm:"column:name" json:"name"`
+	Prefix     string     `gorm:"column:prefix" json:"prefix"`
+	KeyHash    string     `gorm:"column:key_hash" json:"-"`
+	Mode       string     `gorm:"column:mode" json:"mode"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at" json:"revoked_at,omitempty"`
+}
+
+func (ApiKey) TableName() string {
+	return "api_key"
+}
@@ -0,0 +1,22 @@
+package entities
+
+import "time"
+
+// AuditLog is one append-only record of a change to an audited table. Each record hashes the
+// previous one, so rewriting or removing a record breaks the chain from that point on.
+type AuditLog struct {
+	ID         uint       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	EntityType string     `gorm:"column:entity_type" json:"entity_type"` // Name of the audited table
+	EntityID   uint       `gorm:"column:entity_id" json:"entity_id"`
+	Action     string     `gorm:"column:action" json:"action"`
+	Actor      string     `gorm:"column:actor" json:"actor"`
+	RequestID  string     `gorm:"column:request_id" json:"request_id"`
+	Changes    string     `gorm:"column:changes" json:"changes"` // JSON object of column -> {before, after}
+	CreatedAt  *time.Time `gorm:"column:created_at" json:"created_at"`
+	PrevHash   string     `gorm:"column:prev_hash" json:"prev_hash"`
+	Hash       string     `gorm:"column:hash" json:"hash"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_log"
+}
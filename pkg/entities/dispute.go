@@ -0,0 +1,37 @@
+package entities
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type Dispute struct {
+	AuditTrail
+	PaymentID     uint            `gorm:"column:payment_id" json:"payment_id"`
+	InvoiceID     uint            `gorm:"column:invoice_id" json:"invoice_id"`
+	MerchantID    uint            `gorm:"column:merchant_id" json:"merchant_id"`
+	Amount        decimal.Decimal `gorm:"column:amount" json:"amount"`
+	Currency      string          `gorm:"column:currency" json:"currency"`
+	ReasonCode    string          `gorm:"column:reason_code" json:"reason_code"`
+	DisputeStatus string          `gorm:"column:dispute_status" json:"dispute_status"`
+	RespondBy     *time.Time      `gorm:"column:respond_by" json:"respond_by"` // Deadline for the merchant to submit evidence
+	ResolvedAt    *time.Time      `gorm:"column:resolved_at" json:"resolved_at,omitempty"`
+}
+
+func (Dispute) TableName() string {
+	return "dispute"
+}
+
+type DisputeEvidence struct {
+	AuditTrail
+	DisputeID    uint   `gorm:"column:dispute_id" json:"dispute_id"`
+	EvidenceType string `gorm:"column:evidence_type" json:"evidence_type"`
+	Description  string `gorm:"column:description" json:"description,omitempty"`
+	FileName     string `gorm:"column:file_name" json:"file_name"`
+	ContentType  string `gorm:"column:content_type" json:"content_type"`
+	Content      []byte `gorm:"column:content" json:"-"`
+}
+
+func (DisputeEvidence) TableName() string {
+	return "dispute_evidence"
+}
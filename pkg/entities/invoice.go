@@ -12,6 +12,8 @@ type Invoice struct {
 	Amount              decimal.Decimal `gorm:"column:amount" json:"amount"`
 	Currency            string          `gorm:"column:currency" json:"currency"`
 	OptionalDescription string          `gorm:"column:optional_description" json:"optional_description,omitempty"`
+	InvoiceStatus       string          `gorm:"column:invoice_status" json:"invoice_status"`
+	Mode                string          `gorm:"column:mode" json:"mode"` // test or live, taken from the API key that created it
 }
 
 func (Invoice) TableName() string {
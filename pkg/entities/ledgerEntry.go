@@ -0,0 +1,18 @@
+package entities
+
+import "github.com/shopspring/decimal"
+
+// LedgerEntry records a movement of funds for a merchant. Debits are stored as negative amounts.
+type LedgerEntry struct {
+	AuditTrail
+	MerchantID uint            `gorm:"column:merchant_id" json:"merchant_id"`
+	PaymentID  uint            `gorm:"column:payment_id" json:"payment_id"`
+	DisputeID  *uint           `gorm:"column:dispute_id" json:"dispute_id,omitempty"`
+	EntryType  string          `gorm:"column:entry_type" json:"entry_type"`
+	Amount     decimal.Decimal `gorm:"column:amount" json:"amount"`
+	Currency   string          `gorm:"column:currency" json:"currency"`
+}
+
+func (LedgerEntry) TableName() string {
+	return "ledger_entry"
+}
@@ -4,7 +4,8 @@ type Merchant struct {
 	AuditTrail
 	MerchantName    string `gorm:"column:merchant_name" json:"merchant_name"`
 	MerchantCode    string `gorm:"column:merchant_code" json:"merchant_code"`
-	AllowedCurrency string `gorm:"column:allowed_currency" json:"allowed_currency"`
+	AllowedCurrency string `gorm:"column:allowed_currency" json:"allowed_currency"` // Comma separated ISO 4217 codes
+	MerchantStatus  string `gorm:"column:merchant_status" json:"merchant_status"`
 }
 
 func (Merchant) TableName() string {
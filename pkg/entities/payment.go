@@ -11,6 +11,19 @@ type Payment struct {
 	PaymentStatus string          `gorm:"column:payment_status" json:"payment_status"`
 	PaymentMethod string          `gorm:"column:payment_method" json:"payment_method"`
 	PaymentSource string          `gorm:"column:payment_source" json:"payment_source"`
+	// PaymentMethodID is set when a saved payment method was charged; PaymentSource is then masked.
+	PaymentMethodID *uint `gorm:"column:payment_method_id" json:"payment_method_id,omitempty"`
+	// ReferenceID is the idempotency reference sent to the provider, ProviderPaymentID the ID it assigned.
+	ReferenceID       string `gorm:"column:reference_id" json:"reference_id"`
+	ProviderPaymentID string `gorm:"column:provider_payment_id" json:"provider_payment_id"`
+	// SourceFingerprint and ClientIP feed the risk engine's velocity rules.
+	SourceFingerprint string `gorm:"column:source_fingerprint" json:"-"`
+	ClientIP          string `gorm:"column:client_ip" json:"-"`
+	// RiskDecision, RiskScore and RiskReasons (a JSON array) record the risk assessment made before authorization.
+	RiskDecision string `gorm:"column:risk_decision" json:"risk_decision"`
+	RiskScore    int    `gorm:"column:risk_score" json:"risk_score"`
+	RiskReasons  string `gorm:"column:risk_reasons" json:"-"`
+	ReviewNote   string `gorm:"column:review_note" json:"review_note,omitempty"`
 }
 
 func (Payment) TableName() string {
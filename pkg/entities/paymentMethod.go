@@ -0,0 +1,17 @@
+package entities
+
+// PaymentMethod is a payment source saved against a customer. The source itself is only stored encrypted.
+type PaymentMethod struct {
+	AuditTrail
+	CustomerID      uint   `gorm:"column:customer_id" json:"customer_id"`
+	MethodType      string `gorm:"column:method_type" json:"method_type"`
+	EncryptedSource string `gorm:"column:encrypted_source" json:"-"`
+	Fingerprint     string `gorm:"column:fingerprint" json:"fingerprint"` // SHA-256 of the source, for duplicate detection
+	Last4           string `gorm:"column:last4" json:"last4"`
+	HolderName      string `gorm:"column:holder_name" json:"holder_name,omitempty"`
+	Expiry          string `gorm:"column:expiry" json:"expiry,omitempty"`
+}
+
+func (PaymentMethod) TableName() string {
+	return "payment_method"
+}
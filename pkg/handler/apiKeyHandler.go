@@ -0,0 +1,71 @@
+package http
+
+import (
+	"errors"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/dto"
+	services "go/payment-processor/pkg/service"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func (h *Handler) createAPIKey(c echo.Context) error {
+	var req dto.CreateAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger(c).Error("Invalid request payload", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid request payload")
+	}
+
+	principal := auth.GetPrincipal(c)
+	req.MerchantID = principal.MerchantID
+	if err := h.validator.Struct(req); err != nil {
+		h.logger(c).Error("Validation failed", zap.Error(err))
+		return apperror.Validation(err)
+	}
+	if req.Mode != principal.Mode {
+		h.logger(c).Warn("API key mode mismatch", zap.String("mode", req.Mode), zap.String("principal_mode", principal.Mode))
+		return services.ErrAPIKeyModeMismatch
+	}
+
+	apiKey, err := h.apiKeyService.CreateAPIKey(c.Request().Context(), &req)
+	if err != nil {
+		h.logger(c).Error("Failed to create API key", zap.Error(err))
+		return internalError("Failed to create API key", err)
+	}
+
+	return c.JSON(http.StatusCreated, apiKey)
+}
+
+func (h *Handler) listAPIKeys(c echo.Context) error {
+	apiKeys, err := h.apiKeyService.ListAPIKeys(c.Request().Context(), auth.GetPrincipal(c).MerchantID)
+	if err != nil {
+		h.logger(c).Error("Failed to list API keys", zap.Error(err))
+		return internalError("Failed to list API keys", err)
+	}
+
+	return c.JSON(http.StatusOK, apiKeys)
+}
+
+func (h *Handler) revokeAPIKey(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger(c).Error("Invalid API key ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid API key ID")
+	}
+
+	principal := auth.GetPrincipal(c)
+	if err := h.apiKeyService.RevokeAPIKey(c.Request().Context(), principal.MerchantID, uint(id), principal.Mode); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.ErrNotFound.WithMessage("API key not found")
+		}
+		h.logger(c).Error("Failed to revoke API key", zap.Error(err))
+		return internalError("Failed to revoke API key", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
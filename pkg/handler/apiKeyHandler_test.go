@@ -0,0 +1,101 @@
+package http
+
+import (
+	"context"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/repository"
+	services "go/payment-processor/pkg/service"
+	"go/payment-processor/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// fakeAPIKeyRepository holds the keys of merchant 1 and records the revoked ones
+type fakeAPIKeyRepository struct {
+	repository.Repository
+	keys    map[uint]*entities.ApiKey
+	revoked []uint
+}
+
+func (r *fakeAPIKeyRepository) GetMerchantByID(_ context.Context, id uint) (*entities.Merchant, error) {
+	merchant := &entities.Merchant{MerchantStatus: utils.MerchantStatusActive}
+	merchant.ID = id
+	return merchant, nil
+}
+
+func (r *fakeAPIKeyRepository) CreateAPIKey(_ context.Context, apiKey *entities.ApiKey) (*entities.ApiKey, error) {
+	apiKey.ID = uint(len(r.keys) + 1)
+	r.keys[apiKey.ID] = apiKey
+	return apiKey, nil
+}
+
+func (r *fakeAPIKeyRepository) GetAPIKeyByID(_ context.Context, merchantID, apiKeyID uint) (*entities.ApiKey, error) {
+	apiKey, ok := r.keys[apiKeyID]
+	if !ok || apiKey.MerchantID != merchantID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return apiKey, nil
+}
+
+func (r *fakeAPIKeyRepository) RevokeAPIKey(_ context.Context, _, apiKeyID uint) error {
+	r.revoked = append(r.revoked, apiKeyID)
+	return nil
+}
+
+// modeAPIKeyService runs the real API key service but authenticates every caller as merchantPrincipal
+type modeAPIKeyService struct{ services.APIKeyService }
+
+func (modeAPIKeyService) Authenticate(context.Context, string) (*auth.Principal, error) {
+	return merchantPrincipal, nil
+}
+
+func TestAPIKeysOnlyManageKeysOfTheirMode(t *testing.T) {
+	repo := &fakeAPIKeyRepository{keys: map[uint]*entities.ApiKey{
+		1: {MerchantID: 1, Prefix: "sk_test_0000000a", Mode: utils.APIKeyModeTest},
+		2: {MerchantID: 1, Prefix: "sk_live_0000000b", Mode: utils.APIKeyModeLive},
+	}}
+	apiKeys := services.NewAPIKeyService(zap.NewNop(), repo, validator.New())
+	h := &Handler{log: zap.NewNop(), validator: validator.New(), tokenIssuer: &auth.TokenIssuer{},
+		apiKeyService: modeAPIKeyService{apiKeys}}
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler(zap.NewNop())
+	Register(e.Group(contractPrefix), h)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{"create a test key", http.MethodPost, "/api-keys", `{"name":"ci","mode":"test"}`, http.StatusCreated},
+		{"create a live key with a test key", http.MethodPost, "/api-keys", `{"name":"prod","mode":"live"}`, http.StatusForbidden},
+		{"revoke a live key with a test key", http.MethodDelete, "/api-keys/2", "", http.StatusForbidden},
+		{"revoke a test key", http.MethodDelete, "/api-keys/1", "", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, contractPrefix+tt.target, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			}
+			req.Header.Set(apiKeyHeader, "merchant")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, tt.status, rec.Code, rec.Body.String())
+		})
+	}
+
+	assert.Len(t, repo.keys, 3)
+	assert.Equal(t, utils.APIKeyModeTest, repo.keys[3].Mode)
+	assert.Equal(t, []uint{1}, repo.revoked)
+}
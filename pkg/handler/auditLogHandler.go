@@ -0,0 +1,41 @@
+package http
+
+import (
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/dto"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) listAuditLogs(c echo.Context) error {
+	var req dto.ListAuditLogsRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger(c).Error("Invalid query parameters", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid query parameters")
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.logger(c).Error("Validation failed", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	logs, err := h.auditLogService.ListAuditLogs(c.Request().Context(), &req)
+	if err != nil {
+		h.logger(c).Error("Failed to list audit logs", zap.Error(err))
+		return internalError("Failed to list audit logs", err)
+	}
+
+	return c.JSON(http.StatusOK, logs)
+}
+
+func (h *Handler) verifyAuditLog(c echo.Context) error {
+	result, err := h.auditLogService.VerifyAuditLog(c.Request().Context())
+	if err != nil {
+		h.logger(c).Error("Failed to verify audit log", zap.Error(err))
+		return internalError("Failed to verify audit log", err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,178 @@
+package http
+
+import (
+	"errors"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/dto"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func (h *Handler) createCustomer(c echo.Context) error {
+	var req dto.CreateCustomerRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger(c).Error("Invalid request payload", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid request payload")
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.logger(c).Error("Validation failed", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	customer, err := h.customerService.CreateCustomer(c.Request().Context(), &req)
+	if err != nil {
+		return h.customerError(c, "Failed to create customer", err)
+	}
+
+	return c.JSON(http.StatusCreated, customer)
+}
+
+func (h *Handler) searchCustomers(c echo.Context) error {
+	var req dto.SearchCustomersRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger(c).Error("Invalid search parameters", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid search parameters")
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.logger(c).Error("Validation failed", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	customers, err := h.customerService.SearchCustomers(c.Request().Context(), &req)
+	if err != nil {
+		return h.customerError(c, "Failed to search customers", err)
+	}
+
+	return c.JSON(http.StatusOK, customers)
+}
+
+func (h *Handler) getCustomer(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger(c).Error("Invalid customer ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid customer ID")
+	}
+
+	customer, err := h.customerService.GetCustomerByID(c.Request().Context(), uint(id))
+	if err != nil {
+		return h.customerError(c, "Failed to fetch customer", err)
+	}
+
+	return c.JSON(http.StatusOK, customer)
+}
+
+func (h *Handler) updateCustomer(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger(c).Error("Invalid customer ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid customer ID")
+	}
+
+	var req dto.UpdateCustomerRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger(c).Error("Invalid request payload", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid request payload")
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.logger(c).Error("Validation failed", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	customer, err := h.customerService.UpdateCustomer(c.Request().Context(), uint(id), &req)
+	if err != nil {
+		return h.customerError(c, "Failed to update customer", err)
+	}
+
+	return c.JSON(http.StatusOK, customer)
+}
+
+func (h *Handler) deleteCustomer(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger(c).Error("Invalid customer ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid customer ID")
+	}
+
+	if err := h.customerService.DeleteCustomer(c.Request().Context(), uint(id)); err != nil {
+		return h.customerError(c, "Failed to delete customer", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) addPaymentMethod(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger(c).Error("Invalid customer ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid customer ID")
+	}
+
+	var req dto.CreatePaymentMethodRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger(c).Error("Invalid request payload", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid request payload")
+	}
+
+	req.CustomerID = uint(id)
+	if err := h.validator.Struct(req); err != nil {
+		h.logger(c).Error("Validation failed", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	paymentMethod, err := h.customerService.AddPaymentMethod(c.Request().Context(), &req)
+	if err != nil {
+		return h.customerError(c, "Failed to save payment method", err)
+	}
+
+	return c.JSON(http.StatusCreated, paymentMethod)
+}
+
+func (h *Handler) getPaymentMethods(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger(c).Error("Invalid customer ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid customer ID")
+	}
+
+	paymentMethods, err := h.customerService.GetPaymentMethods(c.Request().Context(), uint(id))
+	if err != nil {
+		return h.customerError(c, "Failed to fetch payment methods", err)
+	}
+
+	return c.JSON(http.StatusOK, paymentMethods)
+}
+
+func (h *Handler) deletePaymentMethod(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger(c).Error("Invalid customer ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid customer ID")
+	}
+	paymentMethodID, err := strconv.Atoi(c.Param("paymentMethodId"))
+	if err != nil {
+		h.logger(c).Error("Invalid payment method ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid payment method ID")
+	}
+
+	if err := h.customerService.DeletePaymentMethod(c.Request().Context(), uint(id), uint(paymentMethodID)); err != nil {
+		return h.customerError(c, "Failed to delete payment method", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// customerError maps customer service errors to a response status
+func (h *Handler) customerError(c echo.Context, msg string, err error) error {
+	h.logger(c).Error(msg, zap.Error(err))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperror.ErrNotFound.WithMessage("Not found")
+	}
+	return internalError(msg, err)
+}
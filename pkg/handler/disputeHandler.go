@@ -0,0 +1,163 @@
+package http
+
+import (
+	"errors"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/dto"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// maxEvidenceSize caps a single evidence upload at 10 MiB
+const maxEvidenceSize = 10 << 20
+
+func (h *Handler) openDispute(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger(c).Error("Invalid payment ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid payment ID")
+	}
+
+	var req dto.CreateDisputeRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger(c).Error("Invalid request payload", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid request payload")
+	}
+
+	req.PaymentID = uint(id)
+	if err := h.validator.Struct(req); err != nil {
+		h.logger(c).Error("Validation failed", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	dispute, err := h.disputeService.OpenDispute(c.Request().Context(), &req)
+	if err != nil {
+		return h.disputeError(c, "Failed to open dispute", err)
+	}
+
+	return c.JSON(http.StatusCreated, dispute)
+}
+
+func (h *Handler) getDispute(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger(c).Error("Invalid dispute ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid dispute ID")
+	}
+
+	dispute, err := h.disputeService.GetDisputeByID(c.Request().Context(), uint(id))
+	if err != nil {
+		return h.disputeError(c, "Failed to fetch dispute", err)
+	}
+	if !h.ownsDispute(c, dispute) {
+		return apperror.ErrNotFound.WithMessage("Not found")
+	}
+
+	return c.JSON(http.StatusOK, dispute)
+}
+
+func (h *Handler) submitDisputeEvidence(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger(c).Error("Invalid dispute ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid dispute ID")
+	}
+
+	dispute, err := h.disputeService.GetDisputeByID(c.Request().Context(), uint(id))
+	if err != nil {
+		return h.disputeError(c, "Failed to fetch dispute", err)
+	}
+	if !h.ownsDispute(c, dispute) {
+		return apperror.ErrNotFound.WithMessage("Not found")
+	}
+
+	var req dto.SubmitDisputeEvidenceRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger(c).Error("Invalid request payload", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid request payload")
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		h.logger(c).Error("Evidence file missing", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Evidence file is required")
+	}
+	if file.Size > maxEvidenceSize {
+		return apperror.ErrTooLarge.WithMessage("Evidence file is too large")
+	}
+	src, err := file.Open()
+	if err != nil {
+		h.logger(c).Error("Failed to open evidence file", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid evidence file")
+	}
+	defer src.Close()
+
+	req.Content, err = io.ReadAll(io.LimitReader(src, maxEvidenceSize))
+	if err != nil {
+		h.logger(c).Error("Failed to read evidence file", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid evidence file")
+	}
+	req.DisputeID = uint(id)
+	req.FileName = file.Filename
+	req.ContentType = file.Header.Get(echo.HeaderContentType)
+
+	if err := h.validator.Struct(req); err != nil {
+		h.logger(c).Error("Validation failed", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	evidence, err := h.disputeService.SubmitEvidence(c.Request().Context(), &req)
+	if err != nil {
+		return h.disputeError(c, "Failed to submit dispute evidence", err)
+	}
+
+	return c.JSON(http.StatusCreated, evidence)
+}
+
+func (h *Handler) resolveDispute(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger(c).Error("Invalid dispute ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid dispute ID")
+	}
+
+	var req dto.ResolveDisputeRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger(c).Error("Invalid request payload", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid request payload")
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.logger(c).Error("Validation failed", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	dispute, err := h.disputeService.ResolveDispute(c.Request().Context(), uint(id), &req)
+	if err != nil {
+		return h.disputeError(c, "Failed to resolve dispute", err)
+	}
+
+	return c.JSON(http.StatusOK, dispute)
+}
+
+// disputeError maps dispute service errors to a response status. A missing dispute or payment is a 404;
+// the other dispute errors, such as ErrDisputeClosed, are apperrors that keep their own status.
+func (h *Handler) disputeError(c echo.Context, msg string, err error) error {
+	h.logger(c).Error(msg, zap.Error(err))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperror.ErrNotFound.WithMessage("Not found")
+	}
+	return internalError(msg, err)
+}
+
+// ownsDispute reports whether the caller is the merchant being disputed or an internal service
+func (h *Handler) ownsDispute(c echo.Context, dispute *dto.DisputeResponse) bool {
+	principal := auth.GetPrincipal(c)
+	return principal != nil && (principal.ActsForAnyMerchant() || principal.MerchantID == dispute.MerchantID)
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/logging"
+	"go/payment-processor/pkg/requestid"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrorHandler renders every error returned by a handler or middleware as RFC 7807 problem
+// details. Errors without a code are logged and reported as internal errors.
+func ErrorHandler(logger *zap.Logger) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		if c.Response().Committed {
+			return
+		}
+
+		appErr := toAppError(err)
+		requestID := c.Response().Header().Get(requestid.Header)
+		if requestID == "" {
+			requestID = requestid.FromContext(c.Request().Context())
+		}
+
+		log := logging.FromContext(c.Request().Context(), logger)
+		fields := []zap.Field{zap.Int("status", appErr.Status), zap.String("code", appErr.Code),
+			zap.String("path", c.Request().URL.Path), zap.Error(err)}
+		if appErr.Status >= http.StatusInternalServerError {
+			log.Error("Request failed", fields...)
+		} else {
+			log.Info("Request rejected", fields...)
+		}
+
+		if appErr.RetryAfter > 0 {
+			c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(ceilSeconds(appErr.RetryAfter)))
+		}
+		if c.Request().Method == http.MethodHead {
+			err = c.NoContent(appErr.Status)
+		} else {
+			c.Response().Header().Set(echo.HeaderContentType, apperror.ProblemContentType)
+			err = c.JSON(appErr.Status, appErr.Problem(c.Request().URL.Path, requestID))
+		}
+		if err != nil {
+			log.Error("Failed to write error response", zap.Error(err))
+		}
+	}
+}
+
+// toAppError maps the errors of Echo and GORM that reach the error handler to their generic codes
+func toAppError(err error) *apperror.Error {
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		generic := apperror.FromStatus(httpErr.Code)
+		if message, ok := httpErr.Message.(string); ok && httpErr.Code < http.StatusInternalServerError {
+			generic = generic.WithMessage(message)
+		} else if httpErr.Code < http.StatusInternalServerError {
+			generic = generic.WithMessage(fmt.Sprint(httpErr.Message))
+		}
+		return generic.WithCause(err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperror.ErrNotFound.WithCause(err)
+	}
+	return apperror.From(err)
+}
+
+// internalError passes errors with a code through and reports any other error as an internal
+// error with the given message
+func internalError(msg string, err error) error {
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) {
+		return err
+	}
+	return apperror.ErrInternal.WithMessage(msg).WithCause(err)
+}
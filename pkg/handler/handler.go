@@ -1,9 +1,22 @@
 package http
 
 import (
+	"errors"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/auth"
 	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/idempotency"
+	"go/payment-processor/pkg/logging"
+	"go/payment-processor/pkg/mapper"
+	"go/payment-processor/pkg/metrics"
+	"go/payment-processor/pkg/payments/inflight"
+	"go/payment-processor/pkg/ratelimit"
 	"go/payment-processor/pkg/repository"
+	"go/payment-processor/pkg/risk"
 	services "go/payment-processor/pkg/service"
+	"go/payment-processor/pkg/vault"
+	"go/payment-processor/pkg/velocity"
 	"net/http"
 	"strconv"
 
@@ -13,46 +26,170 @@ import (
 	"gorm.io/gorm"
 )
 
-func RegisterRoutes(e *echo.Group, db *gorm.DB, logger *zap.Logger, validator *validator.Validate) {
-	handler := NewHandler(db, logger, validator)
-	e.POST("/invoices", handler.CreateInvoice)
-	e.GET("/invoices/:id", handler.getInvoice)
-	e.POST("/invoices/:id/payments", handler.processPayment)
-	e.GET("/invoices/:id/payment-status", handler.getPaymentStatus)
+// Dependencies are the collaborators of the handlers besides the database
+type Dependencies struct {
+	Gateway services.PaymentGateway
+	Vault   *vault.Vault // nil disables saved payment methods
+	// Fingerprints identifies payment sources, nil uses a random key that does not survive a restart
+	Fingerprints *vault.Fingerprinter
+	RiskEngine   *risk.Engine       // nil sends every payment to the provider without a risk assessment
+	Velocity     *velocity.Guard    // nil disables blocking after repeated declines
+	JWTValidator *auth.JWTValidator // nil disables bearer tokens for internal services
+	TokenIssuer  *auth.TokenIssuer  // nil disables the local token endpoint
+	RateLimiter  *ratelimit.Limiter // nil disables rate limiting
+	Idempotency  idempotency.Store  // nil ignores Idempotency-Key headers
+	InFlight     *inflight.Tracker  // nil does not track the payments being authorized
+	Metrics      *metrics.Metrics   // nil records no metrics
 }
 
-type Handler struct {
-	log            *zap.Logger
-	validator      *validator.Validate
-	invoiceService services.InvoiceService
-	paymentService services.PaymentService
+// Services are the business services behind the handlers.
+type Services struct {
+	Invoices  services.InvoiceService
+	Payments  services.PaymentService
+	Disputes  services.DisputeService
+	Merchants services.MerchantService
+	Customers services.CustomerService
+	APIKeys   services.APIKeyService
+	AuditLogs services.AuditLogService
 }
 
-func NewHandler(db *gorm.DB, logger *zap.Logger, validate *validator.Validate) *Handler {
+// NewServices builds the services on top of the database.
+func NewServices(db *gorm.DB, logger *zap.Logger, validate *validator.Validate, deps Dependencies) Services {
 	repo := repository.NewRepository(db, logger)
-	invoiceService := services.NewInvoiceService(logger, repo, validate)
-	paymentService := services.NewPaymentService(logger, repo, validate)
+	fingerprints := deps.Fingerprints
+	if fingerprints == nil {
+		fingerprints = vault.NewEphemeralFingerprinter()
+	}
+	return Services{
+		Invoices:  services.NewInvoiceService(logger, repo, validate, deps.Metrics),
+		Payments:  services.NewPaymentService(logger, repo, validate, deps.Gateway, deps.Vault, fingerprints, deps.RiskEngine, deps.Velocity, deps.InFlight, deps.Metrics),
+		Disputes:  services.NewDisputeService(logger, repo, validate),
+		Merchants: services.NewMerchantService(logger, repo, validate),
+		Customers: services.NewCustomerService(logger, repo, validate, deps.Vault, fingerprints),
+		APIKeys:   services.NewAPIKeyService(logger, repo, validate),
+		AuditLogs: services.NewAuditLogService(logger, repo, validate),
+	}
+}
 
-	return &Handler{log: logger, validator: validate, invoiceService: invoiceService, paymentService: paymentService}
+func RegisterRoutes(e *echo.Group, db *gorm.DB, logger *zap.Logger, validator *validator.Validate, deps Dependencies) {
+	Register(e, NewHandler(db, logger, validator, deps))
+}
+
+// Register registers the routes of handler. Every route must be documented in apiRoutes.
+func Register(e *echo.Group, handler *Handler) {
+	// Every request counts against the client IP, authenticated ones also against the caller
+	e.Use(handler.limitByIP)
+	authenticate := chain(handler.authenticate, handler.limitByCaller, handler.idempotent)
+
+	read := requireScope(auth.ScopeRead)
+	invoicesWrite := requireScope(auth.ScopeInvoicesWrite)
+	paymentsWrite := requireScope(auth.ScopePaymentsWrite)
+	refundsWrite := requireScope(auth.ScopeRefundsWrite)
+
+	// Merchant routes accept merchant API keys, which only expose the merchant's own resources,
+	// and internal service tokens
+	e.POST("/invoices", handler.CreateInvoice, authenticate, invoicesWrite, requirePermission(auth.PermissionInvoicesWrite))
+	e.GET("/invoices", handler.listInvoices, authenticate, read, requirePermission(auth.PermissionInvoicesRead))
+	e.GET("/invoices/:id", handler.getInvoice, authenticate, read, requirePermission(auth.PermissionInvoicesRead))
+	e.POST("/invoices/:id/payments", handler.processPayment, authenticate, paymentsWrite, requirePermission(auth.PermissionPaymentsWrite))
+	e.POST("/invoices/:id/payments/:paymentId/challenge", handler.completePaymentChallenge, authenticate, paymentsWrite, requirePermission(auth.PermissionPaymentsWrite))
+	e.GET("/invoices/:id/payment-status", handler.getPaymentStatus, authenticate, read, requirePermission(auth.PermissionInvoicesRead))
+	e.GET("/disputes/:id", handler.getDispute, authenticate, read, requirePermission(auth.PermissionDisputesRead))
+	e.POST("/disputes/:id/evidence", handler.submitDisputeEvidence, authenticate, refundsWrite, requirePermission(auth.PermissionDisputesRespond))
+	e.GET("/api-keys", handler.listAPIKeys, authenticate, merchantOnly, requirePermission(auth.PermissionAPIKeysManage))
+	e.POST("/api-keys", handler.createAPIKey, authenticate, merchantOnly, requirePermission(auth.PermissionAPIKeysManage))
+	e.DELETE("/api-keys/:id", handler.revokeAPIKey, authenticate, merchantOnly, requirePermission(auth.PermissionAPIKeysManage))
+
+	// Back-office routes are only available to internal services, depending on their roles
+	e.POST("/payments/:id/disputes", handler.openDispute, authenticate, internalOnly, refundsWrite, requirePermission(auth.PermissionDisputesOpen))
+	e.GET("/payment-reviews", handler.listPaymentReviews, authenticate, internalOnly, read, requirePermission(auth.PermissionPaymentsReview))
+	e.GET("/velocity/metrics", handler.getVelocityMetrics, authenticate, internalOnly, read, requirePermission(auth.PermissionPaymentsReview))
+	e.POST("/payments/:id/review", handler.reviewPayment, authenticate, internalOnly, paymentsWrite, requirePermission(auth.PermissionPaymentsReview))
+	e.POST("/disputes/:id/resolve", handler.resolveDispute, authenticate, internalOnly, refundsWrite, requirePermission(auth.PermissionDisputesResolve))
+	e.POST("/merchants", handler.createMerchant, authenticate, internalOnly, requirePermission(auth.PermissionMerchantsWrite))
+	e.GET("/merchants/:id", handler.getMerchant, authenticate, internalOnly, read, requirePermission(auth.PermissionMerchantsRead))
+	e.PATCH("/merchants/:id", handler.updateMerchant, authenticate, internalOnly, requirePermission(auth.PermissionMerchantsWrite))
+	e.DELETE("/merchants/:id", handler.deleteMerchant, authenticate, internalOnly, requirePermission(auth.PermissionMerchantsWrite))
+	e.POST("/customers", handler.createCustomer, authenticate, internalOnly, paymentsWrite, requirePermission(auth.PermissionCustomersWrite))
+	e.GET("/customers", handler.searchCustomers, authenticate, internalOnly, read, requirePermission(auth.PermissionCustomersRead))
+	e.GET("/customers/:id", handler.getCustomer, authenticate, internalOnly, read, requirePermission(auth.PermissionCustomersRead))
+	e.PATCH("/customers/:id", handler.updateCustomer, authenticate, internalOnly, paymentsWrite, requirePermission(auth.PermissionCustomersWrite))
+	e.DELETE("/customers/:id", handler.deleteCustomer, authenticate, internalOnly, paymentsWrite, requirePermission(auth.PermissionCustomersWrite))
+	e.POST("/customers/:id/payment-methods", handler.addPaymentMethod, authenticate, internalOnly, paymentsWrite, requirePermission(auth.PermissionCustomersWrite))
+	e.GET("/customers/:id/payment-methods", handler.getPaymentMethods, authenticate, internalOnly, read, requirePermission(auth.PermissionCustomersRead))
+	e.DELETE("/customers/:id/payment-methods/:paymentMethodId", handler.deletePaymentMethod, authenticate, internalOnly, paymentsWrite, requirePermission(auth.PermissionCustomersWrite))
+	e.GET("/audit-logs", handler.listAuditLogs, authenticate, internalOnly, read, requirePermission(auth.PermissionAuditLogRead))
+	e.GET("/audit-logs/verify", handler.verifyAuditLog, authenticate, internalOnly, read, requirePermission(auth.PermissionAuditLogRead))
+
+	// Token endpoint for tests and local environments
+	if handler.tokenIssuer != nil {
+		e.POST("/oauth/token", handler.issueToken)
+	}
+}
+
+type Handler struct {
+	log             *zap.Logger
+	validator       *validator.Validate
+	jwtValidator    *auth.JWTValidator
+	tokenIssuer     *auth.TokenIssuer
+	rateLimiter     *ratelimit.Limiter
+	idempotency     idempotency.Store
+	invoiceService  services.InvoiceService
+	paymentService  services.PaymentService
+	disputeService  services.DisputeService
+	merchantService services.MerchantService
+	customerService services.CustomerService
+	apiKeyService   services.APIKeyService
+	auditLogService services.AuditLogService
+}
+
+func NewHandler(db *gorm.DB, logger *zap.Logger, validate *validator.Validate, deps Dependencies) *Handler {
+	return NewHandlerWithServices(logger, validate, NewServices(db, logger, validate, deps), deps)
+}
+
+// NewHandlerWithServices builds the handlers on top of the given services, which lets tests serve
+// the real routes without a database.
+func NewHandlerWithServices(logger *zap.Logger, validate *validator.Validate, svc Services, deps Dependencies) *Handler {
+	return &Handler{log: logger, validator: validate, jwtValidator: deps.JWTValidator, tokenIssuer: deps.TokenIssuer,
+		rateLimiter: deps.RateLimiter, idempotency: deps.Idempotency,
+		invoiceService: svc.Invoices, paymentService: svc.Payments, disputeService: svc.Disputes,
+		merchantService: svc.Merchants, customerService: svc.Customers, apiKeyService: svc.APIKeys,
+		auditLogService: svc.AuditLogs}
+}
+
+// logger returns the logger of the request, carrying its request ID and the merchant and invoice it concerns
+func (h *Handler) logger(c echo.Context) *zap.Logger {
+	return logging.FromContext(c.Request().Context(), h.log)
 }
 
 func (h *Handler) CreateInvoice(c echo.Context) error {
 	var req dto.CreateInvoiceRequest
 	if err := c.Bind(&req); err != nil {
-		h.log.Error("Invalid request payload", zap.Error(err))
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+		h.logger(c).Error("Invalid request payload", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid request payload")
+	}
+
+	principal := auth.GetPrincipal(c)
+	if req.MerchantID == 0 {
+		req.MerchantID = principal.MerchantID
+	}
+	if !principal.ActsForAnyMerchant() && req.MerchantID != principal.MerchantID {
+		h.logger(c).Warn("Merchant attempted to invoice on behalf of another merchant",
+			zap.Uint("merchant_id", principal.MerchantID), zap.Uint("requested_merchant_id", req.MerchantID))
+		return apperror.ErrForbidden.WithMessage("Cannot create invoices for another merchant")
 	}
+	req.Mode = principal.Mode
 
 	if err := h.validator.Struct(req); err != nil {
-		h.log.Error("Validation failed", zap.Error(err))
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		h.logger(c).Error("Validation failed", zap.Error(err))
+		return apperror.Validation(err)
 	}
 
 	// Create invoice
-	invoice, err := h.invoiceService.CreateInvoice(&req)
+	invoice, err := h.invoiceService.CreateInvoice(c.Request().Context(), &req)
 	if err != nil {
-		h.log.Error("Failed to create invoice", zap.Error(err))
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create invoice"})
+		h.logger(c).Error("Failed to create invoice", zap.Error(err))
+		return internalError("Failed to create invoice", err)
 	}
 
 	return c.JSON(http.StatusCreated, invoice)
@@ -61,61 +198,165 @@ func (h *Handler) CreateInvoice(c echo.Context) error {
 func (h *Handler) getInvoice(c echo.Context) error {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		h.log.Error("Invalid invoice ID", zap.Error(err))
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid invoice ID"})
+		h.logger(c).Error("Invalid invoice ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid invoice ID")
 	}
 
-	invoice, err := h.invoiceService.GetInvoiceByID(uint(id))
-	if err != nil {
-		h.log.Error("Invoice not found", zap.Error(err))
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Invoice not found"})
+	invoice, err := h.invoiceService.GetInvoiceByID(c.Request().Context(), uint(id))
+	if err != nil || !h.ownsInvoice(c, invoice) {
+		h.logger(c).Error("Invoice not found", zap.Error(err))
+		return services.ErrInvoiceNotFound
 	}
 
 	return c.JSON(http.StatusOK, invoice)
 }
 
+func (h *Handler) listInvoices(c echo.Context) error {
+	var req dto.ListInvoicesRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger(c).Error("Invalid query parameters", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid query parameters")
+	}
+
+	principal := auth.GetPrincipal(c)
+	if !principal.ActsForAnyMerchant() {
+		if req.MerchantID != 0 && req.MerchantID != principal.MerchantID {
+			return apperror.ErrForbidden.WithMessage("Cannot list invoices of another merchant")
+		}
+		req.MerchantID = principal.MerchantID
+	}
+	req.Mode = principal.Mode
+
+	if err := h.validator.Struct(req); err != nil {
+		h.logger(c).Error("Validation failed", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	invoices, err := h.invoiceService.ListInvoices(c.Request().Context(), &req)
+	if err != nil {
+		h.logger(c).Error("Failed to list invoices", zap.Error(err))
+		return internalError("Failed to list invoices", err)
+	}
+
+	return c.JSON(http.StatusOK, invoices)
+}
+
 func (h *Handler) processPayment(c echo.Context) error {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		h.log.Error("Invalid invoice ID", zap.Error(err))
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid invoice ID"})
+		h.logger(c).Error("Invalid invoice ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid invoice ID")
 	}
 
 	var req dto.ProcessPaymentRequest
 	if err := c.Bind(&req); err != nil {
-		h.log.Error("Invalid request payload", zap.Error(err))
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+		h.logger(c).Error("Invalid request payload", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid request payload")
 	}
 
 	// Validate request
 	req.InvoiceID = uint(id)
+	req.ClientIP = c.RealIP()
 	if err := h.validator.Struct(req); err != nil {
-		h.log.Error("Validation failed", zap.Error(err))
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		h.logger(c).Error("Validation failed", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	if invoice, err := h.invoiceService.GetInvoiceByID(c.Request().Context(), req.InvoiceID); err != nil || !h.ownsInvoice(c, invoice) {
+		h.logger(c).Error("Invoice not found", zap.Uint("invoice_id", req.InvoiceID), zap.Error(err))
+		return services.ErrInvoiceNotFound
 	}
 
 	// Process payment
-	payment, err := h.paymentService.ProcessPayment(&req)
+	payment, err := h.paymentService.ProcessPayment(c.Request().Context(), &req)
+	if err != nil {
+		h.logger(c).Error("Failed to process payment", zap.Error(err))
+		return internalError("Failed to process payment", err)
+	}
+
+	return paymentResult(c, payment)
+}
+
+// completePaymentChallenge finishes the simulated 3-D Secure challenge of a REQUIRES_ACTION payment
+func (h *Handler) completePaymentChallenge(c echo.Context) error {
+	invoiceID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger(c).Error("Invalid invoice ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid invoice ID")
+	}
+	paymentID, err := strconv.Atoi(c.Param("paymentId"))
+	if err != nil {
+		h.logger(c).Error("Invalid payment ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid payment ID")
+	}
+
+	var req dto.CompleteChallengeRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger(c).Error("Invalid request payload", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid request payload")
+	}
+
+	req.InvoiceID = uint(invoiceID)
+	req.PaymentID = uint(paymentID)
+	if err := h.validator.Struct(req); err != nil {
+		h.logger(c).Error("Validation failed", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	if invoice, err := h.invoiceService.GetInvoiceByID(c.Request().Context(), req.InvoiceID); err != nil || !h.ownsInvoice(c, invoice) {
+		h.logger(c).Error("Invoice not found", zap.Uint("invoice_id", req.InvoiceID), zap.Error(err))
+		return services.ErrInvoiceNotFound
+	}
+
+	payment, err := h.paymentService.CompletePaymentChallenge(c.Request().Context(), &req)
 	if err != nil {
-		h.log.Error("Failed to process payment", zap.Error(err))
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to process payment"})
+		h.logger(c).Error("Failed to complete payment challenge", zap.Error(err))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.ErrNotFound.WithMessage("Payment not found")
+		}
+		return internalError("Failed to complete payment challenge", err)
 	}
 
-	return c.JSON(http.StatusOK, payment)
+	return paymentResult(c, payment)
 }
 
 func (h *Handler) getPaymentStatus(c echo.Context) error {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		h.log.Error("Invalid invoice ID", zap.Error(err))
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid invoice ID"})
+		h.logger(c).Error("Invalid invoice ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid invoice ID")
 	}
 
-	status, err := h.paymentService.GetPaymentStatus(uint(id))
+	if invoice, err := h.invoiceService.GetInvoiceByID(c.Request().Context(), uint(id)); err != nil || !h.ownsInvoice(c, invoice) {
+		h.logger(c).Error("Invoice not found", zap.Int("invoice_id", id), zap.Error(err))
+		return services.ErrInvoiceNotFound
+	}
+
+	status, err := h.paymentService.GetPaymentStatus(c.Request().Context(), uint(id))
 	if err != nil {
-		h.log.Error("Payment status not found", zap.Error(err))
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Payment status not found"})
+		h.logger(c).Error("Payment status not found", zap.Error(err))
+		return apperror.ErrNotFound.WithMessage("Payment status not found")
+	}
+
+	return c.JSON(http.StatusOK, dto.PaymentStatusResponse{PaymentStatus: status})
+}
+
+// paymentResult responds with the outcome of a payment attempt. Declines are a business result rather
+// than an error, so they carry the payment with 402 Payment Required.
+func paymentResult(c echo.Context, payment *entities.Payment) error {
+	response := mapper.ToPaymentResponse(payment)
+	if response.DeclineCode != "" {
+		return c.JSON(http.StatusPaymentRequired, response)
 	}
+	return c.JSON(http.StatusOK, response)
+}
 
-	return c.JSON(http.StatusOK, map[string]string{"payment_status": status})
+// ownsInvoice reports whether the caller may see the invoice. Invoices of other merchants, or created
+// with a key of the other mode, are reported as not found. Internal services see every invoice.
+func (h *Handler) ownsInvoice(c echo.Context, invoice *dto.InvoiceResponse) bool {
+	principal := auth.GetPrincipal(c)
+	if principal == nil {
+		return false
+	}
+	return principal.ActsForAnyMerchant() || (principal.MerchantID == invoice.MerchantID && principal.Mode == invoice.Mode)
 }
@@ -0,0 +1,48 @@
+package http
+
+import (
+	"go/payment-processor/pkg/health"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// statusResponse is the body of the status endpoint
+type statusResponse struct {
+	Status        string           `json:"status"`
+	Build         health.BuildInfo `json:"build"`
+	StartedAt     time.Time        `json:"started_at"`
+	UptimeSeconds int64            `json:"uptime_seconds"`
+	Checks        []health.Result  `json:"checks"`
+}
+
+// ServeLiveness answers as long as the process serves requests. It checks no dependency, so an
+// unreachable database does not get the process restarted.
+func ServeLiveness() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, health.Report{Status: health.StatusOK, Checks: []health.Result{}})
+	}
+}
+
+// ServeReadiness runs the registered checks and answers 503 while one of them fails, so no traffic
+// is sent to the server.
+func ServeReadiness(registry *health.Registry) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		report := registry.Run(c.Request().Context())
+		if report.Status != health.StatusOK {
+			return c.JSON(http.StatusServiceUnavailable, report)
+		}
+		return c.JSON(http.StatusOK, report)
+	}
+}
+
+// ServeStatus reports the build, the uptime and the outcome and latency of every check. It answers
+// 200 even when a check fails; the status field tells.
+func ServeStatus(registry *health.Registry, build health.BuildInfo, startedAt time.Time) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		report := registry.Run(c.Request().Context())
+		return c.JSON(http.StatusOK, statusResponse{Status: report.Status, Build: build, StartedAt: startedAt,
+			UptimeSeconds: int64(time.Since(startedAt).Seconds()), Checks: report.Checks})
+	}
+}
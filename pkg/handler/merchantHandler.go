@@ -0,0 +1,97 @@
+package http
+
+import (
+	"errors"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/dto"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func (h *Handler) createMerchant(c echo.Context) error {
+	var req dto.CreateMerchantRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger(c).Error("Invalid request payload", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid request payload")
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.logger(c).Error("Validation failed", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	merchant, err := h.merchantService.CreateMerchant(c.Request().Context(), &req)
+	if err != nil {
+		return h.merchantError(c, "Failed to create merchant", err)
+	}
+
+	return c.JSON(http.StatusCreated, merchant)
+}
+
+func (h *Handler) getMerchant(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger(c).Error("Invalid merchant ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid merchant ID")
+	}
+
+	merchant, err := h.merchantService.GetMerchantByID(c.Request().Context(), uint(id))
+	if err != nil {
+		return h.merchantError(c, "Failed to fetch merchant", err)
+	}
+
+	return c.JSON(http.StatusOK, merchant)
+}
+
+func (h *Handler) updateMerchant(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger(c).Error("Invalid merchant ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid merchant ID")
+	}
+
+	var req dto.UpdateMerchantRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger(c).Error("Invalid request payload", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid request payload")
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.logger(c).Error("Validation failed", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	merchant, err := h.merchantService.UpdateMerchant(c.Request().Context(), uint(id), &req)
+	if err != nil {
+		return h.merchantError(c, "Failed to update merchant", err)
+	}
+
+	return c.JSON(http.StatusOK, merchant)
+}
+
+func (h *Handler) deleteMerchant(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger(c).Error("Invalid merchant ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid merchant ID")
+	}
+
+	if err := h.merchantService.DeleteMerchant(c.Request().Context(), uint(id)); err != nil {
+		return h.merchantError(c, "Failed to delete merchant", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// merchantError maps merchant service errors to a response status
+func (h *Handler) merchantError(c echo.Context, msg string, err error) error {
+	h.logger(c).Error(msg, zap.Error(err))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperror.ErrNotFound.WithMessage("Merchant not found")
+	}
+	return internalError(msg, err)
+}
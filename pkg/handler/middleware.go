@@ -0,0 +1,262 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/idempotency"
+	"go/payment-processor/pkg/logging"
+	"go/payment-processor/pkg/ratelimit"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// apiKeyHeader is accepted as an alternative to "Authorization: Bearer <key>"
+const apiKeyHeader = "X-API-Key"
+
+// apiKeyBearerPrefix tells merchant API keys apart from JWTs in the Authorization header
+const apiKeyBearerPrefix = "sk_"
+
+// RateLimit headers as described by the IETF httpapi rate limit fields draft
+const (
+	rateLimitLimitHeader     = "RateLimit-Limit"
+	rateLimitRemainingHeader = "RateLimit-Remaining"
+	rateLimitResetHeader     = "RateLimit-Reset"
+	rateLimitPolicyHeader    = "RateLimit-Policy"
+)
+
+var (
+	errIdempotencyKeyInUse  = apperror.New(http.StatusConflict, "idempotency_key_in_use", "A request with this idempotency key is in progress")
+	errIdempotencyKeyReused = apperror.New(http.StatusUnprocessableEntity, "idempotency_key_reused", "The idempotency key was used for a different request")
+)
+
+// authenticate resolves the caller of the request and stores it as the principal. Merchants present
+// an API key, internal services a JWT issued for the configured issuer and audience. The merchant
+// is added to the logs of the rest of the request.
+func (h *Handler) authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		rawKey := c.Request().Header.Get(apiKeyHeader)
+		var bearer string
+		if authorization := c.Request().Header.Get(echo.HeaderAuthorization); rawKey == "" && authorization != "" {
+			scheme, token, found := strings.Cut(authorization, " ")
+			if found && strings.EqualFold(scheme, "Bearer") {
+				bearer = strings.TrimSpace(token)
+			}
+		}
+		if strings.HasPrefix(bearer, apiKeyBearerPrefix) {
+			rawKey, bearer = bearer, ""
+		}
+
+		var principal *auth.Principal
+		var err error
+		switch {
+		case rawKey != "":
+			if principal, err = h.apiKeyService.Authenticate(c.Request().Context(), rawKey); err != nil {
+				h.logger(c).Warn("API key authentication failed", zap.Error(err))
+				return apperror.ErrUnauthorized.WithMessage("Invalid API key")
+			}
+		case bearer != "" && h.jwtValidator != nil:
+			if principal, err = h.jwtValidator.Validate(bearer); err != nil {
+				h.logger(c).Warn("Bearer token validation failed", zap.Error(err))
+				return apperror.ErrUnauthorized.WithMessage("Invalid bearer token")
+			}
+		default:
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+			return apperror.ErrUnauthorized.WithMessage("Missing credentials")
+		}
+		auth.SetPrincipal(c, principal)
+		if principal.MerchantID != 0 {
+			// Internal services act for no merchant in particular
+			c.SetRequest(c.Request().WithContext(logging.With(c.Request().Context(), zap.Uint("merchant_id", principal.MerchantID))))
+		}
+		return next(c)
+	}
+}
+
+// requireScope rejects principals that were granted none of the scopes
+func requireScope(scopes ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal := auth.GetPrincipal(c)
+			if principal == nil || !principal.HasAnyScope(scopes...) {
+				return apperror.ErrForbidden.WithMessage("Insufficient scope")
+			}
+			return next(c)
+		}
+	}
+}
+
+// requirePermission rejects principals whose roles do not grant the permission
+func requirePermission(permission auth.Permission) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !auth.GetPrincipal(c).Can(permission) {
+				return apperror.ErrForbidden.WithMessage("Permission denied")
+			}
+			return next(c)
+		}
+	}
+}
+
+// merchantOnly restricts a route to merchants authenticated with an API key
+func merchantOnly(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if principal := auth.GetPrincipal(c); principal == nil || principal.APIKeyID == 0 {
+			return apperror.ErrForbidden.WithMessage("Only available to merchant API keys")
+		}
+		return next(c)
+	}
+}
+
+// internalOnly restricts a route to internal services not tied to a merchant
+func internalOnly(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if principal := auth.GetPrincipal(c); principal == nil || !principal.ActsForAnyMerchant() {
+			return apperror.ErrForbidden.WithMessage("Only available to internal services")
+		}
+		return next(c)
+	}
+}
+
+// limitByIP rate limits requests per client IP, before they are authenticated
+func (h *Handler) limitByIP(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if h.rateLimiter == nil {
+			return next(c)
+		}
+		result, err := h.rateLimiter.AllowIP(c.Request().Context(), c.RealIP())
+		return h.applyRateLimit(c, next, result, err)
+	}
+}
+
+// limitByCaller rate limits authenticated requests per caller, and per caller and endpoint for
+// endpoints with their own limit
+func (h *Handler) limitByCaller(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if h.rateLimiter == nil {
+			return next(c)
+		}
+		endpoint := c.Request().Method + " " + c.Path()
+		result, err := h.rateLimiter.AllowCaller(c.Request().Context(), auth.GetPrincipal(c).Actor(), endpoint)
+		return h.applyRateLimit(c, next, result, err)
+	}
+}
+
+// applyRateLimit sets the RateLimit headers and rejects the request once the limit is exhausted.
+// Requests are let through when the store fails, so an outage of the store does not take down the API.
+func (h *Handler) applyRateLimit(c echo.Context, next echo.HandlerFunc, result ratelimit.Result, err error) error {
+	if err != nil {
+		h.logger(c).Warn("Rate limit store unavailable", zap.Error(err))
+		return next(c)
+	}
+	if result.Limit.Unlimited() {
+		return next(c)
+	}
+
+	header := c.Response().Header()
+	header.Set(rateLimitLimitHeader, strconv.Itoa(result.Limit.Requests))
+	header.Set(rateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+	header.Set(rateLimitResetHeader, strconv.Itoa(ceilSeconds(result.ResetAfter)))
+	header.Set(rateLimitPolicyHeader, fmt.Sprintf("%d;w=%d", result.Limit.Requests, ceilSeconds(result.Limit.Period)))
+	if !result.Allowed {
+		h.logger(c).Warn("Rate limit exceeded", zap.String("ip", c.RealIP()), zap.String("path", c.Path()))
+		return apperror.ErrRateLimited.WithRetryAfter(result.RetryAfter)
+	}
+	return next(c)
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// idempotent replays the stored response when a request is retried with the same Idempotency-Key,
+// so clients can safely retry payments after a timeout. Keys are scoped to the caller and bound to
+// the method, target and body of the first request. Server errors and 429s are not stored, so the
+// retry runs again, unless the request committed: a payment that reached the provider may have been
+// charged, so its response is stored whatever it is and a retry never sends it to the provider again.
+func (h *Handler) idempotent(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		key := req.Header.Get(idempotency.Header)
+		if h.idempotency == nil || key == "" || req.Method == http.MethodGet || req.Method == http.MethodHead {
+			return next(c)
+		}
+		if len(key) > idempotency.MaxKeyLength {
+			return apperror.ErrInvalidRequest.WithMessage(fmt.Sprintf("%s must not exceed %d characters", idempotency.Header, idempotency.MaxKeyLength))
+		}
+
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return apperror.ErrInvalidRequest.WithMessage("Invalid request payload").WithCause(err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := req.Context()
+		scopedKey := auth.GetPrincipal(c).Actor() + ":" + key
+		stored, err := h.idempotency.Begin(ctx, scopedKey, idempotency.Fingerprint(req.Method, req.URL.RequestURI(), body))
+		switch {
+		case errors.Is(err, idempotency.ErrInProgress):
+			return errIdempotencyKeyInUse.WithRetryAfter(time.Second)
+		case errors.Is(err, idempotency.ErrMismatch):
+			return errIdempotencyKeyReused
+		case err != nil:
+			h.logger(c).Warn("Idempotency store unavailable", zap.Error(err))
+			return next(c)
+		case stored != nil:
+			c.Response().Header().Set(idempotency.ReplayedHeader, "true")
+			return c.Blob(stored.Status, stored.ContentType, stored.Body)
+		}
+
+		commitment := &idempotency.Commitment{}
+		c.SetRequest(req.WithContext(idempotency.ContextWithCommitment(ctx, commitment)))
+		recorder := &bodyRecorder{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = recorder
+		if err = next(c); err != nil {
+			// Render the error here so that it is stored like any other response
+			c.Error(err)
+		}
+
+		response := c.Response()
+		failed := response.Status >= http.StatusInternalServerError || response.Status == http.StatusTooManyRequests
+		if !response.Committed || (failed && !commitment.Committed()) {
+			if releaseErr := h.idempotency.Release(ctx, scopedKey); releaseErr != nil {
+				h.logger(c).Warn("Failed to release idempotency key", zap.Error(releaseErr))
+			}
+			return err
+		}
+		if completeErr := h.idempotency.Complete(ctx, scopedKey, idempotency.Response{Status: response.Status,
+			ContentType: response.Header().Get(echo.HeaderContentType), Body: recorder.body.Bytes()}); completeErr != nil {
+			h.logger(c).Warn("Failed to store idempotent response", zap.Error(completeErr))
+		}
+		return err
+	}
+}
+
+// bodyRecorder keeps a copy of the response body
+type bodyRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// chain combines middlewares into one, applied in order
+func chain(middlewares ...echo.MiddlewareFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+		return next
+	}
+}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/openapi"
+	"go/payment-processor/pkg/velocity"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// apiRoutes documents every route of Register. The contract test fails when a route is
+// registered without being documented here, or when a handler renders another shape than documented.
+var apiRoutes = []openapi.Route{
+	// Invoices and payments
+	{Method: http.MethodPost, Path: "/invoices", OperationID: "createInvoice", Summary: "Create an invoice", Tag: "invoices",
+		Request: dto.CreateInvoiceRequest{}, Responses: map[int]any{http.StatusCreated: dto.InvoiceResponse{}}},
+	{Method: http.MethodGet, Path: "/invoices", OperationID: "listInvoices", Summary: "List invoices", Tag: "invoices",
+		Request: dto.ListInvoicesRequest{}, Responses: map[int]any{http.StatusOK: dto.ListInvoicesResponse{}}},
+	{Method: http.MethodGet, Path: "/invoices/:id", OperationID: "getInvoice", Summary: "Get an invoice", Tag: "invoices",
+		Responses: map[int]any{http.StatusOK: dto.InvoiceResponse{}}},
+	{Method: http.MethodPost, Path: "/invoices/:id/payments", OperationID: "processPayment", Summary: "Pay an invoice", Tag: "payments",
+		Request:   dto.ProcessPaymentRequest{},
+		Responses: map[int]any{http.StatusOK: dto.ProcessPaymentResponse{}, http.StatusPaymentRequired: dto.ProcessPaymentResponse{}}},
+	{Method: http.MethodPost, Path: "/invoices/:id/payments/:paymentId/challenge", OperationID: "completePaymentChallenge",
+		Summary: "Complete the 3-D Secure challenge of a payment", Tag: "payments", Request: dto.CompleteChallengeRequest{},
+		Responses: map[int]any{http.StatusOK: dto.ProcessPaymentResponse{}, http.StatusPaymentRequired: dto.ProcessPaymentResponse{}}},
+	{Method: http.MethodGet, Path: "/invoices/:id/payment-status", OperationID: "getPaymentStatus", Summary: "Get the payment status of an invoice",
+		Tag: "payments", Responses: map[int]any{http.StatusOK: dto.PaymentStatusResponse{}}},
+
+	// Disputes
+	{Method: http.MethodPost, Path: "/payments/:id/disputes", OperationID: "openDispute", Summary: "Open a dispute", Tag: "disputes",
+		Request: dto.CreateDisputeRequest{}, Responses: map[int]any{http.StatusCreated: dto.DisputeResponse{}}},
+	{Method: http.MethodGet, Path: "/disputes/:id", OperationID: "getDispute", Summary: "Get a dispute", Tag: "disputes",
+		Responses: map[int]any{http.StatusOK: dto.DisputeResponse{}}},
+	{Method: http.MethodPost, Path: "/disputes/:id/evidence", OperationID: "submitDisputeEvidence", Summary: "Submit dispute evidence",
+		Tag: "disputes", Request: dto.SubmitDisputeEvidenceRequest{}, RequestContentType: echo.MIMEMultipartForm, FormFiles: []string{"file"},
+		Responses: map[int]any{http.StatusCreated: dto.DisputeEvidenceResponse{}}},
+	{Method: http.MethodPost, Path: "/disputes/:id/resolve", OperationID: "resolveDispute", Summary: "Resolve a dispute", Tag: "disputes",
+		Request: dto.ResolveDisputeRequest{}, Responses: map[int]any{http.StatusOK: dto.DisputeResponse{}}},
+
+	// API keys
+	{Method: http.MethodGet, Path: "/api-keys", OperationID: "listAPIKeys", Summary: "List the API keys of the merchant", Tag: "api-keys",
+		Responses: map[int]any{http.StatusOK: []*dto.APIKeyResponse{}}},
+	{Method: http.MethodPost, Path: "/api-keys", OperationID: "createAPIKey", Summary: "Create an API key", Tag: "api-keys",
+		Request: dto.CreateAPIKeyRequest{}, Responses: map[int]any{http.StatusCreated: dto.APIKeyResponse{}}},
+	{Method: http.MethodDelete, Path: "/api-keys/:id", OperationID: "revokeAPIKey", Summary: "Revoke an API key", Tag: "api-keys",
+		Responses: map[int]any{http.StatusNoContent: nil}},
+
+	// Risk review
+	{Method: http.MethodGet, Path: "/payment-reviews", OperationID: "listPaymentReviews", Summary: "List payments held for review",
+		Tag: "risk", Request: dto.ListPaymentReviewsRequest{}, Responses: map[int]any{http.StatusOK: []*dto.PaymentReviewResponse{}}},
+	{Method: http.MethodPost, Path: "/payments/:id/review", OperationID: "reviewPayment", Summary: "Approve or reject a held payment",
+		Tag: "risk", Request: dto.ReviewPaymentRequest{}, Responses: map[int]any{http.StatusOK: dto.ProcessPaymentResponse{}}},
+	{Method: http.MethodGet, Path: "/velocity/metrics", OperationID: "getVelocityMetrics", Summary: "Get decline velocity metrics",
+		Tag: "risk", Responses: map[int]any{http.StatusOK: velocity.Metrics{}}},
+
+	// Merchants
+	{Method: http.MethodPost, Path: "/merchants", OperationID: "createMerchant", Summary: "Create a merchant", Tag: "merchants",
+		Request: dto.CreateMerchantRequest{}, Responses: map[int]any{http.StatusCreated: dto.MerchantResponse{}}},
+	{Method: http.MethodGet, Path: "/merchants/:id", OperationID: "getMerchant", Summary: "Get a merchant", Tag: "merchants",
+		Responses: map[int]any{http.StatusOK: dto.MerchantResponse{}}},
+	{Method: http.MethodPatch, Path: "/merchants/:id", OperationID: "updateMerchant", Summary: "Update a merchant", Tag: "merchants",
+		Request: dto.UpdateMerchantRequest{}, Responses: map[int]any{http.StatusOK: dto.MerchantResponse{}}},
+	{Method: http.MethodDelete, Path: "/merchants/:id", OperationID: "deleteMerchant", Summary: "Delete a merchant", Tag: "merchants",
+		Responses: map[int]any{http.StatusNoContent: nil}},
+
+	// Customers
+	{Method: http.MethodPost, Path: "/customers", OperationID: "createCustomer", Summary: "Create a customer", Tag: "customers",
+		Request: dto.CreateCustomerRequest{}, Responses: map[int]any{http.StatusCreated: dto.CustomerResponse{}}},
+	{Method: http.MethodGet, Path: "/customers", OperationID: "searchCustomers", Summary: "Search customers", Tag: "customers",
+		Request: dto.SearchCustomersRequest{}, Responses: map[int]any{http.StatusOK: []*dto.CustomerResponse{}}},
+	{Method: http.MethodGet, Path: "/customers/:id", OperationID: "getCustomer", Summary: "Get a customer", Tag: "customers",
+		Responses: map[int]any{http.StatusOK: dto.CustomerResponse{}}},
+	{Method: http.MethodPatch, Path: "/customers/:id", OperationID: "updateCustomer", Summary: "Update a customer", Tag: "customers",
+		Request: dto.UpdateCustomerRequest{}, Responses: map[int]any{http.StatusOK: dto.CustomerResponse{}}},
+	{Method: http.MethodDelete, Path: "/customers/:id", OperationID: "deleteCustomer", Summary: "Delete a customer", Tag: "customers",
+		Responses: map[int]any{http.StatusNoContent: nil}},
+	{Method: http.MethodPost, Path: "/customers/:id/payment-methods", OperationID: "addPaymentMethod", Summary: "Save a payment method",
+		Tag: "customers", Request: dto.CreatePaymentMethodRequest{}, Responses: map[int]any{http.StatusCreated: dto.PaymentMethodResponse{}}},
+	{Method: http.MethodGet, Path: "/customers/:id/payment-methods", OperationID: "getPaymentMethods", Summary: "List saved payment methods",
+		Tag: "customers", Responses: map[int]any{http.StatusOK: []*dto.PaymentMethodResponse{}}},
+	{Method: http.MethodDelete, Path: "/customers/:id/payment-methods/:paymentMethodId", OperationID: "deletePaymentMethod",
+		Summary: "Delete a saved payment method", Tag: "customers", Responses: map[int]any{http.StatusNoContent: nil}},
+
+	// Audit log
+	{Method: http.MethodGet, Path: "/audit-logs", OperationID: "listAuditLogs", Summary: "List audit log entries", Tag: "audit-log",
+		Request: dto.ListAuditLogsRequest{}, Responses: map[int]any{http.StatusOK: dto.ListAuditLogsResponse{}}},
+	{Method: http.MethodGet, Path: "/audit-logs/verify", OperationID: "verifyAuditLog", Summary: "Verify the audit log hash chain",
+		Tag: "audit-log", Responses: map[int]any{http.StatusOK: dto.AuditLogVerificationResponse{}}},
+
+	// Token endpoint for tests and local environments; errors follow RFC 6749 rather than problem details
+	{Method: http.MethodPost, Path: "/oauth/token", OperationID: "issueToken", Summary: "Obtain an access token", Tag: "auth",
+		Request: dto.TokenRequest{}, RequestContentType: echo.MIMEApplicationForm, Public: true,
+		Responses: map[int]any{http.StatusOK: dto.TokenResponse{}}},
+}
+
+const apiDescription = "Invoices, payments, disputes and the back-office operations of the payment processor. " +
+	"Requests that change state accept an Idempotency-Key header; retrying with the same key replays the first response."
+
+// OpenAPIDocument describes the API served under serverURL.
+func OpenAPIDocument(serverURL string) *openapi.Document {
+	builder := openapi.NewBuilder(openapi.Info{
+		Title:       "Payment Processor API",
+		Version:     "1.0.0",
+		Description: apiDescription,
+	}, serverURL).
+		SecurityScheme("apiKey", openapi.SecurityScheme{Type: "apiKey", In: "header", Name: apiKeyHeader,
+			Description: "Merchant API key, also accepted as \"Authorization: Bearer <key>\""}).
+		SecurityScheme("bearer", openapi.SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT",
+			Description: "Access token of an internal service"}).
+		DefaultError(apperror.ProblemContentType, apperror.Problem{})
+	for _, route := range apiRoutes {
+		builder.Add(route)
+	}
+	return builder.Document()
+}
+
+// ServeOpenAPI serves the OpenAPI document of the API under serverURL.
+func ServeOpenAPI(serverURL string) echo.HandlerFunc {
+	document := OpenAPIDocument(serverURL)
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, document)
+	}
+}
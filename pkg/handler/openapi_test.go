@@ -0,0 +1,236 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/openapi"
+	services "go/payment-processor/pkg/service"
+	"go/payment-processor/pkg/utils"
+	"go/payment-processor/pkg/velocity"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+const contractPrefix = "/payment-process"
+
+var (
+	merchantPrincipal = &auth.Principal{MerchantID: 1, APIKeyID: 1, Mode: utils.APIKeyModeTest,
+		Scopes: auth.MerchantScopes, Roles: []string{auth.RoleMerchant}}
+	internalPrincipal = &auth.Principal{Subject: "back-office", Mode: utils.APIKeyModeTest,
+		Scopes: auth.MerchantScopes, Roles: []string{auth.RoleAdmin}}
+)
+
+// The fakes embed the service interfaces and only implement what the contract test calls
+type fakeAPIKeyService struct{ services.APIKeyService }
+
+func (fakeAPIKeyService) Authenticate(_ context.Context, rawKey string) (*auth.Principal, error) {
+	if rawKey == "internal" {
+		return internalPrincipal, nil
+	}
+	return merchantPrincipal, nil
+}
+
+func (fakeAPIKeyService) ListAPIKeys(_ context.Context, merchantID uint) ([]*dto.APIKeyResponse, error) {
+	now := time.Now()
+	return []*dto.APIKeyResponse{{ID: 1, MerchantID: merchantID, Prefix: "pk_test_abcd", Mode: utils.APIKeyModeTest, CreatedAt: &now}}, nil
+}
+
+type fakeInvoiceService struct{ services.InvoiceService }
+
+func (fakeInvoiceService) GetInvoiceByID(_ context.Context, id uint) (*dto.InvoiceResponse, error) {
+	now := time.Now()
+	return &dto.InvoiceResponse{ID: id, MerchantID: 1, CustomerID: 2, Amount: decimal.NewFromInt(25), Currency: "USD",
+		Status: utils.InvoiceStatusPending, Mode: utils.APIKeyModeTest, CreatedAt: &now}, nil
+}
+
+func (s fakeInvoiceService) CreateInvoice(ctx context.Context, req *dto.CreateInvoiceRequest) (*dto.InvoiceResponse, error) {
+	return s.GetInvoiceByID(ctx, 1)
+}
+
+func (s fakeInvoiceService) ListInvoices(ctx context.Context, _ *dto.ListInvoicesRequest) (*dto.ListInvoicesResponse, error) {
+	invoice, _ := s.GetInvoiceByID(ctx, 1)
+	return &dto.ListInvoicesResponse{Data: []*dto.InvoiceResponse{invoice}, NextCursor: "next"}, nil
+}
+
+type fakePaymentService struct{ services.PaymentService }
+
+// ProcessPayment declines cards ending in 3434, like the simulated provider
+func (fakePaymentService) ProcessPayment(_ context.Context, req *dto.ProcessPaymentRequest) (*entities.Payment, error) {
+	status := utils.PaymentStatusSuccess
+	if strings.HasSuffix(req.PaymentSource, "3434") {
+		status = utils.PaymentStatusDeclined
+	}
+	return &entities.Payment{InvoiceID: req.InvoiceID, Amount: decimal.NewFromInt(25), PaymentStatus: status,
+		PaymentMethod: req.PaymentMethod, PaymentSource: req.PaymentSource}, nil
+}
+
+func (fakePaymentService) GetPaymentStatus(context.Context, uint) (string, error) {
+	return utils.PaymentStatusSuccess, nil
+}
+
+func (fakePaymentService) ReviewPayment(_ context.Context, req *dto.ReviewPaymentRequest) (*entities.Payment, error) {
+	return &entities.Payment{InvoiceID: 1, Amount: decimal.NewFromInt(25), PaymentStatus: utils.PaymentStatusRejected,
+		RiskDecision: "REVIEW", RiskScore: 50, ReviewNote: req.Note}, nil
+}
+
+func (fakePaymentService) VelocityMetrics() *velocity.Metrics {
+	return &velocity.Metrics{DeclinesRecorded: 3, BlocksTriggered: map[string]int64{velocity.DimensionCard: 1},
+		BlockedAttempts: map[string]int64{}}
+}
+
+func newContractServer() *echo.Echo {
+	h := &Handler{log: zap.NewNop(), validator: validator.New(), tokenIssuer: &auth.TokenIssuer{},
+		apiKeyService: fakeAPIKeyService{}, invoiceService: fakeInvoiceService{}, paymentService: fakePaymentService{}}
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler(zap.NewNop())
+	Register(e.Group(contractPrefix), h)
+	return e
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	document := OpenAPIDocument(contractPrefix)
+
+	var registered []string
+	for _, route := range newContractServer().Routes() {
+		if route.Method == echo.RouteNotFound {
+			continue
+		}
+		registered = append(registered, route.Method+" "+strings.TrimPrefix(route.Path, contractPrefix))
+	}
+	var documented []string
+	for path, item := range document.Paths {
+		for method := range item {
+			documented = append(documented, strings.ToUpper(method)+" "+openapi.EchoPath(path))
+		}
+	}
+	sort.Strings(registered)
+	sort.Strings(documented)
+
+	assert.Equal(t, registered, documented)
+}
+
+func TestResponsesMatchOpenAPIDocument(t *testing.T) {
+	document := OpenAPIDocument(contractPrefix)
+	e := newContractServer()
+
+	tests := []struct {
+		name   string
+		method string
+		route  string // documented Echo path
+		target string
+		key    string
+		body   string
+		status int
+		// malformed requests break the documented request schema on purpose
+		malformed bool
+	}{
+		{"create invoice", http.MethodPost, "/invoices", "/invoices", "merchant", `{"customer_id":2,"amount":25,"currency":"USD"}`, http.StatusCreated, false},
+		{"list invoices", http.MethodGet, "/invoices", "/invoices?limit=10&status=PENDING&min_amount=10.5&sort=amount&order=desc",
+			"merchant", "", http.StatusOK, false},
+		{"get invoice", http.MethodGet, "/invoices/:id", "/invoices/1", "merchant", "", http.StatusOK, false},
+		{"invalid invoice ID", http.MethodGet, "/invoices/:id", "/invoices/abc", "merchant", "", http.StatusBadRequest, true},
+		{"missing credentials", http.MethodGet, "/invoices/:id", "/invoices/1", "", "", http.StatusUnauthorized, false},
+		{"successful payment", http.MethodPost, "/invoices/:id/payments", "/invoices/1/payments", "merchant",
+			`{"payment_method":"credit_card","payment_source":"4242424242424242","billing_country":"US"}`, http.StatusOK, false},
+		{"declined payment", http.MethodPost, "/invoices/:id/payments", "/invoices/1/payments", "merchant",
+			`{"payment_method":"credit_card","payment_source":"4242424242423434"}`, http.StatusPaymentRequired, false},
+		{"invalid challenge result", http.MethodPost, "/invoices/:id/payments/:paymentId/challenge", "/invoices/1/payments/1/challenge",
+			"merchant", `{"result":"maybe"}`, http.StatusBadRequest, true},
+		{"payment status", http.MethodGet, "/invoices/:id/payment-status", "/invoices/1/payment-status", "merchant", "", http.StatusOK, false},
+		{"list API keys", http.MethodGet, "/api-keys", "/api-keys", "merchant", "", http.StatusOK, false},
+		{"internal route as merchant", http.MethodGet, "/velocity/metrics", "/velocity/metrics", "merchant", "", http.StatusForbidden, false},
+		{"velocity metrics", http.MethodGet, "/velocity/metrics", "/velocity/metrics", "internal", "", http.StatusOK, false},
+		{"review payment", http.MethodPost, "/payments/:id/review", "/payments/1/review", "internal",
+			`{"decision":"reject","note":"stolen card"}`, http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, contractPrefix+tt.target, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			}
+			if tt.key != "" {
+				req.Header.Set(apiKeyHeader, tt.key)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			requestErr := document.ValidateRequest(tt.method, tt.route, tt.target, req.Header.Get(echo.HeaderContentType), []byte(tt.body))
+			if tt.malformed {
+				assert.Error(t, requestErr)
+			} else {
+				assert.NoError(t, requestErr)
+			}
+
+			assert.Equal(t, tt.status, rec.Code, rec.Body.String())
+			assert.NoError(t, document.ValidateResponse(tt.method, tt.route, rec.Code, rec.Header().Get(echo.HeaderContentType), rec.Body.Bytes()))
+		})
+	}
+}
+
+// TestRequestBodiesMatchOpenAPIDocument encodes the DTO each handler binds with every property set and
+// checks both the document and the handler accept it, so the two cannot drift apart unnoticed
+func TestRequestBodiesMatchOpenAPIDocument(t *testing.T) {
+	document := OpenAPIDocument(contractPrefix)
+	e := newContractServer()
+
+	tests := []struct {
+		name   string
+		method string
+		route  string
+		target string
+		key    string
+		body   any
+		status int
+	}{
+		{"create invoice", http.MethodPost, "/invoices", "/invoices", "merchant",
+			dto.CreateInvoiceRequest{MerchantID: 1, CustomerID: 2, Amount: 25, Currency: "USD", OptionalDescription: "order 1"},
+			http.StatusCreated},
+		{"pay invoice", http.MethodPost, "/invoices/:id/payments", "/invoices/1/payments", "merchant",
+			dto.ProcessPaymentRequest{PaymentMethod: "credit_card", PaymentSource: "4242424242424242", PaymentMethodID: 3,
+				BillingCountry: "US"}, http.StatusOK},
+		{"review payment", http.MethodPost, "/payments/:id/review", "/payments/1/review", "internal",
+			dto.ReviewPaymentRequest{Decision: utils.ReviewDecisionApprove, Note: "checked with the cardholder"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.body)
+			assert.NoError(t, err)
+			assert.NoError(t, document.ValidateRequest(tt.method, tt.route, tt.target, echo.MIMEApplicationJSON, body))
+
+			req := httptest.NewRequest(tt.method, contractPrefix+tt.target, strings.NewReader(string(body)))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(apiKeyHeader, tt.key)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, tt.status, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	e := echo.New()
+	e.GET("/openapi.json", ServeOpenAPI(contractPrefix))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	var document openapi.Document
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &document))
+	assert.Equal(t, openapi.Version, document.OpenAPI)
+	assert.Equal(t, contractPrefix, document.Servers[0].URL)
+	assert.Contains(t, document.Paths, "/invoices/{id}/payments")
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"errors"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/mapper"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func (h *Handler) listPaymentReviews(c echo.Context) error {
+	var req dto.ListPaymentReviewsRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger(c).Error("Invalid query parameters", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid query parameters")
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.logger(c).Error("Validation failed", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	reviews, err := h.paymentService.ListPaymentReviews(c.Request().Context(), &req)
+	if err != nil {
+		h.logger(c).Error("Failed to list payment reviews", zap.Error(err))
+		return internalError("Failed to list payment reviews", err)
+	}
+
+	return c.JSON(http.StatusOK, reviews)
+}
+
+func (h *Handler) reviewPayment(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger(c).Error("Invalid payment ID", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid payment ID")
+	}
+
+	var req dto.ReviewPaymentRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger(c).Error("Invalid request payload", zap.Error(err))
+		return apperror.ErrInvalidRequest.WithMessage("Invalid request payload")
+	}
+
+	req.PaymentID = uint(id)
+	if err := h.validator.Struct(req); err != nil {
+		h.logger(c).Error("Validation failed", zap.Error(err))
+		return apperror.Validation(err)
+	}
+
+	payment, err := h.paymentService.ReviewPayment(c.Request().Context(), &req)
+	if err != nil {
+		h.logger(c).Error("Failed to review payment", zap.Error(err))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.ErrNotFound.WithMessage("Payment not found")
+		}
+		return internalError("Failed to review payment", err)
+	}
+
+	// The review itself succeeded, so a rejected or declined payment is reported with 200 as well
+	return c.JSON(http.StatusOK, mapper.ToPaymentResponse(payment))
+}
+
+// getVelocityMetrics reports declines and the attempts stopped by temporary blocks since startup
+func (h *Handler) getVelocityMetrics(c echo.Context) error {
+	metrics := h.paymentService.VelocityMetrics()
+	if metrics == nil {
+		return apperror.ErrNotFound.WithMessage("Velocity limits are disabled")
+	}
+	return c.JSON(http.StatusOK, metrics)
+}
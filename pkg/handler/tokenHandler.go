@@ -0,0 +1,45 @@
+package http
+
+import (
+	"errors"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/dto"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// issueToken implements the OAuth2 client-credentials grant of the local token issuer. Errors use
+// the error codes of RFC 6749 section 5.2.
+func (h *Handler) issueToken(c echo.Context) error {
+	var req dto.TokenRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger(c).Error("Invalid token request", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+	}
+	if err := h.validator.Struct(req); err != nil {
+		if req.GrantType != "" && req.GrantType != "client_credentials" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		}
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+	}
+
+	token, ttl, scopes, err := h.tokenIssuer.Issue(req.ClientID, req.ClientSecret, strings.Fields(req.Scope))
+	if err != nil {
+		h.logger(c).Warn("Token request rejected", zap.String("client_id", req.ClientID), zap.Error(err))
+		if errors.Is(err, auth.ErrScopeNotAllowed) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid_scope"})
+		}
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(http.StatusOK, dto.TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(ttl.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	})
+}
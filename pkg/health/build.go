@@ -0,0 +1,39 @@
+package health
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Version is the release of the binary, set at build time with
+// -ldflags "-X go/payment-processor/pkg/health.Version=v1.2.3".
+var Version = "dev"
+
+// BuildInfo describes the running binary.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	CommitAt  string `json:"commit_time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // built from a checkout with uncommitted changes
+	GoVersion string `json:"go_version"`
+}
+
+// ReadBuildInfo returns the version and the VCS details the Go toolchain stamped into the binary.
+func ReadBuildInfo() BuildInfo {
+	build := BuildInfo{Version: Version, GoVersion: runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return build
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			build.Commit = setting.Value
+		case "vcs.time":
+			build.CommitAt = setting.Value
+		case "vcs.modified":
+			build.Modified = setting.Value == "true"
+		}
+	}
+	return build
+}
@@ -0,0 +1,123 @@
+// Package health runs the health checks that subsystems register, for the liveness, readiness and
+// status endpoints.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Statuses of a check and of a report
+const (
+	StatusOK      = "ok"
+	StatusFailing = "failing"
+)
+
+// defaultTimeout bounds each check when the registry is built without a timeout
+const defaultTimeout = 2 * time.Second
+
+// Check reports whether a dependency works, nil meaning healthy. It must return once ctx is done.
+type Check func(ctx context.Context) error
+
+// Pinger is implemented by collaborators, such as payment provider adapters, that can tell whether
+// they are reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of every check. It is failing as soon as one check fails.
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Registry holds the checks that decide whether the server is ready.
+type Registry struct {
+	mu      sync.RWMutex
+	checks  []namedCheck
+	timeout time.Duration
+}
+
+// NewRegistry returns an empty registry whose checks each get timeout to complete. A zero timeout
+// uses two seconds.
+func NewRegistry(timeout time.Duration) *Registry {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Registry{timeout: timeout}
+}
+
+// Register adds a check. A check registered again under the same name replaces the previous one.
+func (r *Registry) Register(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.checks {
+		if r.checks[i].name == name {
+			r.checks[i].check = check
+			return
+		}
+	}
+	r.checks = append(r.checks, namedCheck{name: name, check: check})
+}
+
+// Run runs every check concurrently and reports them in the order they were registered.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := append([]namedCheck(nil), r.checks...)
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make([]Result, len(checks))}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = r.run(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFailing
+		}
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, c namedCheck) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.check(ctx)
+	result := Result{Name: c.name, Status: StatusOK, LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		result.Status = StatusFailing
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// PingCheck checks a collaborator that can be pinged.
+func PingCheck(pinger Pinger) Check {
+	return pinger.Ping
+}
@@ -0,0 +1,52 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunReportsEveryCheck(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.Register("database", func(context.Context) error { return nil })
+	registry.Register("provider", func(context.Context) error { return errors.New("unreachable") })
+
+	report := registry.Run(context.Background())
+
+	assert.Equal(t, StatusFailing, report.Status)
+	if assert.Len(t, report.Checks, 2) {
+		assert.Equal(t, Result{Name: "database", Status: StatusOK, LatencyMS: report.Checks[0].LatencyMS}, report.Checks[0])
+		assert.Equal(t, "provider", report.Checks[1].Name)
+		assert.Equal(t, StatusFailing, report.Checks[1].Status)
+		assert.Equal(t, "unreachable", report.Checks[1].Error)
+	}
+
+	// Registering a name again replaces its check
+	registry.Register("provider", func(context.Context) error { return nil })
+	report = registry.Run(context.Background())
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Len(t, report.Checks, 2)
+}
+
+func TestRunTimesOutSlowChecks(t *testing.T) {
+	registry := NewRegistry(10 * time.Millisecond)
+	registry.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	report := registry.Run(context.Background())
+
+	assert.Equal(t, StatusFailing, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[0].Error)
+}
+
+func TestEmptyRegistryIsHealthy(t *testing.T) {
+	report := NewRegistry(0).Run(context.Background())
+
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Empty(t, report.Checks)
+}
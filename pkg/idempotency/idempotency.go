@@ -0,0 +1,82 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync/atomic"
+)
+
+// Header carries the key clients choose to make a request safe to retry
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is set on responses replayed from the store
+const ReplayedHeader = "Idempotent-Replayed"
+
+// MaxKeyLength bounds the keys accepted from clients
+const MaxKeyLength = 255
+
+var (
+	// ErrInProgress is returned while another request holds the key
+	ErrInProgress = errors.New("idempotency: a request with this key is in progress")
+	// ErrMismatch is returned when the key was used for a request with another fingerprint
+	ErrMismatch = errors.New("idempotency: key was used for a different request")
+)
+
+// Response is the response stored for a key and replayed to retries.
+type Response struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// Store keeps the responses of idempotent requests. Implementations must be safe for concurrent
+// use; the in-memory store only covers a single instance, a shared store covers retries that reach
+// another instance.
+type Store interface {
+	// Begin reserves key for a request with the given fingerprint. It returns the stored response
+	// when a request with the key completed before, ErrInProgress while another request holds the key
+	// and ErrMismatch when the key was used with another fingerprint.
+	Begin(ctx context.Context, key, fingerprint string) (*Response, error)
+	// Complete stores the response of the request holding key.
+	Complete(ctx context.Context, key string, response Response) error
+	// Release frees key without storing a response, so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// Fingerprint identifies a request by its method, target and body, so a key cannot be reused for
+// a different request.
+func Fingerprint(method, target string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + target + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Commitment records whether a request went past the point where running it again could repeat its
+// effects, such as a payment reaching the provider. The response of a committed request is stored
+// even when it is an error, so a retry replays it instead of running the request again.
+type Commitment struct {
+	committed atomic.Bool
+}
+
+type commitmentKey struct{}
+
+// ContextWithCommitment returns a copy of ctx through which Commit marks commitment.
+func ContextWithCommitment(ctx context.Context, commitment *Commitment) context.Context {
+	return context.WithValue(ctx, commitmentKey{}, commitment)
+}
+
+// Commit marks the request of ctx as committed. It does nothing when the request carries no
+// Idempotency-Key.
+func Commit(ctx context.Context) {
+	if commitment, ok := ctx.Value(commitmentKey{}).(*Commitment); ok {
+		commitment.committed.Store(true)
+	}
+}
+
+// Committed reports whether Commit was called for the request.
+func (c *Commitment) Committed() bool {
+	return c.committed.Load()
+}
@@ -0,0 +1,63 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreReplaysCompletedResponses(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore(time.Hour)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+	fingerprint := Fingerprint("POST", "/invoices/1/payments", []byte(`{"payment_source":"4242"}`))
+
+	stored, err := store.Begin(ctx, "merchant:1:k", fingerprint)
+	assert.NoError(t, err)
+	assert.Nil(t, stored)
+
+	_, err = store.Begin(ctx, "merchant:1:k", fingerprint)
+	assert.ErrorIs(t, err, ErrInProgress)
+
+	response := Response{Status: 200, ContentType: "application/json", Body: []byte(`{"payment_status":"SUCCESS"}`)}
+	assert.NoError(t, store.Complete(ctx, "merchant:1:k", response))
+	stored, err = store.Begin(ctx, "merchant:1:k", fingerprint)
+	assert.NoError(t, err)
+	assert.Equal(t, &response, stored)
+
+	_, err = store.Begin(ctx, "merchant:1:k", Fingerprint("POST", "/invoices/2/payments", nil))
+	assert.ErrorIs(t, err, ErrMismatch)
+
+	// Keys expire after the TTL and can then be used for another request
+	now = now.Add(time.Hour)
+	stored, err = store.Begin(ctx, "merchant:1:k", Fingerprint("POST", "/invoices/2/payments", nil))
+	assert.NoError(t, err)
+	assert.Nil(t, stored)
+}
+
+func TestMemoryStoreRelease(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+	ctx := context.Background()
+
+	_, err := store.Begin(ctx, "k", "a")
+	assert.NoError(t, err)
+	assert.NoError(t, store.Release(ctx, "k"))
+
+	stored, err := store.Begin(ctx, "k", "a")
+	assert.NoError(t, err)
+	assert.Nil(t, stored)
+}
+
+func TestCommit(t *testing.T) {
+	// Without a commitment there is nothing to mark
+	Commit(context.Background())
+
+	commitment := &Commitment{}
+	ctx := ContextWithCommitment(context.Background(), commitment)
+	assert.False(t, commitment.Committed())
+	Commit(context.WithoutCancel(ctx))
+	assert.True(t, commitment.Committed())
+}
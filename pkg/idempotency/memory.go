@@ -0,0 +1,82 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store drops expired keys
+const sweepInterval = time.Minute
+
+type entry struct {
+	fingerprint string
+	response    *Response // nil while the request is in progress
+	expires     time.Time
+}
+
+// MemoryStore keeps responses in process memory for ttl after they complete.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*entry
+	ttl       time.Duration
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*entry), ttl: ttl, now: time.Now}
+}
+
+func (s *MemoryStore) Begin(_ context.Context, key, fingerprint string) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		switch {
+		case e.fingerprint != fingerprint:
+			return nil, ErrMismatch
+		case e.response == nil:
+			return nil, ErrInProgress
+		}
+		return e.response, nil
+	}
+	// Requests in progress hold the key for at most ttl, in case they never complete
+	s.entries[key] = &entry{fingerprint: fingerprint, expires: now.Add(s.ttl)}
+	return nil, nil
+}
+
+func (s *MemoryStore) Complete(_ context.Context, key string, response Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.response = &response
+		e.expires = s.now().Add(s.ttl)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// sweep drops expired keys
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
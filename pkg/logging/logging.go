@@ -0,0 +1,50 @@
+// Package logging carries request-scoped log fields in a context, so the lines logged by the
+// handlers, services and repository while serving a request share its request ID and the merchant
+// and invoice it concerns.
+package logging
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type contextKey struct{}
+
+// With returns a copy of ctx carrying fields in addition to those ctx already carries. A field
+// replaces the carried field with the same key, so the carried fields never repeat a key.
+func With(ctx context.Context, fields ...zap.Field) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	carried := Fields(ctx)
+	// Copied so that contexts derived from the same parent do not share an array
+	all := make([]zap.Field, len(carried), len(carried)+len(fields))
+	copy(all, carried)
+next:
+	for _, field := range fields {
+		for i := range all {
+			if all[i].Key == field.Key {
+				all[i] = field
+				continue next
+			}
+		}
+		all = append(all, field)
+	}
+	return context.WithValue(ctx, contextKey{}, all)
+}
+
+// Fields returns the fields carried by ctx.
+func Fields(ctx context.Context) []zap.Field {
+	fields, _ := ctx.Value(contextKey{}).([]zap.Field)
+	return fields
+}
+
+// FromContext returns logger with the fields carried by ctx, the logger of the request ctx belongs to.
+func FromContext(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	fields := Fields(ctx)
+	if len(fields) == 0 {
+		return logger
+	}
+	return logger.With(fields...)
+}
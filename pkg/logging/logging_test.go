@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFromContextAddsCarriedFields(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := With(context.Background(), zap.String("request_id", "req-1"))
+	ctx = With(ctx, zap.Uint("merchant_id", 7))
+
+	FromContext(ctx, zap.New(core)).Info("Payment processed", zap.Uint("invoice_id", 3))
+
+	assert.Equal(t, map[string]any{"request_id": "req-1", "merchant_id": uint64(7), "invoice_id": uint64(3)},
+		logs.All()[0].ContextMap())
+}
+
+func TestFromContextWithoutFieldsReturnsLogger(t *testing.T) {
+	logger := zap.NewNop()
+
+	assert.Same(t, logger, FromContext(context.Background(), logger))
+}
+
+func TestWithDoesNotShareFieldsBetweenSiblings(t *testing.T) {
+	parent := With(context.Background(), zap.String("request_id", "req-1"))
+	first := With(parent, zap.Uint("invoice_id", 1))
+	second := With(parent, zap.Uint("invoice_id", 2))
+
+	assert.Len(t, Fields(parent), 1)
+	assert.Equal(t, zap.Uint("invoice_id", 1), Fields(first)[1])
+	assert.Equal(t, zap.Uint("invoice_id", 2), Fields(second)[1])
+}
+
+func TestWithReplacesFieldsOfTheSameKey(t *testing.T) {
+	ctx := With(context.Background(), zap.String("request_id", "req-1"), zap.Uint("merchant_id", 7))
+	ctx = With(ctx, zap.Uint("merchant_id", 8))
+
+	assert.Equal(t, []zap.Field{zap.String("request_id", "req-1"), zap.Uint("merchant_id", 8)}, Fields(ctx))
+}
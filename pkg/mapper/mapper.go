@@ -1,9 +1,15 @@
 package mapper
 
 import (
+	"encoding/json"
 	"go/payment-processor/pkg/dto"
 	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/payments/provider"
+	"go/payment-processor/pkg/risk"
 	"go/payment-processor/pkg/utils"
+	"strings"
+
+	"github.com/google/uuid"
 )
 
 func ToPaymentResponse(payment *entities.Payment) *dto.ProcessPaymentResponse {
@@ -12,8 +18,44 @@ func ToPaymentResponse(payment *entities.Payment) *dto.ProcessPaymentResponse {
 		InvoiceID:     payment.InvoiceID,
 		Amount:        payment.Amount,
 		PaymentStatus: payment.PaymentStatus,
+		DeclineCode:   ToDeclineCode(payment.PaymentStatus),
+		Message:       ToPaymentMessage(payment.PaymentStatus),
 		PaymentMethod: payment.PaymentMethod,
-		PaymentSource: payment.PaymentSource,
+		PaymentSource: utils.MaskPaymentSource(payment.PaymentSource),
+	}
+}
+
+// ToDeclineCode maps a payment status to its normalized decline code, or "" when the payment was not declined
+func ToDeclineCode(status string) string {
+	switch status {
+	case utils.PaymentStatusInsufficientFunds:
+		return utils.DeclineCodeInsufficientFunds
+	case utils.PaymentStatusDoNotHonor:
+		return utils.DeclineCodeDoNotHonor
+	case utils.PaymentStatusAuthenticationFailed:
+		return utils.DeclineCodeAuthenticationFailed
+	case utils.PaymentStatusDeclined, utils.PaymentStatusBlocked, utils.PaymentStatusRejected:
+		return utils.DeclineCodeGeneric
+	default:
+		return ""
+	}
+}
+
+// ToPaymentMessage describes a payment status in words that are safe to show to the payer
+func ToPaymentMessage(status string) string {
+	switch status {
+	case utils.PaymentStatusSuccess:
+		return "Payment succeeded."
+	case utils.PaymentStatusRequiresAction:
+		return "Additional authentication is required to complete the payment."
+	case utils.PaymentStatusPendingReview:
+		return "Payment is being reviewed."
+	case utils.PaymentStatusInsufficientFunds:
+		return "Payment was declined due to insufficient funds. Try another payment method."
+	case utils.PaymentStatusAuthenticationFailed:
+		return "Payment authentication failed. Try again or use another payment method."
+	default:
+		return "Payment was declined. Try another payment method or contact your bank."
 	}
 }
 
@@ -26,6 +68,25 @@ func ToPaymentEntity(request *dto.ProcessPaymentRequest) *entities.Payment {
 	}
 }
 
+// ToPaymentReviewResponse maps a payment held for review, decoding the stored risk reasons
+func ToPaymentReviewResponse(payment *entities.Payment) *dto.PaymentReviewResponse {
+	response := &dto.PaymentReviewResponse{
+		PaymentID:     payment.ID,
+		InvoiceID:     payment.InvoiceID,
+		MerchantID:    payment.MerchantID,
+		CustomerID:    payment.CustomerID,
+		Amount:        payment.Amount,
+		PaymentMethod: payment.PaymentMethod,
+		PaymentStatus: payment.PaymentStatus,
+		RiskDecision:  payment.RiskDecision,
+		RiskScore:     payment.RiskScore,
+		RiskReasons:   []risk.Reason{},
+		CreatedAt:     payment.CreatedAt,
+	}
+	_ = json.Unmarshal([]byte(payment.RiskReasons), &response.RiskReasons)
+	return response
+}
+
 func ToInvoiceResponse(invoice *entities.Invoice) *dto.InvoiceResponse {
 	return &dto.InvoiceResponse{
 		ID:         invoice.ID,
@@ -33,16 +94,192 @@ func ToInvoiceResponse(invoice *entities.Invoice) *dto.InvoiceResponse {
 		CustomerID: invoice.CustomerID,
 		Amount:     invoice.Amount,
 		Currency:   invoice.Currency,
+		Status:     invoice.InvoiceStatus,
+		Mode:       invoice.Mode,
+		CreatedAt:  invoice.CreatedAt,
 	}
 }
 
 // ToInvoiceEntity maps a CreateInvoiceRequest DTO to an Invoice entity
 func ToInvoiceEntity(request *dto.CreateInvoiceRequest) *entities.Invoice {
-	return &entities.Invoice{
+	invoice := &entities.Invoice{
 		MerchantID:          request.MerchantID,
 		CustomerID:          request.CustomerID,
 		Amount:              utils.ConvertFloat64ToDecimal(request.Amount),
 		Currency:            request.Currency,
 		OptionalDescription: request.OptionalDescription,
+		InvoiceStatus:       utils.InvoiceStatusPending,
+		Mode:                request.Mode,
+	}
+	if invoice.Mode == "" {
+		invoice.Mode = utils.APIKeyModeLive
+	}
+	return invoice
+}
+
+// ToPaymentDetails maps a Payment entity to the details expected by the payment provider
+func ToPaymentDetails(payment *entities.Payment, referenceID uuid.UUID, currency string) provider.PaymentDetails {
+	details := provider.PaymentDetails{
+		ReferenceID:  referenceID,
+		Amount:       payment.Amount.InexactFloat64(),
+		CurrencyCode: currency,
+	}
+	if strings.EqualFold(payment.PaymentMethod, utils.PaymentMethodBankTransfer) {
+		details.BankAccountNumber = payment.PaymentSource
+	} else {
+		details.CardNumber = payment.PaymentSource
+	}
+	return details
+}
+
+// ToPaymentStatus maps a provider status to the payment status stored on a Payment entity
+func ToPaymentStatus(status provider.PaymentStatus) string {
+	switch status {
+	case provider.PaymentStatusSuccess:
+		return utils.PaymentStatusSuccess
+	case provider.PaymentStatusInsufficientFunds:
+		return utils.PaymentStatusInsufficientFunds
+	case provider.PaymentStatusDoNotHonor:
+		return utils.PaymentStatusDoNotHonor
+	case provider.PaymentStatusRequiresAction:
+		return utils.PaymentStatusRequiresAction
+	case provider.PaymentStatusAuthenticationFailed:
+		return utils.PaymentStatusAuthenticationFailed
+	default:
+		return utils.PaymentStatusDeclined
+	}
+}
+
+func ToDisputeResponse(dispute *entities.Dispute, evidence []entities.DisputeEvidence) *dto.DisputeResponse {
+	response := &dto.DisputeResponse{
+		ID:            dispute.ID,
+		PaymentID:     dispute.PaymentID,
+		InvoiceID:     dispute.InvoiceID,
+		MerchantID:    dispute.MerchantID,
+		Amount:        dispute.Amount,
+		Currency:      dispute.Currency,
+		ReasonCode:    dispute.ReasonCode,
+		DisputeStatus: dispute.DisputeStatus,
+		RespondBy:     dispute.RespondBy,
+		ResolvedAt:    dispute.ResolvedAt,
+	}
+	for i := range evidence {
+		response.Evidence = append(response.Evidence, *ToDisputeEvidenceResponse(&evidence[i]))
+	}
+	return response
+}
+
+func ToDisputeEvidenceResponse(evidence *entities.DisputeEvidence) *dto.DisputeEvidenceResponse {
+	return &dto.DisputeEvidenceResponse{
+		ID:           evidence.ID,
+		EvidenceType: evidence.EvidenceType,
+		Description:  evidence.Description,
+		FileName:     evidence.FileName,
+		ContentType:  evidence.ContentType,
+		Size:         len(evidence.Content),
+		CreatedAt:    evidence.CreatedAt,
+	}
+}
+
+// ToDisputeEvidenceEntity maps a SubmitDisputeEvidenceRequest DTO to a DisputeEvidence entity
+func ToDisputeEvidenceEntity(request *dto.SubmitDisputeEvidenceRequest) *entities.DisputeEvidence {
+	return &entities.DisputeEvidence{
+		DisputeID:    request.DisputeID,
+		EvidenceType: request.EvidenceType,
+		Description:  request.Description,
+		FileName:     request.FileName,
+		ContentType:  request.ContentType,
+		Content:      request.Content,
+	}
+}
+
+func ToMerchantResponse(merchant *entities.Merchant) *dto.MerchantResponse {
+	return &dto.MerchantResponse{
+		ID:                merchant.ID,
+		MerchantName:      merchant.MerchantName,
+		MerchantCode:      merchant.MerchantCode,
+		AllowedCurrencies: utils.SplitCurrencies(merchant.AllowedCurrency),
+		MerchantStatus:    merchant.MerchantStatus,
+		IsActive:          merchant.IsActive,
+		CreatedAt:         merchant.CreatedAt,
+		LastUpdatedAt:     merchant.LastUpdatedAt,
+	}
+}
+
+// ToMerchantEntity maps a CreateMerchantRequest DTO to a Merchant entity
+func ToMerchantEntity(request *dto.CreateMerchantRequest) *entities.Merchant {
+	merchant := &entities.Merchant{
+		MerchantName:    request.MerchantName,
+		MerchantCode:    request.MerchantCode,
+		AllowedCurrency: utils.JoinCurrencies(request.AllowedCurrencies),
+		MerchantStatus:  request.MerchantStatus,
+	}
+	if merchant.MerchantStatus == "" {
+		merchant.MerchantStatus = utils.MerchantStatusActive
+	}
+	merchant.IsActive = true
+	return merchant
+}
+
+func ToCustomerResponse(customer *entities.Customer) *dto.CustomerResponse {
+	return &dto.CustomerResponse{
+		ID:              customer.ID,
+		CustomerName:    customer.CustomerName,
+		CustomerEmail:   customer.CustomerEmail,
+		CustomerAddress: customer.CustomerAddress,
+		IsActive:        customer.IsActive,
+		CreatedAt:       customer.CreatedAt,
+		LastUpdatedAt:   customer.LastUpdatedAt,
+	}
+}
+
+// ToCustomerEntity maps a CreateCustomerRequest DTO to a Customer entity
+func ToCustomerEntity(request *dto.CreateCustomerRequest) *entities.Customer {
+	customer := &entities.Customer{
+		CustomerName:    request.CustomerName,
+		CustomerEmail:   strings.ToLower(strings.TrimSpace(request.CustomerEmail)),
+		CustomerAddress: request.CustomerAddress,
+	}
+	customer.IsActive = true
+	return customer
+}
+
+func ToPaymentMethodResponse(paymentMethod *entities.PaymentMethod) *dto.PaymentMethodResponse {
+	return &dto.PaymentMethodResponse{
+		ID:         paymentMethod.ID,
+		CustomerID: paymentMethod.CustomerID,
+		MethodType: paymentMethod.MethodType,
+		Last4:      paymentMethod.Last4,
+		HolderName: paymentMethod.HolderName,
+		Expiry:     paymentMethod.Expiry,
+		CreatedAt:  paymentMethod.CreatedAt,
+	}
+}
+
+func ToAPIKeyResponse(apiKey *entities.ApiKey) *dto.APIKeyResponse {
+	return &dto.APIKeyResponse{
+		ID:         apiKey.ID,
+		MerchantID: apiKey.MerchantID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Mode:       apiKey.Mode,
+		CreatedAt:  apiKey.CreatedAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		RevokedAt:  apiKey.RevokedAt,
+	}
+}
+
+func ToAuditLogResponse(auditLog *entities.AuditLog) *dto.AuditLogResponse {
+	return &dto.AuditLogResponse{
+		ID:         auditLog.ID,
+		EntityType: auditLog.EntityType,
+		EntityID:   auditLog.EntityID,
+		Action:     auditLog.Action,
+		Actor:      auditLog.Actor,
+		RequestID:  auditLog.RequestID,
+		Changes:    json.RawMessage(auditLog.Changes),
+		CreatedAt:  auditLog.CreatedAt,
+		PrevHash:   auditLog.PrevHash,
+		Hash:       auditLog.Hash,
 	}
 }
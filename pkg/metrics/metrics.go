@@ -0,0 +1,134 @@
+// Package metrics records the Prometheus metrics of the payment processor: HTTP traffic, payment
+// attempts, provider calls, database queries and invoice creation.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "payment_processor"
+
+// Outcomes of provider calls and database queries
+const (
+	OutcomeOK    = "ok"
+	OutcomeError = "error"
+)
+
+// unmatchedRoute labels requests that matched no route, so unknown paths do not create new series
+const unmatchedRoute = "unmatched"
+
+// Metrics holds the collectors. A nil Metrics records nothing.
+type Metrics struct {
+	registry        *prometheus.Registry
+	httpRequests    *prometheus.HistogramVec
+	paymentAttempts *prometheus.CounterVec
+	providerCalls   *prometheus.HistogramVec
+	dbQueries       *prometheus.HistogramVec
+	invoicesCreated *prometheus.CounterVec
+}
+
+// New returns metrics registered on their own registry, along with the Go runtime and process metrics.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "http", Name: "request_duration_seconds",
+			Help:    "Latency of HTTP requests by method, route and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		paymentAttempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "payments", Name: "attempts_total",
+			Help: "Payment attempts by payment method, resulting status and decline code.",
+		}, []string{"method", "status", "decline_code"}),
+		providerCalls: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "provider", Name: "call_duration_seconds",
+			Help:    "Latency of payment provider calls by operation and outcome.",
+			Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"operation", "outcome"}),
+		dbQueries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "db", Name: "query_duration_seconds",
+			Help:    "Duration of database queries by statement type and outcome.",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "outcome"}),
+		invoicesCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "invoices", Name: "created_total",
+			Help: "Invoices created by merchant and currency.",
+		}, []string{"merchant_id", "currency"}),
+	}
+	m.registry.MustRegister(m.httpRequests, m.paymentAttempts, m.providerCalls, m.dbQueries, m.invoicesCreated,
+		collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTPRequest records a request. route is the route pattern, not the path, and empty when no
+// route matched.
+func (m *Metrics) ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	if route == "" {
+		route = unmatchedRoute
+	}
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// PaymentAttempt counts a payment that reached a status, with the decline code sent to the client if any.
+func (m *Metrics) PaymentAttempt(method, status, declineCode string) {
+	if m == nil {
+		return
+	}
+	m.paymentAttempts.WithLabelValues(method, status, declineCode).Inc()
+}
+
+// ObserveProviderCall records a call to the payment provider.
+func (m *Metrics) ObserveProviderCall(operation string, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.providerCalls.WithLabelValues(operation, outcome(err)).Observe(duration.Seconds())
+}
+
+// ObserveQuery records a database query, labelled by its statement type.
+func (m *Metrics) ObserveQuery(sql string, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.dbQueries.WithLabelValues(statementType(sql), outcome(err)).Observe(duration.Seconds())
+}
+
+// InvoiceCreated counts a created invoice.
+func (m *Metrics) InvoiceCreated(merchantID uint, currency string) {
+	if m == nil {
+		return
+	}
+	m.invoicesCreated.WithLabelValues(strconv.FormatUint(uint64(merchantID), 10), currency).Inc()
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return OutcomeError
+	}
+	return OutcomeOK
+}
+
+// statementType returns the SQL command of a statement, such as SELECT or INSERT
+func statementType(sql string) string {
+	command, _, _ := strings.Cut(strings.TrimSpace(sql), " ")
+	switch command = strings.ToUpper(command); command {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "BEGIN", "COMMIT", "ROLLBACK", "SAVEPOINT":
+		return command
+	}
+	return "OTHER"
+}
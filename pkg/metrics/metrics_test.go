@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	assert.NoError(t, err)
+	return string(body)
+}
+
+func TestMetricsAreExposed(t *testing.T) {
+	m := New()
+	m.ObserveHTTPRequest(http.MethodGet, "/payment-process/invoices/:id", http.StatusOK, 20*time.Millisecond)
+	m.ObserveHTTPRequest(http.MethodGet, "", http.StatusNotFound, time.Millisecond)
+	m.PaymentAttempt("credit_card", "DECLINED", "generic_decline")
+	m.ObserveProviderCall("pay", time.Second, errors.New("unavailable"))
+	m.ObserveQuery(`  select * FROM "invoice" WHERE id = 1`, time.Millisecond, nil)
+	m.InvoiceCreated(7, "USD")
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `payment_processor_http_request_duration_seconds_count{method="GET",route="/payment-process/invoices/:id",status="200"} 1`)
+	assert.Contains(t, body, `route="unmatched",status="404"`)
+	assert.Contains(t, body, `payment_processor_payments_attempts_total{decline_code="generic_decline",method="credit_card",status="DECLINED"} 1`)
+	assert.Contains(t, body, `payment_processor_provider_call_duration_seconds_count{operation="pay",outcome="error"} 1`)
+	assert.Contains(t, body, `payment_processor_db_query_duration_seconds_count{operation="SELECT",outcome="ok"} 1`)
+	assert.Contains(t, body, `payment_processor_invoices_created_total{currency="USD",merchant_id="7"} 1`)
+	assert.Contains(t, body, "go_goroutines")
+}
+
+func TestNilMetricsRecordNothing(t *testing.T) {
+	var m *Metrics
+	m.ObserveHTTPRequest(http.MethodGet, "/", http.StatusOK, time.Millisecond)
+	m.PaymentAttempt("credit_card", "SUCCESS", "")
+	m.ObserveProviderCall("pay", time.Millisecond, nil)
+	m.ObserveQuery("SELECT 1", time.Millisecond, nil)
+	m.InvoiceCreated(1, "USD")
+}
+
+func TestStatementType(t *testing.T) {
+	assert.Equal(t, "INSERT", statementType(`INSERT INTO "payment" ("amount") VALUES (1)`))
+	assert.Equal(t, "UPDATE", statementType("update invoice set invoice_status = 'PAID'"))
+	assert.Equal(t, "OTHER", statementType("WITH recent AS (SELECT 1) SELECT * FROM recent"))
+	assert.Equal(t, "OTHER", statementType(""))
+}
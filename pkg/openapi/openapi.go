@@ -0,0 +1,273 @@
+// Package openapi builds an OpenAPI 3.1 document from route descriptions and the Go types the
+// handlers bind requests to and render responses from, so the document cannot drift from the DTOs.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is the OpenAPI version of the generated documents
+const Version = "3.1.0"
+
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Servers    []Server              `json:"servers,omitempty"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []SecurityRequirement `json:"security,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+// PathItem maps lower case HTTP methods to the operations of a path
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []SecurityRequirement `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Description  string `json:"description,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// SecurityRequirement maps security scheme names to the scopes an operation needs
+type SecurityRequirement map[string][]string
+
+// Route describes an operation of the API.
+type Route struct {
+	Method      string
+	Path        string // Echo path, with :name path parameters
+	OperationID string
+	Summary     string
+	Tag         string
+	// Request is a value of the type the handler binds. Fields tagged json or form make up the body,
+	// fields tagged query the query parameters and fields tagged param the path parameters.
+	Request            any
+	RequestContentType string   // defaults to application/json
+	FormFiles          []string // file parts of a multipart request
+	// Responses maps status codes to a value of the response body type, nil for responses without a body
+	Responses map[int]any
+	Public    bool // the operation does not require credentials
+}
+
+// Builder collects routes into a Document.
+type Builder struct {
+	doc          *Document
+	schemas      *schemaRegistry
+	errorType    string
+	errorSchema  *Schema
+	errorSummary string
+}
+
+func NewBuilder(info Info, serverURL string) *Builder {
+	doc := &Document{
+		OpenAPI:    Version,
+		Info:       info,
+		Paths:      make(map[string]PathItem),
+		Components: Components{Schemas: make(map[string]*Schema)},
+	}
+	if serverURL != "" {
+		doc.Servers = []Server{{URL: serverURL}}
+	}
+	return &Builder{doc: doc, schemas: newSchemaRegistry(doc.Components.Schemas)}
+}
+
+// SecurityScheme registers a way to authenticate; every operation that is not public accepts any of them.
+func (b *Builder) SecurityScheme(name string, scheme SecurityScheme) *Builder {
+	if b.doc.Components.SecuritySchemes == nil {
+		b.doc.Components.SecuritySchemes = make(map[string]*SecurityScheme)
+	}
+	b.doc.Components.SecuritySchemes[name] = &scheme
+	b.doc.Security = append(b.doc.Security, SecurityRequirement{name: {}})
+	return b
+}
+
+// DefaultError documents the body every operation responds with on errors.
+func (b *Builder) DefaultError(contentType string, body any) *Builder {
+	b.errorType = contentType
+	b.errorSchema = b.schemas.schemaFor(reflect.TypeOf(body), false)
+	return b
+}
+
+// Add documents a route.
+func (b *Builder) Add(route Route) *Builder {
+	path, pathParams := convertPath(route.Path)
+	operation := &Operation{
+		OperationID: route.OperationID,
+		Summary:     route.Summary,
+		Responses:   make(map[string]*Response),
+	}
+	if route.Tag != "" {
+		operation.Tags = []string{route.Tag}
+	}
+	if route.Public {
+		operation.Security = []SecurityRequirement{}
+	}
+
+	var requestType reflect.Type
+	if route.Request != nil {
+		requestType = indirect(reflect.TypeOf(route.Request))
+	}
+	for _, name := range pathParams {
+		schema := &Schema{Type: "integer", Minimum: float(1)}
+		if field, ok := findTagged(requestType, "param", name); ok {
+			schema = b.schemas.schemaFor(field.Type, true)
+		}
+		operation.Parameters = append(operation.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	if requestType != nil {
+		operation.Parameters = append(operation.Parameters, b.queryParameters(requestType)...)
+		operation.RequestBody = b.requestBody(route, requestType)
+	}
+
+	for status, body := range route.Responses {
+		response := &Response{Description: http.StatusText(status)}
+		if body != nil {
+			response.Content = map[string]MediaType{"application/json": {Schema: b.schemas.schemaFor(reflect.TypeOf(body), false)}}
+		}
+		operation.Responses[strconv.Itoa(status)] = response
+	}
+	if b.errorSchema != nil {
+		operation.Responses["default"] = &Response{Description: "Error", Content: map[string]MediaType{b.errorType: {Schema: b.errorSchema}}}
+	}
+
+	item, ok := b.doc.Paths[path]
+	if !ok {
+		item = make(PathItem)
+		b.doc.Paths[path] = item
+	}
+	item[strings.ToLower(route.Method)] = operation
+	return b
+}
+
+// Document returns the document built so far.
+func (b *Builder) Document() *Document {
+	return b.doc
+}
+
+func (b *Builder) queryParameters(requestType reflect.Type) []Parameter {
+	var parameters []Parameter
+	for _, field := range fields(requestType) {
+		name, _ := tagName(field, "query")
+		if name == "" {
+			continue
+		}
+		rules := validationRules(field)
+		schema := b.schemas.schemaFor(field.Type, true)
+		applyRules(schema, rules)
+		parameters = append(parameters, Parameter{Name: name, In: "query", Required: rules.required, Schema: schema})
+	}
+	return parameters
+}
+
+func (b *Builder) requestBody(route Route, requestType reflect.Type) *RequestBody {
+	contentType := route.RequestContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	var schema *Schema
+	if contentType == "application/json" {
+		if !hasTag(requestType, "json") {
+			return nil
+		}
+		schema = b.schemas.schemaFor(requestType, true)
+	} else {
+		schema = b.schemas.objectSchema(requestType, "form", true)
+		for _, file := range route.FormFiles {
+			schema.Properties[file] = &Schema{Type: "string", ContentMediaType: "application/octet-stream"}
+			schema.Required = append(schema.Required, file)
+		}
+		sort.Strings(schema.Required)
+	}
+	return &RequestBody{Required: true, Content: map[string]MediaType{contentType: {Schema: schema}}}
+}
+
+// convertPath turns an Echo path into an OpenAPI path and lists its parameters
+func convertPath(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+	var params []string
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			params = append(params, segment[1:])
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// EchoPath turns an OpenAPI path back into an Echo path
+func EchoPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":" + segment[1:len(segment)-1]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Operation finds the operation for an Echo route
+func (d *Document) Operation(method, echoPath string) (*Operation, error) {
+	path, _ := convertPath(echoPath)
+	item, ok := d.Paths[path]
+	if !ok {
+		return nil, fmt.Errorf("path %s is not documented", path)
+	}
+	operation, ok := item[strings.ToLower(method)]
+	if !ok {
+		return nil, fmt.Errorf("%s %s is not documented", method, path)
+	}
+	return operation, nil
+}
@@ -0,0 +1,124 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+type widgetRequest struct {
+	ID       uint    `param:"id"`
+	Name     string  `json:"name" validate:"required,max=20"`
+	Color    string  `json:"color,omitempty" validate:"omitempty,oneof=red blue"`
+	Quantity int     `json:"quantity" validate:"gte=1"`
+	Internal string  `json:"-"`
+	Price    float64 `query:"price"`
+}
+
+type widgetResponse struct {
+	ID        uint            `json:"id"`
+	Name      string          `json:"name"`
+	Price     decimal.Decimal `json:"price"`
+	Note      string          `json:"note,omitempty"`
+	CreatedAt *time.Time      `json:"created_at"`
+	Parts     []widgetPart    `json:"parts"`
+}
+
+type widgetPart struct {
+	Label string `json:"label"`
+}
+
+func testDocument() *Document {
+	return NewBuilder(Info{Title: "Widgets", Version: "1"}, "/api").
+		Add(Route{Method: http.MethodPost, Path: "/widgets/:id", OperationID: "updateWidget",
+			Request: widgetRequest{}, Responses: map[int]any{http.StatusOK: widgetResponse{}, http.StatusNoContent: nil}}).
+		Document()
+}
+
+func TestBuilderDerivesSchemasFromTags(t *testing.T) {
+	document := testDocument()
+	operation, err := document.Operation(http.MethodPost, "/widgets/:id")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []Parameter{
+		{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer", Minimum: float(0)}},
+		{Name: "price", In: "query", Schema: &Schema{Type: "number"}},
+	}, operation.Parameters)
+
+	request := document.Components.Schemas["widgetRequest"]
+	assert.Equal(t, []string{"name"}, request.Required)
+	assert.Equal(t, []any{"red", "blue"}, request.Properties["color"].Enum)
+	assert.Equal(t, 20, *request.Properties["name"].MaxLength)
+	assert.Equal(t, 1.0, *request.Properties["quantity"].Minimum)
+	assert.NotContains(t, request.Properties, "internal")
+	assert.NotContains(t, request.Properties, "price")
+
+	response := document.Components.Schemas["widgetResponse"]
+	assert.Equal(t, []string{"created_at", "id", "name", "parts", "price"}, response.Required)
+	assert.Equal(t, []string{"string", "null"}, response.Properties["created_at"].Type)
+	assert.Equal(t, false, response.AdditionalProperties)
+}
+
+func TestValidateResponse(t *testing.T) {
+	document := testDocument()
+	now := time.Now()
+	valid, _ := json.Marshal(widgetResponse{ID: 1, Name: "gear", Price: decimal.RequireFromString("9.99"), CreatedAt: &now,
+		Parts: []widgetPart{{Label: "tooth"}}})
+
+	assert.NoError(t, document.ValidateResponse(http.MethodPost, "/widgets/:id", http.StatusOK, "application/json; charset=UTF-8", valid))
+	assert.NoError(t, document.ValidateResponse(http.MethodPost, "/widgets/:id", http.StatusNoContent, "", nil))
+
+	tests := map[string]struct {
+		status int
+		body   string
+		err    string
+	}{
+		"missing property":    {http.StatusOK, `{"id":1,"name":"gear","price":"1","created_at":null}`, `missing required property "parts"`},
+		"unexpected property": {http.StatusOK, `{"id":1,"name":"gear","price":"1","created_at":null,"parts":null,"size":3}`, `unexpected property "size"`},
+		"wrong type":          {http.StatusOK, `{"id":"1","name":"gear","price":"1","created_at":null,"parts":null}`, "$.id: expected integer"},
+		"nested item":         {http.StatusOK, `{"id":1,"name":"gear","price":"1","created_at":null,"parts":[{}]}`, `$.parts[0]: missing required property "label"`},
+		"undocumented status": {http.StatusAccepted, `{}`, "status 202 is not documented"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := document.ValidateResponse(http.MethodPost, "/widgets/:id", tt.status, "application/json", []byte(tt.body))
+			if assert.Error(t, err) {
+				assert.True(t, strings.Contains(err.Error(), tt.err), err.Error())
+			}
+		})
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	document := testDocument()
+
+	assert.NoError(t, document.ValidateRequest(http.MethodPost, "/widgets/:id", "/widgets/7?price=9.5", "application/json",
+		[]byte(`{"name":"gear","color":"red","quantity":2}`)))
+
+	tests := map[string]struct {
+		target string
+		body   string
+		err    string
+	}{
+		"invalid path parameter":   {"/widgets/abc", `{"name":"gear","quantity":1}`, `path parameter id: expected integer, got string`},
+		"invalid query parameter":  {"/widgets/7?price=cheap", `{"name":"gear","quantity":1}`, `query parameter price: expected number, got string`},
+		"unknown query parameter":  {"/widgets/7?size=3", `{"name":"gear","quantity":1}`, `query parameter "size" is not documented`},
+		"missing body":             {"/widgets/7", "", "missing request body"},
+		"missing property":         {"/widgets/7", `{"quantity":1}`, `missing required property "name"`},
+		"undocumented property":    {"/widgets/7", `{"name":"gear","quantity":1,"size":3}`, `unexpected property "size"`},
+		"value outside validation": {"/widgets/7", `{"name":"gear","color":"green","quantity":1}`, `$.color: green is not one of`},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := document.ValidateRequest(http.MethodPost, "/widgets/:id", tt.target, "application/json", []byte(tt.body))
+			if assert.Error(t, err) {
+				assert.True(t, strings.Contains(err.Error(), tt.err), err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Schema is a JSON Schema (draft 2020-12) as used by OpenAPI 3.1. Type is a string, or a list of
+// strings for nullable values.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	ContentMediaType     string             `json:"contentMediaType,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"` // *Schema or false
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+}
+
+const componentRefPrefix = "#/components/schemas/"
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	decimalType       = reflect.TypeOf(decimal.Decimal{})
+	uuidType          = reflect.TypeOf(uuid.UUID{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemaRegistry turns Go types into schemas, storing named structs as components. Request and
+// response schemas of a type differ: requests list the fields the validator requires, responses
+// list every field that is always rendered and reject unknown ones.
+type schemaRegistry struct {
+	components map[string]*Schema
+	names      map[schemaKey]string
+}
+
+type schemaKey struct {
+	t       reflect.Type
+	request bool
+}
+
+func newSchemaRegistry(components map[string]*Schema) *schemaRegistry {
+	return &schemaRegistry{components: components, names: make(map[schemaKey]string)}
+}
+
+func (r *schemaRegistry) schemaFor(t reflect.Type, request bool) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case decimalType:
+		return &Schema{Type: "string", Format: "decimal", Pattern: `^-?\d+(\.\d+)?$`}
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(r.schemaFor(t.Elem(), request))
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: float(0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		// encoding/json renders nil slices as null
+		return nullable(&Schema{Type: "array", Items: r.schemaFor(t.Elem(), request)})
+	case reflect.Array:
+		return &Schema{Type: "array", Items: r.schemaFor(t.Elem(), request)}
+	case reflect.Map:
+		return nullable(&Schema{Type: "object", AdditionalProperties: r.schemaFor(t.Elem(), request)})
+	case reflect.Struct:
+		if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+			return &Schema{Type: "string"}
+		}
+		return r.component(t, request)
+	}
+	return &Schema{}
+}
+
+// component stores the schema of a named struct once and refers to it
+func (r *schemaRegistry) component(t reflect.Type, request bool) *Schema {
+	key := schemaKey{t, request}
+	if name, ok := r.names[key]; ok {
+		return &Schema{Ref: componentRefPrefix + name}
+	}
+	if t.Name() == "" {
+		return r.objectSchema(t, "json", request)
+	}
+
+	name := t.Name()
+	if _, taken := r.components[name]; taken {
+		// The other variant of the type already uses the plain name
+		if request {
+			name += "Input"
+		} else {
+			name += "Output"
+		}
+	}
+	r.names[key] = name
+	r.components[name] = &Schema{} // placeholder for recursive types
+	r.components[name] = r.objectSchema(t, "json", request)
+	return &Schema{Ref: componentRefPrefix + name}
+}
+
+// objectSchema describes the fields of a struct carrying the given tag
+func (r *schemaRegistry) objectSchema(t reflect.Type, tag string, request bool) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	if !request {
+		schema.AdditionalProperties = false
+	}
+	for _, field := range fields(t) {
+		name, omitEmpty := tagName(field, tag)
+		if name == "" {
+			continue
+		}
+		property := r.schemaFor(field.Type, request)
+		if request {
+			rules := validationRules(field)
+			property = applyRules(property, rules)
+			if rules.required {
+				schema.Required = append(schema.Required, name)
+			}
+		} else if !omitEmpty {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+	sort.Strings(schema.Required)
+	return schema
+}
+
+// fields lists the exported fields of a struct, including those of embedded structs
+func fields(t reflect.Type) []reflect.StructField {
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	var result []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			result = append(result, fields(indirect(field.Type))...)
+			continue
+		}
+		if field.IsExported() {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+// tagName returns the name a field is encoded under, or "" when the tag excludes it
+func tagName(field reflect.StructField, tag string) (string, bool) {
+	value, ok := field.Tag.Lookup(tag)
+	if !ok {
+		if tag == "json" && field.Tag.Get("query") == "" && field.Tag.Get("param") == "" && field.Tag.Get("form") == "" {
+			// encoding/json falls back to the field name
+			return field.Name, false
+		}
+		return "", false
+	}
+	name, options, _ := strings.Cut(value, ",")
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(options, "omitempty")
+}
+
+func findTagged(t reflect.Type, tag, name string) (reflect.StructField, bool) {
+	for _, field := range fields(t) {
+		if tagged, _ := tagName(field, tag); tagged == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func hasTag(t reflect.Type, tag string) bool {
+	for _, field := range fields(t) {
+		if _, ok := field.Tag.Lookup(tag); ok {
+			if name, _ := tagName(field, tag); name != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type rules struct {
+	required bool
+	values   []string // validator rules besides required and omitempty
+}
+
+// validationRules reads the go-playground/validator tag of a field
+func validationRules(field reflect.StructField) rules {
+	var result rules
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		switch {
+		case rule == "", rule == "omitempty":
+		case rule == "required":
+			result.required = true
+		case rule == "dive":
+			return result
+		default:
+			result.values = append(result.values, rule)
+		}
+	}
+	return result
+}
+
+// applyRules narrows a schema with the validator rules the JSON Schema vocabulary can express
+func applyRules(schema *Schema, rules rules) *Schema {
+	if schema.Ref != "" || len(rules.values) == 0 {
+		return schema
+	}
+	numeric := hasType(schema, "integer") || hasType(schema, "number")
+	for _, rule := range rules.values {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, value)
+			}
+		case "eq":
+			schema.Enum = []any{param}
+		case "email":
+			schema.Format = "email"
+		case "url":
+			schema.Format = "uri"
+		case "uuid", "uuid4", "uuid7":
+			schema.Format = "uuid"
+		case "datetime":
+			schema.Format = "date-time"
+		case "iso4217":
+			schema.Pattern = "^[A-Z]{3}$"
+		case "iso3166_1_alpha2":
+			schema.Pattern = "^[A-Z]{2}$"
+		case "len":
+			if n, err := strconv.Atoi(param); err == nil && !numeric {
+				schema.MinLength, schema.MaxLength = &n, &n
+			}
+		case "min", "gte", "max", "lte", "gt":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			switch {
+			case numeric && (name == "min" || name == "gte"):
+				schema.Minimum = &n
+			case numeric && (name == "max" || name == "lte"):
+				schema.Maximum = &n
+			case numeric && name == "gt":
+				schema.ExclusiveMinimum = &n
+			case hasType(schema, "array") && (name == "min" || name == "gte"):
+				length := int(n)
+				schema.MinItems = &length
+			case hasType(schema, "array") && (name == "max" || name == "lte"):
+				length := int(n)
+				schema.MaxItems = &length
+			case name == "min" || name == "gte":
+				length := int(n)
+				schema.MinLength = &length
+			case name == "max" || name == "lte":
+				length := int(n)
+				schema.MaxLength = &length
+			}
+		}
+	}
+	return schema
+}
+
+// nullable allows null besides the values of the schema
+func nullable(schema *Schema) *Schema {
+	if typeName, ok := schema.Type.(string); ok {
+		schema.Type = []string{typeName, "null"}
+		return schema
+	}
+	if schema.Type == nil && schema.Ref == "" {
+		return schema
+	}
+	return &Schema{AnyOf: []*Schema{schema, {Type: "null"}}}
+}
+
+func hasType(schema *Schema, name string) bool {
+	switch t := schema.Type.(type) {
+	case string:
+		return t == name
+	case []string:
+		for _, typeName := range t {
+			if typeName == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func float(value float64) *float64 {
+	return &value
+}
@@ -0,0 +1,316 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ValidateRequest checks a request against the operation documented for the Echo route, so that a
+// handler binding another type than documented is caught. target is the path and query of the
+// request below the server URL. Path and query parameters must be documented and match their
+// schemas, as must JSON bodies, which unlike responses may not carry undocumented properties either.
+func (d *Document) ValidateRequest(method, echoPath, target, contentType string, body []byte) error {
+	operation, err := d.Operation(method, echoPath)
+	if err != nil {
+		return err
+	}
+	requestURL, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("%s %s: invalid target %q: %w", method, echoPath, target, err)
+	}
+
+	documentedPath, _ := convertPath(echoPath)
+	pathSegments := strings.Split(requestURL.Path, "/")
+	documentedSegments := strings.Split(documentedPath, "/")
+	if len(pathSegments) != len(documentedSegments) {
+		return fmt.Errorf("%s %s: target %q does not match the path", method, echoPath, requestURL.Path)
+	}
+	query := requestURL.Query()
+	for _, parameter := range operation.Parameters {
+		var raw []string
+		switch parameter.In {
+		case "path":
+			for i, segment := range documentedSegments {
+				if segment == "{"+parameter.Name+"}" {
+					raw = []string{pathSegments[i]}
+				}
+			}
+		case "query":
+			raw = query[parameter.Name]
+			query.Del(parameter.Name)
+		}
+		if len(raw) == 0 {
+			if parameter.Required {
+				return fmt.Errorf("%s %s: missing required %s parameter %q", method, echoPath, parameter.In, parameter.Name)
+			}
+			continue
+		}
+		if err := d.validate(parameter.Schema, parameterValue(parameter.Schema, raw), parameter.Name, true); err != nil {
+			return fmt.Errorf("%s %s: %s parameter %w", method, echoPath, parameter.In, err)
+		}
+	}
+	for name := range query {
+		// The documented parameters were removed above, any left is unknown to the document
+		return fmt.Errorf("%s %s: query parameter %q is not documented", method, echoPath, name)
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if operation.RequestBody != nil && operation.RequestBody.Required {
+			return fmt.Errorf("%s %s: missing request body", method, echoPath)
+		}
+		return nil
+	}
+	if operation.RequestBody == nil {
+		return fmt.Errorf("%s %s: request body is not documented", method, echoPath)
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	content, ok := operation.RequestBody.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("%s %s: request content type %q is not documented", method, echoPath, contentType)
+	}
+	if mediaType != "application/json" {
+		// Form bodies are bound by name, their parts are not checked
+		return nil
+	}
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("%s %s: invalid JSON request body: %w", method, echoPath, err)
+	}
+	if err := d.validate(content.Schema, value, "$", true); err != nil {
+		return fmt.Errorf("%s %s: request body %w", method, echoPath, err)
+	}
+	return nil
+}
+
+// parameterValue decodes the raw values of a parameter into the JSON value its schema describes.
+// Values that do not parse are kept as strings, so validation reports them.
+func parameterValue(schema *Schema, raw []string) any {
+	if hasType(schema, "array") && schema.Items != nil {
+		values := make([]any, len(raw))
+		for i, item := range raw {
+			values[i] = parameterValue(schema.Items, []string{item})
+		}
+		return values
+	}
+	value := raw[0]
+	switch {
+	case hasType(schema, "integer"), hasType(schema, "number"):
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return json.Number(value)
+		}
+	case hasType(schema, "boolean"):
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// ValidateResponse checks a response against the operation documented for the Echo route. The
+// body must match the schema of the status code, or the default response when the status is not
+// listed.
+func (d *Document) ValidateResponse(method, echoPath string, status int, contentType string, body []byte) error {
+	operation, err := d.Operation(method, echoPath)
+	if err != nil {
+		return err
+	}
+	response, ok := operation.Responses[strconv.Itoa(status)]
+	if !ok {
+		if status < 400 {
+			return fmt.Errorf("%s %s: status %d is not documented", method, echoPath, status)
+		}
+		if response, ok = operation.Responses["default"]; !ok {
+			return fmt.Errorf("%s %s: status %d is not documented", method, echoPath, status)
+		}
+	}
+	if len(response.Content) == 0 {
+		if len(bytes.TrimSpace(body)) > 0 {
+			return fmt.Errorf("%s %s: status %d is documented without a body", method, echoPath, status)
+		}
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	content, ok := response.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("%s %s: content type %q is not documented for status %d", method, echoPath, contentType, status)
+	}
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("%s %s: invalid JSON body: %w", method, echoPath, err)
+	}
+	return d.Validate(content.Schema, value)
+}
+
+// Validate checks a decoded JSON value, decoded with json.Decoder.UseNumber, against a schema of the document.
+func (d *Document) Validate(schema *Schema, value any) error {
+	return d.validate(schema, value, "$", false)
+}
+
+// validate checks value against schema. Strict validation also rejects object properties the schema
+// does not list when it says nothing about additional properties.
+func (d *Document) validate(schema *Schema, value any, path string, strict bool) error {
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, componentRefPrefix)
+		target, ok := d.Components.Schemas[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown schema %s", path, schema.Ref)
+		}
+		return d.validate(target, value, path, strict)
+	}
+	if len(schema.AnyOf) > 0 {
+		var errs []string
+		for _, candidate := range schema.AnyOf {
+			err := d.validate(candidate, value, path, strict)
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err.Error())
+		}
+		return fmt.Errorf("%s: matches none of the allowed schemas (%s)", path, strings.Join(errs, "; "))
+	}
+
+	if schema.Type != nil && !matchesType(schema.Type, value) {
+		return fmt.Errorf("%s: expected %v, got %s", path, schema.Type, jsonType(value))
+	}
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		return fmt.Errorf("%s: %v is not one of %v", path, value, schema.Enum)
+	}
+
+	switch v := value.(type) {
+	case string:
+		if schema.Pattern != "" && !regexp.MustCompile(schema.Pattern).MatchString(v) {
+			return fmt.Errorf("%s: %q does not match %s", path, v, schema.Pattern)
+		}
+		if schema.MinLength != nil && len(v) < *schema.MinLength {
+			return fmt.Errorf("%s: shorter than %d", path, *schema.MinLength)
+		}
+		if schema.MaxLength != nil && len(v) > *schema.MaxLength {
+			return fmt.Errorf("%s: longer than %d", path, *schema.MaxLength)
+		}
+	case json.Number:
+		n, _ := v.Float64()
+		if schema.Minimum != nil && n < *schema.Minimum {
+			return fmt.Errorf("%s: %v is less than %v", path, n, *schema.Minimum)
+		}
+		if schema.Maximum != nil && n > *schema.Maximum {
+			return fmt.Errorf("%s: %v is greater than %v", path, n, *schema.Maximum)
+		}
+	case []any:
+		if schema.MinItems != nil && len(v) < *schema.MinItems {
+			return fmt.Errorf("%s: fewer than %d items", path, *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(v) > *schema.MaxItems {
+			return fmt.Errorf("%s: more than %d items", path, *schema.MaxItems)
+		}
+		if schema.Items != nil {
+			for i, item := range v {
+				if err := d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), strict); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]any:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+		for name, property := range v {
+			propertySchema, ok := schema.Properties[name]
+			if !ok {
+				switch additional := schema.AdditionalProperties.(type) {
+				case bool:
+					if !additional {
+						return fmt.Errorf("%s: unexpected property %q", path, name)
+					}
+					continue
+				case *Schema:
+					propertySchema = additional
+				default:
+					if strict && schema.Type != nil {
+						return fmt.Errorf("%s: unexpected property %q", path, name)
+					}
+					continue
+				}
+			}
+			if err := d.validate(propertySchema, property, path+"."+name, strict); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func matchesType(schemaType any, value any) bool {
+	switch t := schemaType.(type) {
+	case string:
+		return matchesTypeName(t, value)
+	case []string:
+		for _, name := range t {
+			if matchesTypeName(name, value) {
+				return true
+			}
+		}
+		return false
+	case []any:
+		for _, name := range t {
+			if s, ok := name.(string); ok && matchesTypeName(s, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesTypeName(name string, value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return name == "null"
+	case bool:
+		return name == "boolean"
+	case string:
+		return name == "string"
+	case json.Number:
+		if name == "number" {
+			return true
+		}
+		n, err := v.Float64()
+		return name == "integer" && err == nil && n == math.Trunc(n)
+	case []any:
+		return name == "array"
+	case map[string]any:
+		return name == "object"
+	}
+	return false
+}
+
+func jsonType(value any) string {
+	for _, name := range []string{"null", "boolean", "string", "integer", "number", "array", "object"} {
+		if matchesTypeName(name, value) {
+			return name
+		}
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func inEnum(enum []any, value any) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
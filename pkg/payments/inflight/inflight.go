@@ -0,0 +1,91 @@
+// Package inflight tracks the payments that are being authorized by the provider, so the server
+// can wait for them on shutdown and flag the ones it could not wait for.
+package inflight
+
+import (
+	"context"
+	"go/payment-processor/pkg/entities"
+	"sync"
+)
+
+// Tracker records the payments between the provider call and the moment their outcome is stored.
+// A nil Tracker tracks nothing.
+type Tracker struct {
+	mu       sync.Mutex
+	next     uint64
+	payments map[uint64]entities.Payment
+	idle     chan struct{} // closed while no payment is in flight
+}
+
+func NewTracker() *Tracker {
+	idle := make(chan struct{})
+	close(idle)
+	return &Tracker{payments: make(map[uint64]entities.Payment), idle: idle}
+}
+
+// Begin records that payment is being sent to the provider. The returned function must be called
+// once its outcome is stored or the provider call failed.
+func (t *Tracker) Begin(payment entities.Payment) (done func()) {
+	if t == nil {
+		return func() {}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.payments) == 0 {
+		t.idle = make(chan struct{})
+	}
+	t.next++
+	id := t.next
+	t.payments[id] = payment
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { t.end(id) })
+	}
+}
+
+func (t *Tracker) end(id uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.payments, id)
+	if len(t.payments) == 0 {
+		close(t.idle)
+	}
+}
+
+// Len returns the number of payments in flight.
+func (t *Tracker) Len() int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.payments)
+}
+
+// Wait blocks until no payment is in flight or ctx is done. It returns the payments that were
+// still in flight, which is empty unless ctx ended first.
+func (t *Tracker) Wait(ctx context.Context) []entities.Payment {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pending := make([]entities.Payment, 0, len(t.payments))
+	for _, payment := range t.payments {
+		pending = append(pending, payment)
+	}
+	return pending
+}
@@ -0,0 +1,49 @@
+package inflight
+
+import (
+	"context"
+	"go/payment-processor/pkg/entities"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitReturnsOnceIdle(t *testing.T) {
+	tracker := NewTracker()
+	assert.Empty(t, tracker.Wait(context.Background()))
+
+	done := tracker.Begin(entities.Payment{ReferenceID: "ref-1"})
+	assert.Equal(t, 1, tracker.Len())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		done()
+		done() // calling done twice is harmless
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Empty(t, tracker.Wait(ctx))
+	assert.Equal(t, 0, tracker.Len())
+}
+
+func TestWaitReturnsPendingPaymentsAtDeadline(t *testing.T) {
+	tracker := NewTracker()
+	tracker.Begin(entities.Payment{ReferenceID: "ref-1"})
+	done := tracker.Begin(entities.Payment{ReferenceID: "ref-2"})
+	done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	pending := tracker.Wait(ctx)
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, "ref-1", pending[0].ReferenceID)
+	}
+}
+
+func TestNilTracker(t *testing.T) {
+	var tracker *Tracker
+	tracker.Begin(entities.Payment{})()
+	assert.Equal(t, 0, tracker.Len())
+	assert.Nil(t, tracker.Wait(context.Background()))
+}
@@ -2,15 +2,30 @@ package provider
 
 import (
 	"context"
+	"errors"
+	"go/payment-processor/pkg/tracing"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	// ErrChallengeNotFound is returned when completing a challenge the provider is not waiting for
+	ErrChallengeNotFound = errors.New("no pending challenge for this payment")
+	// ErrUnavailable is returned when the provider cannot take payments; nothing was charged
+	ErrUnavailable = errors.New("payment provider unavailable")
 )
 
 type PaymentProvider struct {
+	// mu guards the maps. The maps are shared between copies of the value, so the lock is as well.
+	mu             *sync.RWMutex
 	byIDs          map[uuid.UUID]PaymentStatus
 	byReferenceIDs map[uuid.UUID]PaymentStatus
+	// challenges holds the details of payments waiting for the cardholder to pass a 3-D Secure challenge
+	challenges map[uuid.UUID]PaymentDetails
 }
 
 type Payment struct {
@@ -36,21 +51,87 @@ const (
 	PaymentStatusInsufficientFunds
 	PaymentStatusDoNotHonor
 	PaymentStatusDeclined
+	PaymentStatusRequiresAction
+	PaymentStatusAuthenticationFailed
 )
 
 func New() PaymentProvider {
 	return PaymentProvider{
+		mu:             &sync.RWMutex{},
 		byIDs:          make(map[uuid.UUID]PaymentStatus),
 		byReferenceIDs: make(map[uuid.UUID]PaymentStatus),
+		challenges:     make(map[uuid.UUID]PaymentDetails),
 	}
 }
 
-func (p PaymentProvider) Pay(ctx context.Context, details PaymentDetails) (Payment, error) {
+// Ping reports whether the provider takes payments. The simulated provider runs in process, so it is
+// reachable as long as the caller is still waiting.
+func (p PaymentProvider) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (p PaymentProvider) Pay(ctx context.Context, details PaymentDetails) (_ Payment, err error) {
+	_, span := tracing.Start(ctx, "provider.Pay", attribute.String("payment.reference_id", details.ReferenceID.String()),
+		attribute.String("payment.currency", details.CurrencyCode))
+	defer tracing.End(span, &err)
+
+	// Cards ending in 6767 simulate an outage of the provider
+	if strings.HasSuffix(details.CardNumber, "6767") {
+		return Payment{}, ErrUnavailable
+	}
+
 	id, err := uuid.NewV7()
 	if err != nil {
 		return Payment{}, err
 	}
 
+	// Cards ending in 5656 require a 3-D Secure challenge before they are authorized
+	if strings.HasSuffix(details.CardNumber, "5656") {
+		p.mu.Lock()
+		p.challenges[id] = details
+		p.byIDs[id] = PaymentStatusRequiresAction
+		p.byReferenceIDs[details.ReferenceID] = PaymentStatusRequiresAction
+		p.mu.Unlock()
+		return Payment{ID: id, Status: PaymentStatusRequiresAction}, nil
+	}
+
+	status := authorize(details)
+	p.mu.Lock()
+	p.byIDs[id] = status
+	p.byReferenceIDs[details.ReferenceID] = status
+	p.mu.Unlock()
+
+	return Payment{ID: id, Status: status}, nil
+}
+
+// CompleteChallenge finishes the 3-D Secure challenge of a payment. The payment is authorized when
+// the cardholder authenticated and fails with PaymentStatusAuthenticationFailed otherwise.
+func (p PaymentProvider) CompleteChallenge(ctx context.Context, id uuid.UUID, authenticated bool) (_ Payment, err error) {
+	_, span := tracing.Start(ctx, "provider.CompleteChallenge", attribute.String("payment.provider_id", id.String()))
+	defer tracing.End(span, &err)
+
+	p.mu.Lock()
+	details, ok := p.challenges[id]
+	delete(p.challenges, id)
+	p.mu.Unlock()
+	if !ok {
+		return Payment{}, ErrChallengeNotFound
+	}
+
+	status := PaymentStatusAuthenticationFailed
+	if authenticated {
+		status = authorize(details)
+	}
+	p.mu.Lock()
+	p.byIDs[id] = status
+	p.byReferenceIDs[details.ReferenceID] = status
+	p.mu.Unlock()
+
+	return Payment{ID: id, Status: status}, nil
+}
+
+// authorize decides the outcome of a payment from the test card number
+func authorize(details PaymentDetails) PaymentStatus {
 	status := PaymentStatusSuccess
 	if strings.HasSuffix(details.CardNumber, "1212") {
 		status = PaymentStatusInsufficientFunds
@@ -61,14 +142,12 @@ func (p PaymentProvider) Pay(ctx context.Context, details PaymentDetails) (Payme
 	} else if strings.HasSuffix(details.CardNumber, "4545") {
 		time.Sleep(10000 * time.Hour)
 	}
-
-	p.byIDs[id] = status
-	p.byReferenceIDs[id] = status
-
-	return Payment{ID: id, Status: status}, nil
+	return status
 }
 
 func (p PaymentProvider) ByID(id uuid.UUID) (PaymentStatus, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	if status, ok := p.byIDs[id]; ok {
 		return status, true
 	}
@@ -76,6 +155,8 @@ func (p PaymentProvider) ByID(id uuid.UUID) (PaymentStatus, bool) {
 }
 
 func (p PaymentProvider) ByReferenceID(id uuid.UUID) (PaymentStatus, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	if status, ok := p.byReferenceIDs[id]; ok {
 		return status, true
 	}
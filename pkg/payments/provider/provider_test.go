@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"sync"
 	"testing"
 
 	"github.com/google/uuid"
@@ -10,6 +11,7 @@ import (
 
 func TestPayRequestSuccess(t *testing.T) {
 	provider := PaymentProvider{
+		mu:             &sync.RWMutex{},
 		byIDs:          make(map[uuid.UUID]PaymentStatus),
 		byReferenceIDs: make(map[uuid.UUID]PaymentStatus),
 	}
@@ -30,6 +32,7 @@ func TestPayRequestSuccess(t *testing.T) {
 
 func TestPayRequestInsufficientFunds(t *testing.T) {
 	provider := PaymentProvider{
+		mu:             &sync.RWMutex{},
 		byIDs:          make(map[uuid.UUID]PaymentStatus),
 		byReferenceIDs: make(map[uuid.UUID]PaymentStatus),
 	}
@@ -50,6 +53,7 @@ func TestPayRequestInsufficientFunds(t *testing.T) {
 
 func TestPayRequestDoNotHonor(t *testing.T) {
 	provider := PaymentProvider{
+		mu:             &sync.RWMutex{},
 		byIDs:          make(map[uuid.UUID]PaymentStatus),
 		byReferenceIDs: make(map[uuid.UUID]PaymentStatus),
 	}
@@ -70,6 +74,7 @@ func TestPayRequestDoNotHonor(t *testing.T) {
 
 func TestPayRequestDeclined(t *testing.T) {
 	provider := PaymentProvider{
+		mu:             &sync.RWMutex{},
 		byIDs:          make(map[uuid.UUID]PaymentStatus),
 		byReferenceIDs: make(map[uuid.UUID]PaymentStatus),
 	}
@@ -88,6 +93,22 @@ func TestPayRequestDeclined(t *testing.T) {
 	assert.Equal(t, PaymentStatusDeclined, payment.Status)
 }
 
+func TestPayRequestProviderUnavailable(t *testing.T) {
+	provider := New()
+
+	_, err := provider.Pay(context.Background(), PaymentDetails{
+		CardNumber:   "4242424242426767",
+		CardHolder:   "John Smith",
+		Expiry:       "01/25",
+		CVC:          "123",
+		Amount:       100.00,
+		CurrencyCode: "USD",
+	})
+
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Empty(t, provider.byIDs)
+}
+
 func TestByID(t *testing.T) {
 	uuid1, _ := uuid.NewV7()
 	uuid2, _ := uuid.NewV7()
@@ -95,6 +116,7 @@ func TestByID(t *testing.T) {
 	uuid4, _ := uuid.NewV7()
 
 	provider := PaymentProvider{
+		mu: &sync.RWMutex{},
 		byIDs: map[uuid.UUID]PaymentStatus{
 			uuid1: PaymentStatusSuccess,
 			uuid2: PaymentStatusInsufficientFunds,
@@ -127,6 +149,7 @@ func TestByReferenceID(t *testing.T) {
 	uuid4, _ := uuid.NewV7()
 
 	provider := PaymentProvider{
+		mu: &sync.RWMutex{},
 		byReferenceIDs: map[uuid.UUID]PaymentStatus{
 			uuid1: PaymentStatusSuccess,
 			uuid2: PaymentStatusInsufficientFunds,
@@ -151,3 +174,35 @@ func TestByReferenceID(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, PaymentStatusDeclined, status)
 }
+
+func TestPayRequestRequiresChallenge(t *testing.T) {
+	provider := New()
+
+	payment, err := provider.Pay(context.Background(), PaymentDetails{
+		ReferenceID:  uuid.New(),
+		CardNumber:   "4242424242425656",
+		Amount:       100.00,
+		CurrencyCode: "USD",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, PaymentStatusRequiresAction, payment.Status)
+
+	completed, err := provider.CompleteChallenge(context.Background(), payment.ID, true)
+	assert.Nil(t, err)
+	assert.Equal(t, PaymentStatusSuccess, completed.Status)
+	status, _ := provider.ByID(payment.ID)
+	assert.Equal(t, PaymentStatusSuccess, status)
+
+	// A challenge can only be completed once
+	_, err = provider.CompleteChallenge(context.Background(), payment.ID, true)
+	assert.ErrorIs(t, err, ErrChallengeNotFound)
+}
+
+func TestFailedChallenge(t *testing.T) {
+	provider := New()
+
+	payment, _ := provider.Pay(context.Background(), PaymentDetails{ReferenceID: uuid.New(), CardNumber: "4242424242425656"})
+	completed, err := provider.CompleteChallenge(context.Background(), payment.ID, false)
+	assert.Nil(t, err)
+	assert.Equal(t, PaymentStatusAuthenticationFailed, completed.Status)
+}
@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests requests per Period. It is enforced as a token bucket holding up to
+// Requests tokens, refilled evenly over the period. The zero Limit is unlimited.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Unlimited reports whether the limit does not restrict anything.
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+// String formats the limit the way ParseLimit reads it.
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+var periodUnits = map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}
+
+// ParseLimit reads limits such as "100/m", "10/s" or "1000/1h".
+func ParseLimit(raw string) (Limit, error) {
+	requests, period, found := strings.Cut(strings.TrimSpace(raw), "/")
+	if !found {
+		return Limit{}, fmt.Errorf("rate limit %q: expected <requests>/<period>", raw)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q: invalid request count", raw)
+	}
+	d, ok := periodUnits[period]
+	if !ok {
+		if d, err = time.ParseDuration(period); err != nil || d <= 0 {
+			return Limit{}, fmt.Errorf("rate limit %q: invalid period", raw)
+		}
+	}
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed    bool
+	Limit      Limit
+	Remaining  int
+	ResetAfter time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next request is allowed, zero when allowed
+}
+
+// Store keeps the token buckets. Implementations must be safe for concurrent use; the in-memory
+// store only limits a single instance, a shared store limits across instances.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Config holds the limits applied to the API. Endpoint limits are keyed by "<METHOD> <route>",
+// e.g. "POST /payment-process/invoices/:id/payments", and apply per caller on top of the per key limit.
+type Config struct {
+	PerKey    Limit
+	PerIP     Limit
+	Endpoints map[string]Limit
+}
+
+// Limiter applies the configured limits using a store.
+type Limiter struct {
+	store  Store
+	config Config
+}
+
+func NewLimiter(store Store, config Config) *Limiter {
+	return &Limiter{store: store, config: config}
+}
+
+// AllowIP takes a token from the bucket of the client IP.
+func (l *Limiter) AllowIP(ctx context.Context, ip string) (Result, error) {
+	if l.config.PerIP.Unlimited() {
+		return Result{Allowed: true}, nil
+	}
+	return l.store.Take(ctx, "ip:"+ip, l.config.PerIP)
+}
+
+// AllowCaller takes a token from the bucket of the caller and, if the endpoint has its own
+// limit, from the caller's bucket for the endpoint. The most restrictive result is returned.
+func (l *Limiter) AllowCaller(ctx context.Context, caller, endpoint string) (Result, error) {
+	result := Result{Allowed: true}
+	if !l.config.PerKey.Unlimited() {
+		var err error
+		if result, err = l.store.Take(ctx, "key:"+caller, l.config.PerKey); err != nil || !result.Allowed {
+			return result, err
+		}
+	}
+
+	endpointLimit, ok := l.config.Endpoints[endpoint]
+	if !ok || endpointLimit.Unlimited() {
+		return result, nil
+	}
+	endpointResult, err := l.store.Take(ctx, "endpoint:"+endpoint+":"+caller, endpointLimit)
+	if err != nil {
+		return result, err
+	}
+	if result.Limit.Unlimited() || !endpointResult.Allowed || endpointResult.Remaining < result.Remaining {
+		return endpointResult, nil
+	}
+	return result, nil
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestStore(now *time.Time) *MemoryStore {
+	store := NewMemoryStore()
+	store.now = func() time.Time { return *now }
+	return store
+}
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("100/m")
+	assert.NoError(t, err)
+	assert.Equal(t, Limit{Requests: 100, Period: time.Minute}, limit)
+
+	limit, err = ParseLimit("5/10s")
+	assert.NoError(t, err)
+	assert.Equal(t, Limit{Requests: 5, Period: 10 * time.Second}, limit)
+
+	for _, raw := range []string{"", "100", "0/m", "x/m", "10/week"} {
+		_, err := ParseLimit(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestMemoryStoreTokenBucket(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := newTestStore(&now)
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+	ctx := context.Background()
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		result, err := store.Take(ctx, "k", limit)
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, remaining, result.Remaining)
+	}
+
+	result, _ := store.Take(ctx, "k", limit)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.ResetAfter)
+
+	// Other keys have their own bucket
+	result, _ = store.Take(ctx, "other", limit)
+	assert.True(t, result.Allowed)
+
+	// One token is refilled per second
+	now = now.Add(time.Second)
+	result, _ = store.Take(ctx, "k", limit)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	// Buckets never hold more than the limit
+	now = now.Add(time.Hour)
+	result, _ = store.Take(ctx, "k", limit)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Remaining)
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := newTestStore(&now)
+	limit := Limit{Requests: 1, Period: time.Second}
+
+	_, _ = store.Take(context.Background(), "a", limit)
+	now = now.Add(2 * sweepInterval)
+	_, _ = store.Take(context.Background(), "b", limit)
+	assert.NotContains(t, store.buckets, "a")
+	assert.Contains(t, store.buckets, "b")
+}
+
+func TestLimiterEndpointLimit(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(newTestStore(&now), Config{
+		PerKey:    Limit{Requests: 100, Period: time.Minute},
+		PerIP:     Limit{Requests: 1, Period: time.Minute},
+		Endpoints: map[string]Limit{"POST /invoices/:id/payments": {Requests: 2, Period: time.Minute}},
+	})
+	ctx := context.Background()
+
+	result, _ := limiter.AllowCaller(ctx, "api_key:1", "POST /invoices/:id/payments")
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Limit.Requests)
+	assert.Equal(t, 1, result.Remaining)
+
+	_, _ = limiter.AllowCaller(ctx, "api_key:1", "POST /invoices/:id/payments")
+	result, _ = limiter.AllowCaller(ctx, "api_key:1", "POST /invoices/:id/payments")
+	assert.False(t, result.Allowed)
+
+	// The endpoint limit is per caller and does not affect other endpoints
+	result, _ = limiter.AllowCaller(ctx, "api_key:2", "POST /invoices/:id/payments")
+	assert.True(t, result.Allowed)
+	result, _ = limiter.AllowCaller(ctx, "api_key:1", "GET /invoices/:id")
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 100, result.Limit.Requests)
+
+	result, _ = limiter.AllowIP(ctx, "203.0.113.7")
+	assert.True(t, result.Allowed)
+	result, _ = limiter.AllowIP(ctx, "203.0.113.7")
+	assert.False(t, result.Allowed)
+}
@@ -0,0 +1,186 @@
+package reconciliation
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"go/payment-processor/pkg/entities"
+	"io"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Outcome constants describe how a payment compared against the provider
+const (
+	OutcomeMatched           = "MATCHED"
+	OutcomeMissingAtProvider = "MISSING_AT_PROVIDER"
+	OutcomeMissingLocally    = "MISSING_LOCALLY"
+	OutcomeAmountMismatch    = "AMOUNT_MISMATCH"
+	OutcomeStatusMismatch    = "STATUS_MISMATCH"
+)
+
+// Record is what the provider reports for a single payment. Amount is only
+// set when the source carries it (settlement reports do, ByID lookups do not).
+type Record struct {
+	ProviderPaymentID string              `json:"provider_payment_id"`
+	ReferenceID       string              `json:"reference_id,omitempty"`
+	Amount            decimal.NullDecimal `json:"amount"`
+	Status            string              `json:"status"`
+}
+
+// Entry is one line of a reconciliation report.
+type Entry struct {
+	Outcome           string              `json:"outcome"`
+	PaymentID         uint                `json:"payment_id,omitempty"`
+	ProviderPaymentID string              `json:"provider_payment_id"`
+	LocalAmount       decimal.NullDecimal `json:"local_amount"`
+	ProviderAmount    decimal.NullDecimal `json:"provider_amount"`
+	LocalStatus       string              `json:"local_status,omitempty"`
+	ProviderStatus    string              `json:"provider_status,omitempty"`
+	Corrected         bool                `json:"corrected"`
+}
+
+// Report summarises a reconciliation run.
+type Report struct {
+	Matched          int     `json:"matched"`
+	Missing          int     `json:"missing"`
+	AmountMismatched int     `json:"amount_mismatched"`
+	StatusMismatched int     `json:"status_mismatched"`
+	Corrected        int     `json:"corrected"`
+	Entries          []Entry `json:"entries"`
+}
+
+// Match compares stored payments with provider records, keyed by provider payment ID. Payments that
+// were never given a provider payment ID, such as those interrupted by a shutdown, are matched by
+// reference ID. An amount mismatch takes precedence over a status mismatch on the same payment.
+func Match(payments []entities.Payment, records []Record) *Report {
+	byProviderID := make(map[string]int, len(records))
+	byReferenceID := make(map[string]int, len(records))
+	for i, record := range records {
+		if record.ProviderPaymentID != "" {
+			byProviderID[record.ProviderPaymentID] = i
+		}
+		if record.ReferenceID != "" {
+			byReferenceID[record.ReferenceID] = i
+		}
+	}
+
+	report := &Report{}
+	seen := make(map[int]bool, len(payments))
+	for _, payment := range payments {
+		entry := Entry{
+			PaymentID:         payment.ID,
+			ProviderPaymentID: payment.ProviderPaymentID,
+			LocalAmount:       decimal.NewNullDecimal(payment.Amount),
+			LocalStatus:       payment.PaymentStatus,
+		}
+		i, ok := byProviderID[payment.ProviderPaymentID]
+		if payment.ProviderPaymentID == "" {
+			i, ok = byReferenceID[payment.ReferenceID]
+			ok = ok && payment.ReferenceID != ""
+		}
+		if !ok {
+			entry.Outcome = OutcomeMissingAtProvider
+			report.add(entry)
+			continue
+		}
+		seen[i] = true
+		record := records[i]
+		entry.ProviderAmount = record.Amount
+		entry.ProviderStatus = record.Status
+
+		switch {
+		case record.Amount.Valid && !record.Amount.Decimal.Equal(payment.Amount):
+			entry.Outcome = OutcomeAmountMismatch
+		case !strings.EqualFold(record.Status, payment.PaymentStatus):
+			entry.Outcome = OutcomeStatusMismatch
+		default:
+			entry.Outcome = OutcomeMatched
+		}
+		report.add(entry)
+	}
+
+	for i, record := range records {
+		if seen[i] {
+			continue
+		}
+		report.add(Entry{
+			Outcome:           OutcomeMissingLocally,
+			ProviderPaymentID: record.ProviderPaymentID,
+			ProviderAmount:    record.Amount,
+			ProviderStatus:    record.Status,
+		})
+	}
+	return report
+}
+
+func (r *Report) add(entry Entry) {
+	switch entry.Outcome {
+	case OutcomeMatched:
+		r.Matched++
+	case OutcomeMissingAtProvider, OutcomeMissingLocally:
+		r.Missing++
+	case OutcomeAmountMismatch:
+		r.AmountMismatched++
+	case OutcomeStatusMismatch:
+		r.StatusMismatched++
+	}
+	r.Entries = append(r.Entries, entry)
+}
+
+// ParseSettlementCSV reads a provider settlement report. The first row must be a header
+// containing at least provider_payment_id and status; reference_id and amount are optional.
+func ParseSettlementCSV(reader io.Reader) ([]Record, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("settlement report is empty")
+		}
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"provider_payment_id", "status"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("settlement report is missing the %s column", required)
+		}
+	}
+
+	var records []Record
+	for {
+		row, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := csvReader.FieldPos(0)
+
+		record := Record{
+			ProviderPaymentID: strings.TrimSpace(row[columns["provider_payment_id"]]),
+			Status:            strings.ToUpper(strings.TrimSpace(row[columns["status"]])),
+		}
+		if i, ok := columns["reference_id"]; ok {
+			record.ReferenceID = strings.TrimSpace(row[i])
+		}
+		if i, ok := columns["amount"]; ok && strings.TrimSpace(row[i]) != "" {
+			amount, err := decimal.NewFromString(strings.TrimSpace(row[i]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid amount %q", line, row[i])
+			}
+			record.Amount = decimal.NewNullDecimal(amount)
+		}
+		if record.ProviderPaymentID == "" {
+			return nil, fmt.Errorf("line %d: provider_payment_id is empty", line)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -0,0 +1,92 @@
+package reconciliation
+
+import (
+	"strings"
+	"testing"
+
+	"go/payment-processor/pkg/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func payment(id uint, providerID string, amount string, status string) entities.Payment {
+	p := entities.Payment{
+		Amount:            decimal.RequireFromString(amount),
+		PaymentStatus:     status,
+		ProviderPaymentID: providerID,
+	}
+	p.ID = id
+	return p
+}
+
+func TestParseSettlementCSV(t *testing.T) {
+	records, err := ParseSettlementCSV(strings.NewReader(
+		"provider_payment_id,reference_id,amount,status\n" +
+			"p-1,r-1,100.00,success\n" +
+			"p-2,r-2,,DECLINED\n"))
+
+	assert.Nil(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "SUCCESS", records[0].Status)
+	assert.True(t, records[0].Amount.Valid)
+	assert.True(t, decimal.NewFromInt(100).Equal(records[0].Amount.Decimal))
+	assert.False(t, records[1].Amount.Valid)
+}
+
+func TestParseSettlementCSVMissingColumn(t *testing.T) {
+	_, err := ParseSettlementCSV(strings.NewReader("provider_payment_id,amount\np-1,10\n"))
+
+	assert.NotNil(t, err)
+}
+
+func TestParseSettlementCSVInvalidAmount(t *testing.T) {
+	_, err := ParseSettlementCSV(strings.NewReader("provider_payment_id,amount,status\np-1,ten,SUCCESS\n"))
+
+	assert.NotNil(t, err)
+}
+
+func TestMatch(t *testing.T) {
+	payments := []entities.Payment{
+		payment(1, "p-1", "100", "SUCCESS"),
+		payment(2, "p-2", "50", "SUCCESS"),
+		payment(3, "p-3", "75", "SUCCESS"),
+		payment(4, "p-4", "20", "SUCCESS"),
+	}
+	records := []Record{
+		{ProviderPaymentID: "p-1", Amount: decimal.NewNullDecimal(decimal.RequireFromString("100.00")), Status: "SUCCESS"},
+		{ProviderPaymentID: "p-2", Amount: decimal.NewNullDecimal(decimal.NewFromInt(49)), Status: "SUCCESS"},
+		{ProviderPaymentID: "p-3", Status: "DECLINED"},
+		{ProviderPaymentID: "p-9", Status: "SUCCESS"},
+	}
+
+	report := Match(payments, records)
+
+	assert.Equal(t, 1, report.Matched)
+	assert.Equal(t, 1, report.AmountMismatched)
+	assert.Equal(t, 1, report.StatusMismatched)
+	assert.Equal(t, 2, report.Missing)
+	assert.Len(t, report.Entries, 5)
+	assert.Equal(t, OutcomeMatched, report.Entries[0].Outcome)
+	assert.Equal(t, OutcomeAmountMismatch, report.Entries[1].Outcome)
+	assert.Equal(t, OutcomeStatusMismatch, report.Entries[2].Outcome)
+	assert.Equal(t, OutcomeMissingAtProvider, report.Entries[3].Outcome)
+	assert.Equal(t, OutcomeMissingLocally, report.Entries[4].Outcome)
+}
+
+func TestMatchByReferenceID(t *testing.T) {
+	interrupted := payment(1, "", "30", "PENDING_RECONCILIATION")
+	interrupted.ReferenceID = "r-1"
+	unknown := payment(2, "", "40", "PENDING_RECONCILIATION")
+	unknown.ReferenceID = "r-2"
+	records := []Record{{ReferenceID: "r-1", Status: "SUCCESS"}}
+
+	report := Match([]entities.Payment{interrupted, unknown}, records)
+
+	assert.Equal(t, 1, report.StatusMismatched)
+	assert.Equal(t, 1, report.Missing)
+	assert.Len(t, report.Entries, 2)
+	assert.Equal(t, OutcomeStatusMismatch, report.Entries[0].Outcome)
+	assert.Equal(t, "SUCCESS", report.Entries[0].ProviderStatus)
+	assert.Equal(t, OutcomeMissingAtProvider, report.Entries[1].Outcome)
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"go/payment-processor/pkg/entities"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func (r *repository) CreateAPIKey(ctx context.Context, apiKey *entities.ApiKey) (*entities.ApiKey, error) {
+	if err := r.db.WithContext(ctx).Create(apiKey).Error; err != nil {
+		return nil, err
+	}
+	return apiKey, nil
+}
+
+func (r *repository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*entities.ApiKey, error) {
+	var apiKey entities.ApiKey
+	if err := r.db.WithContext(ctx).Where("prefix = ?", prefix).First(&apiKey).Error; err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+func (r *repository) GetAPIKeyByID(ctx context.Context, merchantID, apiKeyID uint) (*entities.ApiKey, error) {
+	var apiKey entities.ApiKey
+	if err := r.db.WithContext(ctx).Where("id = ? AND merchant_id = ?", apiKeyID, merchantID).First(&apiKey).Error; err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+func (r *repository) GetAPIKeysByMerchant(ctx context.Context, merchantID uint) ([]entities.ApiKey, error) {
+	var apiKeys []entities.ApiKey
+	if err := r.db.WithContext(ctx).Where("merchant_id = ?", merchantID).Order("id").Find(&apiKeys).Error; err != nil {
+		return nil, err
+	}
+	return apiKeys, nil
+}
+
+// RevokeAPIKey revokes one of the merchant's keys; revoked keys are kept for auditing
+func (r *repository) RevokeAPIKey(ctx context.Context, merchantID, apiKeyID uint) error {
+	result := r.db.WithContext(ctx).Model(&entities.ApiKey{}).
+		Where("id = ? AND merchant_id = ? AND revoked_at IS NULL", apiKeyID, merchantID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "is_active": false})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *repository) TouchAPIKey(ctx context.Context, apiKeyID uint) error {
+	return r.db.WithContext(ctx).Model(&entities.ApiKey{}).Where("id = ?", apiKeyID).
+		UpdateColumn("last_used_at", time.Now()).Error
+}
@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/payment-processor/pkg/audit"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/requestid"
+	"go/payment-processor/pkg/utils"
+	"reflect"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// auditedTables are the tables whose writes are recorded in the audit log
+var auditedTables = map[string]bool{"invoice": true, "payment": true, "merchant": true, "customer": true}
+
+const (
+	auditLogSnapshotCallback = "audit_log:snapshot"
+	auditLogRecordCallback   = "audit_log:record"
+	auditLogBeforeKey        = "audit_log:before"
+
+	// The callbacks gorm opens and closes the transaction of a write with
+	beginCallback  = "gorm:begin_transaction"
+	commitCallback = "gorm:commit_or_rollback_transaction"
+
+	// auditLogLockID is the transaction level advisory lock serializing audit log appends,
+	// so concurrent writes cannot fork the hash chain
+	auditLogLockID = 7426001
+)
+
+// registerAuditLogCallbacks records every create, update and delete on the audited tables in the
+// audit log, inside the transaction of the write. It is safe to call for every repository.
+func registerAuditLogCallbacks(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if callbacks.Create().Get(auditLogRecordCallback) != nil {
+		return nil
+	}
+	// Snapshots and records are pinned between the begin and commit of the write's transaction, or gorm
+	// may run them after the commit, where the advisory lock would be released as soon as it is taken
+	if err := callbacks.Create().After("gorm:create").Before(commitCallback).
+		Register(auditLogRecordCallback, recordAuditLog(utils.AuditActionCreate)); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After(beginCallback).Before("gorm:update").
+		Register(auditLogSnapshotCallback, snapshotAffectedRows); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Before(commitCallback).
+		Register(auditLogRecordCallback, recordAuditLog(utils.AuditActionUpdate)); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After(beginCallback).Before("gorm:delete").
+		Register(auditLogSnapshotCallback, snapshotAffectedRows); err != nil {
+		return err
+	}
+	return callbacks.Delete().After("gorm:delete").Before(commitCallback).
+		Register(auditLogRecordCallback, recordAuditLog(utils.AuditActionDelete))
+}
+
+func isAudited(db *gorm.DB) bool {
+	return db.Error == nil && !db.Statement.DryRun && db.Statement.Schema != nil && auditedTables[db.Statement.Table]
+}
+
+// snapshotAffectedRows keeps the rows an update or delete is about to change, to diff against afterwards
+func snapshotAffectedRows(db *gorm.DB) {
+	if !isAudited(db) {
+		return
+	}
+
+	stmt := db.Statement
+	query := db.Session(&gorm.Session{NewDB: true, SkipHooks: true})
+	scoped := false
+	if where, ok := stmt.Clauses["WHERE"].Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+		query = query.Clauses(where)
+		scoped = true
+	}
+	if stmt.ReflectValue.Kind() == reflect.Struct && stmt.Schema.PrioritizedPrimaryField != nil {
+		pk := stmt.Schema.PrioritizedPrimaryField
+		if value, zero := pk.ValueOf(stmt.Context, stmt.ReflectValue); !zero {
+			query = query.Where(clause.Eq{Column: clause.Column{Name: pk.DBName}, Value: value})
+			scoped = true
+		}
+	}
+	if !scoped {
+		// gorm refuses updates and deletes without conditions
+		return
+	}
+
+	snapshots, err := loadSnapshots(query, stmt)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	db.InstanceSet(auditLogBeforeKey, snapshots)
+}
+
+func recordAuditLog(action string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if !isAudited(db) {
+			return
+		}
+
+		stmt := db.Statement
+		var before, after map[uint]audit.Snapshot
+		if value, ok := db.InstanceGet(auditLogBeforeKey); ok {
+			before = value.(map[uint]audit.Snapshot)
+		}
+
+		var ids []uint
+		switch action {
+		case utils.AuditActionCreate:
+			ids = primaryKeys(stmt)
+		default:
+			for id := range before {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			return
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		if action != utils.AuditActionDelete {
+			var err error
+			query := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).
+				Where(clause.IN{Column: clause.Column{Name: stmt.Schema.PrioritizedPrimaryField.DBName}, Values: toValues(ids)})
+			if after, err = loadSnapshots(query, stmt); err != nil {
+				db.AddError(err)
+				return
+			}
+		}
+
+		var actor string
+		if principal := auth.PrincipalFromContext(stmt.Context); principal != nil {
+			actor = principal.Actor()
+		}
+		now := time.Now().UTC().Truncate(time.Microsecond)
+
+		var logs []*entities.AuditLog
+		for _, id := range ids {
+			changes := audit.Diff(before[id], after[id])
+			if len(changes) == 0 {
+				continue
+			}
+			encoded, err := json.Marshal(changes)
+			if err != nil {
+				db.AddError(err)
+				return
+			}
+			logs = append(logs, &entities.AuditLog{
+				EntityType: stmt.Table,
+				EntityID:   id,
+				Action:     action,
+				Actor:      actor,
+				RequestID:  requestid.FromContext(stmt.Context),
+				Changes:    string(encoded),
+				CreatedAt:  &now,
+			})
+		}
+		if err := appendAuditLogs(db, logs); err != nil {
+			db.AddError(err)
+		}
+	}
+}
+
+// appendAuditLogs chains the logs to the latest audit log entry and stores them
+func appendAuditLogs(db *gorm.DB, logs []*entities.AuditLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	tx := db.Session(&gorm.Session{NewDB: true, SkipHooks: true})
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditLogLockID).Error; err != nil {
+		return err
+	}
+	var last entities.AuditLog
+	if err := tx.Order("id DESC").Limit(1).Find(&last).Error; err != nil {
+		return err
+	}
+
+	prevHash := last.Hash
+	for _, entry := range logs {
+		entry.PrevHash = prevHash
+		entry.Hash = audit.Hash(prevHash, entry)
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+		prevHash = entry.Hash
+	}
+	return nil
+}
+
+// loadSnapshots reads the rows matched by query as audit snapshots keyed by primary key
+func loadSnapshots(query *gorm.DB, stmt *gorm.Statement) (map[uint]audit.Snapshot, error) {
+	rows := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+	if err := query.Find(rows.Interface()).Error; err != nil {
+		return nil, err
+	}
+
+	snapshots := make(map[uint]audit.Snapshot, rows.Elem().Len())
+	for i := 0; i < rows.Elem().Len(); i++ {
+		row := rows.Elem().Index(i)
+		snapshot := make(audit.Snapshot, len(stmt.Schema.Fields))
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			value, _ := field.ValueOf(stmt.Context, row)
+			encoded, err := audit.EncodeValue(field.DBName, value)
+			if err != nil {
+				return nil, err
+			}
+			snapshot[field.DBName] = encoded
+		}
+		value, _ := stmt.Schema.PrioritizedPrimaryField.ValueOf(stmt.Context, row)
+		id, ok := value.(uint)
+		if !ok {
+			return nil, fmt.Errorf("audit log: %s has a %T primary key, only uint keys are supported", stmt.Table, value)
+		}
+		snapshots[id] = snapshot
+	}
+	return snapshots, nil
+}
+
+// primaryKeys returns the primary keys of the rows a create statement inserted
+func primaryKeys(stmt *gorm.Statement) []uint {
+	pk := stmt.Schema.PrioritizedPrimaryField
+	if pk == nil {
+		return nil
+	}
+
+	var ids []uint
+	collect := func(row reflect.Value) {
+		if value, zero := pk.ValueOf(stmt.Context, row); !zero {
+			if id, ok := value.(uint); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Struct:
+		collect(stmt.ReflectValue)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < stmt.ReflectValue.Len(); i++ {
+			collect(reflect.Indirect(stmt.ReflectValue.Index(i)))
+		}
+	}
+	return ids
+}
+
+func toValues(ids []uint) []interface{} {
+	values := make([]interface{}, len(ids))
+	for i, id := range ids {
+		values[i] = id
+	}
+	return values
+}
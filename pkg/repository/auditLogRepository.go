@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"go/payment-processor/pkg/entities"
+	"time"
+)
+
+// AuditLogQuery filters the audit log. Zero values mean "no filter"; results are newest first.
+type AuditLogQuery struct {
+	EntityType  string
+	EntityID    uint
+	Actor       string
+	RequestID   string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	BeforeID    uint
+	Limit       int
+}
+
+func (r *repository) ListAuditLogs(ctx context.Context, query AuditLogQuery) ([]entities.AuditLog, error) {
+	db := r.db.WithContext(ctx).Model(&entities.AuditLog{})
+	if query.EntityType != "" {
+		db = db.Where("entity_type = ?", query.EntityType)
+	}
+	if query.EntityID != 0 {
+		db = db.Where("entity_id = ?", query.EntityID)
+	}
+	if query.Actor != "" {
+		db = db.Where("actor = ?", query.Actor)
+	}
+	if query.RequestID != "" {
+		db = db.Where("request_id = ?", query.RequestID)
+	}
+	if query.CreatedFrom != nil {
+		db = db.Where("created_at >= ?", *query.CreatedFrom)
+	}
+	if query.CreatedTo != nil {
+		db = db.Where("created_at < ?", *query.CreatedTo)
+	}
+	if query.BeforeID != 0 {
+		db = db.Where("id < ?", query.BeforeID)
+	}
+
+	var logs []entities.AuditLog
+	if err := db.Order("id DESC").Limit(query.Limit).Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// GetAuditLogsAfter returns up to limit audit log entries following afterID, in chain order
+func (r *repository) GetAuditLogsAfter(ctx context.Context, afterID uint, limit int) ([]entities.AuditLog, error) {
+	var logs []entities.AuditLog
+	if err := r.db.WithContext(ctx).Where("id > ?", afterID).Order("id").Limit(limit).Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"go/payment-processor/pkg/audit"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/requestid"
+	"go/payment-processor/pkg/utils"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLogRecordsWritesInAHashChain(t *testing.T) {
+	repo, _ := newFakeRepository(t)
+	ctx := auth.ContextWithPrincipal(requestid.NewContext(context.Background(), "req-1"), auth.SystemPrincipal("test"))
+
+	first, err := repo.CreateInvoice(ctx, &entities.Invoice{MerchantID: 1, CustomerID: 2, Amount: decimal.NewFromInt(25),
+		Currency: "USD", InvoiceStatus: utils.InvoiceStatusPending})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = repo.CreateInvoice(ctx, &entities.Invoice{MerchantID: 1, CustomerID: 3, Amount: decimal.NewFromInt(10),
+		Currency: "EUR", InvoiceStatus: utils.InvoiceStatusPending})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.UpdateInvoiceStatus(ctx, first.ID, utils.InvoiceStatusPaid))
+
+	logs, err := repo.GetAuditLogsAfter(ctx, 0, 10)
+	assert.NoError(t, err)
+	if !assert.Len(t, logs, 3) {
+		return
+	}
+	_, broken := audit.Verify("", logs)
+	assert.Nil(t, broken)
+	assert.Empty(t, logs[0].PrevHash)
+	for _, entry := range logs {
+		assert.Equal(t, "invoice", entry.EntityType)
+		assert.Equal(t, auth.SystemPrincipal("test").Actor(), entry.Actor)
+		assert.Equal(t, "req-1", entry.RequestID)
+	}
+	assert.Equal(t, []string{utils.AuditActionCreate, utils.AuditActionCreate, utils.AuditActionUpdate},
+		[]string{logs[0].Action, logs[1].Action, logs[2].Action})
+
+	// The update records the status change between the before and after snapshots, and nothing else
+	var changes map[string]audit.Change
+	assert.NoError(t, json.Unmarshal([]byte(logs[2].Changes), &changes))
+	assert.Equal(t, first.ID, logs[2].EntityID)
+	assert.Equal(t, `"PENDING"`, string(changes["invoice_status"].Before))
+	assert.Equal(t, `"PAID"`, string(changes["invoice_status"].After))
+	assert.NotContains(t, changes, "amount")
+
+	// Tampering with an entry breaks the chain from there on
+	logs[1].Changes = `{}`
+	_, broken = audit.Verify("", logs)
+	if assert.NotNil(t, broken) {
+		assert.Equal(t, logs[1].ID, broken.ID)
+	}
+}
+
+func TestAuditLogAppendsUnderTheAdvisoryLock(t *testing.T) {
+	repo, db := newFakeRepository(t)
+	ctx := auth.ContextWithPrincipal(context.Background(), auth.SystemPrincipal("test"))
+
+	_, err := repo.CreateInvoice(ctx, &entities.Invoice{MerchantID: 1, Amount: decimal.NewFromInt(25), Currency: "USD",
+		InvoiceStatus: utils.InvoiceStatusPending})
+	assert.NoError(t, err)
+
+	// The chain head is read and extended inside the transaction of the write, after taking the lock
+	statements := db.Statements("BEGIN", "COMMIT", "INSERT", "SELECT pg_advisory_xact_lock", `SELECT * FROM "audit_log"`)
+	if assert.Len(t, statements, 6) {
+		assert.Equal(t, "BEGIN", statements[0])
+		assert.True(t, strings.HasPrefix(statements[1], `INSERT INTO "invoice"`), statements[1])
+		assert.True(t, strings.HasPrefix(statements[2], "SELECT pg_advisory_xact_lock"), statements[2])
+		assert.True(t, strings.HasPrefix(statements[3], `SELECT * FROM "audit_log"`), statements[3])
+		assert.True(t, strings.HasPrefix(statements[4], `INSERT INTO "audit_log"`), statements[4])
+		assert.Equal(t, "COMMIT", statements[5])
+	}
+}
+
+func TestAuditLogSkipsWritesWithoutChanges(t *testing.T) {
+	repo, db := newFakeRepository(t)
+	ctx := auth.ContextWithPrincipal(context.Background(), auth.SystemPrincipal("test"))
+	invoice, err := repo.CreateInvoice(ctx, &entities.Invoice{MerchantID: 1, Amount: decimal.NewFromInt(25), Currency: "USD",
+		InvoiceStatus: utils.InvoiceStatusPending})
+	assert.NoError(t, err)
+
+	// Rows no update touches and unaudited tables are not logged
+	assert.NoError(t, repo.UpdateInvoiceStatus(ctx, invoice.ID+1, utils.InvoiceStatusPaid))
+	assert.NoError(t, appendAuditLogs(repo.db, nil))
+
+	assert.Len(t, db.Statements(`INSERT INTO "audit_log"`), 1)
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"go/payment-processor/pkg/auth"
+
+	"gorm.io/gorm"
+)
+
+const auditTrailCallback = "audit_trail:stamp_actor"
+
+// registerAuditTrailCallbacks stamps the principal carried by the context of a repository call onto
+// every created or updated row that embeds AuditTrail, in AuditTrail.CreatedBy and
+// AuditTrail.LastUpdatedBy. It is safe to call for every repository.
+func registerAuditTrailCallbacks(db *gorm.DB) error {
+	if db.Callback().Create().Get(auditTrailCallback) != nil {
+		return nil
+	}
+	if err := db.Callback().Create().Before("gorm:create").Register(auditTrailCallback, stampActor("CreatedBy", "LastUpdatedBy")); err != nil {
+		return err
+	}
+	return db.Callback().Update().Before("gorm:update").Register(auditTrailCallback, stampActor("LastUpdatedBy"))
+}
+
+func stampActor(fields ...string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement.Schema == nil || db.Statement.SkipHooks {
+			return
+		}
+		principal := auth.PrincipalFromContext(db.Statement.Context)
+		if principal == nil {
+			return
+		}
+		for _, field := range fields {
+			if db.Statement.Schema.LookUpField(field) != nil {
+				db.Statement.SetColumn(field, principal.Actor())
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"go/payment-processor/pkg/entities"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateCustomer fails with gorm.ErrDuplicatedKey when another customer took the email first
+func (r *repository) CreateCustomer(ctx context.Context, customer *entities.Customer) (*entities.Customer, error) {
+	if err := r.db.WithContext(ctx).Create(customer).Error; err != nil {
+		return nil, translateUniqueViolation(err)
+	}
+	return customer, nil
+}
+
+// GetCustomerByID returns the customer unless it has been soft-deleted
+func (r *repository) GetCustomerByID(ctx context.Context, customerID uint) (*entities.Customer, error) {
+	var customer entities.Customer
+	if err := r.db.WithContext(ctx).Where("id = ? AND is_active = ?", customerID, true).First(&customer).Error; err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// GetCustomerByEmail also returns soft-deleted customers, since the email column is unique
+func (r *repository) GetCustomerByEmail(ctx context.Context, email string) (*entities.Customer, error) {
+	var customer entities.Customer
+	if err := r.db.WithContext(ctx).Where("customer_email = ?", email).First(&customer).Error; err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// SearchCustomers matches active customers by case-insensitive name fragment and/or exact email
+func (r *repository) SearchCustomers(ctx context.Context, name, email string, limit int) ([]entities.Customer, error) {
+	query := r.db.WithContext(ctx).Where("is_active = ?", true)
+	if name != "" {
+		query = query.Where("LOWER(customer_name) LIKE ?", "%"+strings.ToLower(name)+"%")
+	}
+	if email != "" {
+		query = query.Where("customer_email = ?", strings.ToLower(email))
+	}
+
+	var customers []entities.Customer
+	if err := query.Order("id").Limit(limit).Find(&customers).Error; err != nil {
+		return nil, err
+	}
+	return customers, nil
+}
+
+// UpdateCustomer fails with gorm.ErrDuplicatedKey when another customer took the email first
+func (r *repository) UpdateCustomer(ctx context.Context, customer *entities.Customer) (*entities.Customer, error) {
+	if err := r.db.WithContext(ctx).Save(customer).Error; err != nil {
+		return nil, translateUniqueViolation(err)
+	}
+	return customer, nil
+}
+
+// DeleteCustomer soft-deletes the customer together with its saved payment methods
+func (r *repository) DeleteCustomer(ctx context.Context, customerID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entities.Customer{}).
+			Where("id = ? AND is_active = ?", customerID, true).
+			Update("is_active", false)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Model(&entities.PaymentMethod{}).
+			Where("customer_id = ?", customerID).
+			Update("is_active", false).Error
+	})
+}
+
+func (r *repository) CreatePaymentMethod(ctx context.Context, paymentMethod *entities.PaymentMethod) (*entities.PaymentMethod, error) {
+	if err := r.db.WithContext(ctx).Create(paymentMethod).Error; err != nil {
+		return nil, err
+	}
+	return paymentMethod, nil
+}
+
+func (r *repository) GetPaymentMethodByID(ctx context.Context, paymentMethodID uint) (*entities.PaymentMethod, error) {
+	var paymentMethod entities.PaymentMethod
+	if err := r.db.WithContext(ctx).Where("id = ? AND is_active = ?", paymentMethodID, true).First(&paymentMethod).Error; err != nil {
+		return nil, err
+	}
+	return &paymentMethod, nil
+}
+
+func (r *repository) GetPaymentMethodsByCustomer(ctx context.Context, customerID uint) ([]entities.PaymentMethod, error) {
+	var paymentMethods []entities.PaymentMethod
+	if err := r.db.WithContext(ctx).Where("customer_id = ? AND is_active = ?", customerID, true).
+		Order("id").Find(&paymentMethods).Error; err != nil {
+		return nil, err
+	}
+	return paymentMethods, nil
+}
+
+// DeletePaymentMethod detaches a saved payment method from its customer
+func (r *repository) DeletePaymentMethod(ctx context.Context, customerID, paymentMethodID uint) error {
+	result := r.db.WithContext(ctx).Model(&entities.PaymentMethod{}).
+		Where("id = ? AND customer_id = ? AND is_active = ?", paymentMethodID, customerID, true).
+		Update("is_active", false)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ReencryptPaymentMethods passes the encrypted source of every saved payment method, deleted ones included,
+// through reseal and stores the result, all in one transaction, and returns how many sources changed.
+// Any failure rolls back the whole run, so no payment method is left under a key nobody holds.
+func (r *repository) ReencryptPaymentMethods(ctx context.Context, reseal func(encryptedSource string) (string, error)) (int, error) {
+	resealed := 0
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var paymentMethods []entities.PaymentMethod
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Order("id").Find(&paymentMethods).Error; err != nil {
+			return err
+		}
+		for _, paymentMethod := range paymentMethods {
+			source, err := reseal(paymentMethod.EncryptedSource)
+			if err != nil {
+				return fmt.Errorf("payment method %d: %w", paymentMethod.ID, err)
+			}
+			if source == paymentMethod.EncryptedSource {
+				continue
+			}
+			if err := tx.Model(&entities.PaymentMethod{}).Where("id = ?", paymentMethod.ID).
+				UpdateColumn("encrypted_source", source).Error; err != nil {
+				return err
+			}
+			resealed++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return resealed, nil
+}
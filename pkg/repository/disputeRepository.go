@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+// openDisputeStatuses are the statuses of disputes that are not resolved yet
+var openDisputeStatuses = []string{utils.DisputeStatusNeedsResponse, utils.DisputeStatusUnderReview}
+
+// CreateDispute stores a new dispute and moves its invoice to invoiceStatus in one transaction. It fails
+// with gorm.ErrDuplicatedKey when the payment already has an open dispute.
+func (r *repository) CreateDispute(ctx context.Context, dispute *entities.Dispute, invoiceStatus string) (*entities.Dispute, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(dispute).Error; err != nil {
+			return translateUniqueViolation(err)
+		}
+		return tx.Model(&entities.Invoice{}).Where("id = ?", dispute.InvoiceID).
+			Update("invoice_status", invoiceStatus).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dispute, nil
+}
+
+func (r *repository) GetDisputeByID(ctx context.Context, disputeID uint) (*entities.Dispute, error) {
+	var dispute entities.Dispute
+	if err := r.db.WithContext(ctx).First(&dispute, disputeID).Error; err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+func (r *repository) CountOpenDisputes(ctx context.Context, paymentID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entities.Dispute{}).
+		Where("payment_id = ? AND dispute_status IN ?", paymentID, openDisputeStatuses).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *repository) GetDisputeEvidence(ctx context.Context, disputeID uint) ([]entities.DisputeEvidence, error) {
+	var evidence []entities.DisputeEvidence
+	if err := r.db.WithContext(ctx).Where("dispute_id = ?", disputeID).Order("id").Find(&evidence).Error; err != nil {
+		return nil, err
+	}
+	return evidence, nil
+}
+
+// AddDisputeEvidence stores the evidence and moves its dispute to disputeStatus in one transaction
+func (r *repository) AddDisputeEvidence(ctx context.Context, evidence *entities.DisputeEvidence, disputeStatus string) (*entities.DisputeEvidence, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(evidence).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entities.Dispute{}).Where("id = ?", evidence.DisputeID).
+			Update("dispute_status", disputeStatus).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return evidence, nil
+}
+
+// ResolveDispute saves the final dispute state, updates the invoice and, for lost disputes, books the ledger entry.
+// The dispute only moves while it is still open, so of concurrent resolutions only one succeeds; the others
+// fail with gorm.ErrRecordNotFound and change nothing.
+func (r *repository) ResolveDispute(ctx context.Context, dispute *entities.Dispute, invoiceStatus string, ledgerEntry *entities.LedgerEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entities.Dispute{}).
+			Where("id = ? AND dispute_status IN ?", dispute.ID, openDisputeStatuses).
+			Updates(map[string]interface{}{
+				"dispute_status": dispute.DisputeStatus,
+				"resolved_at":    dispute.ResolvedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Model(&entities.Invoice{}).Where("id = ?", dispute.InvoiceID).
+			Update("invoice_status", invoiceStatus).Error; err != nil {
+			return err
+		}
+		if ledgerEntry == nil {
+			return nil
+		}
+		return translateUniqueViolation(tx.Create(ledgerEntry).Error)
+	})
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// uniqueViolation is the SQLSTATE of a write breaking a unique constraint
+const uniqueViolation = "23505"
+
+// translateUniqueViolation reports a unique constraint violation as gorm.ErrDuplicatedKey, keeping
+// the database error as the cause, so services can answer a lost race on a unique column with a conflict.
+func translateUniqueViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return errors.Join(gorm.ErrDuplicatedKey, err)
+	}
+	return err
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTranslateUniqueViolation(t *testing.T) {
+	violation := fmt.Errorf("insert merchant: %w", &pgconn.PgError{Code: uniqueViolation, ConstraintName: "merchants_merchant_code_key"})
+	err := translateUniqueViolation(violation)
+	assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+	assert.ErrorIs(t, err, violation)
+
+	other := &pgconn.PgError{Code: "23503"}
+	assert.Same(t, error(other), translateUniqueViolation(other))
+	assert.False(t, errors.Is(translateUniqueViolation(gorm.ErrInvalidData), gorm.ErrDuplicatedKey))
+}
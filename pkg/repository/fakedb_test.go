@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeDB is an in-memory database understanding the statements the repository sends for inserts,
+// updates and lookups by column values, enough to run the gorm callbacks without Postgres. Every
+// statement is kept in order, so tests can check what was sent.
+type fakeDB struct {
+	mu         sync.Mutex
+	tables     map[string][]map[string]driver.Value
+	statements []string
+}
+
+var (
+	insertPattern  = regexp.MustCompile(`^INSERT INTO "(\w+)" \((.+?)\) VALUES (.+?)(?: RETURNING "(\w+)")?$`)
+	updatePattern  = regexp.MustCompile(`^UPDATE "(\w+)" SET (.+?) WHERE (.+)$`)
+	selectPattern  = regexp.MustCompile(`^SELECT \* FROM "(\w+)"(?: WHERE (.+?))?(?: ORDER BY (\w+)( DESC)?)?(?: LIMIT (\d+|\$\d+))?$`)
+	assignPattern  = regexp.MustCompile(`^"?(\w+)"?=\$(\d+)$`)
+	comparePattern = regexp.MustCompile(`^(?:"?\w+"?\.)?"?(\w+)"? (=|>) \$(\d+)$`)
+	inPattern      = regexp.MustCompile(`^(?:"?\w+"?\.)?"?(\w+)"? IN \((.+)\)$`)
+	placeholderRun = regexp.MustCompile(`\$(\d+)`)
+)
+
+// newFakeRepository returns a repository on top of a new fakeDB
+func newFakeRepository(t *testing.T) (*repository, *fakeDB) {
+	fake := &fakeDB{tables: map[string][]map[string]driver.Value{}}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(fake)}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewRepository(db, zap.NewNop()).(*repository), fake
+}
+
+// Statements returns the statements sent so far that start with one of prefixes
+func (f *fakeDB) Statements(prefixes ...string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var statements []string
+	for _, statement := range f.statements {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(statement, prefix) {
+				statements = append(statements, statement)
+				break
+			}
+		}
+	}
+	return statements
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return nil }
+
+// matches reports whether row satisfies the conditions of a WHERE clause joined by AND
+func matches(row map[string]driver.Value, where string, args []driver.NamedValue) (bool, error) {
+	if where == "" {
+		return true, nil
+	}
+	for _, condition := range strings.Split(where, " AND ") {
+		condition = strings.Trim(condition, "()")
+		if m := comparePattern.FindStringSubmatch(condition); m != nil {
+			value := arg(args, m[3])
+			if (m[2] == "=" && !equal(row[m[1]], value)) || (m[2] == ">" && !less(value, row[m[1]])) {
+				return false, nil
+			}
+			continue
+		}
+		if m := inPattern.FindStringSubmatch(condition); m != nil {
+			found := false
+			for _, placeholder := range placeholderRun.FindAllStringSubmatch(m[2], -1) {
+				found = found || equal(row[m[1]], arg(args, placeholder[1]))
+			}
+			if !found {
+				return false, nil
+			}
+			continue
+		}
+		return false, fmt.Errorf("fakedb: unsupported condition %q", condition)
+	}
+	return true, nil
+}
+
+func arg(args []driver.NamedValue, placeholder string) driver.Value {
+	n, _ := strconv.Atoi(placeholder)
+	return args[n-1].Value
+}
+
+func equal(a, b driver.Value) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func (f *fakeDB) insert(m []string, args []driver.NamedValue) (driver.Rows, error) {
+	columns := strings.Split(strings.ReplaceAll(m[2], `"`, ""), ",")
+	groups := regexp.MustCompile(`\(([^)]*)\)`).FindAllStringSubmatch(m[3], -1)
+	rows := &fakeRows{}
+	if m[4] != "" {
+		rows.columns = []string{m[4]}
+	}
+	for _, group := range groups {
+		row := map[string]driver.Value{}
+		for i, placeholder := range placeholderRun.FindAllStringSubmatch(group[1], -1) {
+			row[columns[i]] = arg(args, placeholder[1])
+		}
+		id := int64(len(f.tables[m[1]]) + 1)
+		row["id"] = id
+		f.tables[m[1]] = append(f.tables[m[1]], row)
+		if m[4] != "" {
+			rows.values = append(rows.values, []driver.Value{id})
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeDB) update(m []string, args []driver.NamedValue) (int64, error) {
+	var affected int64
+	for _, row := range f.tables[m[1]] {
+		ok, err := matches(row, m[3], args)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			continue
+		}
+		for _, assignment := range strings.Split(m[2], ",") {
+			a := assignPattern.FindStringSubmatch(assignment)
+			if a == nil {
+				return 0, fmt.Errorf("fakedb: unsupported assignment %q", assignment)
+			}
+			row[a[1]] = arg(args, a[2])
+		}
+		affected++
+	}
+	return affected, nil
+}
+
+func (f *fakeDB) selectRows(m []string, args []driver.NamedValue) (driver.Rows, error) {
+	var selected []map[string]driver.Value
+	for _, row := range f.tables[m[1]] {
+		ok, err := matches(row, m[2], args)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			selected = append(selected, row)
+		}
+	}
+	if m[3] != "" {
+		sort.SliceStable(selected, func(i, j int) bool {
+			less := less(selected[i][m[3]], selected[j][m[3]])
+			if m[4] != "" {
+				return !less
+			}
+			return less
+		})
+	}
+	if m[5] != "" {
+		limit, _ := strconv.Atoi(m[5])
+		if strings.HasPrefix(m[5], "$") {
+			limit, _ = strconv.Atoi(fmt.Sprint(arg(args, m[5][1:])))
+		}
+		if len(selected) > limit {
+			selected = selected[:limit]
+		}
+	}
+
+	rows := &fakeRows{}
+	for column := range firstRow(f.tables[m[1]]) {
+		rows.columns = append(rows.columns, column)
+	}
+	sort.Strings(rows.columns)
+	for _, row := range selected {
+		values := make([]driver.Value, len(rows.columns))
+		for i, column := range rows.columns {
+			values[i] = row[column]
+		}
+		rows.values = append(rows.values, values)
+	}
+	return rows, nil
+}
+
+// less orders integers by value and anything else by its text
+func less(a, b driver.Value) bool {
+	if x, ok := a.(int64); ok {
+		if y, ok := b.(int64); ok {
+			return x < y
+		}
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+func firstRow(rows []map[string]driver.Value) map[string]driver.Value {
+	if len(rows) == 0 {
+		return nil
+	}
+	return rows[0]
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.statements = append(c.db.statements, query)
+	if m := insertPattern.FindStringSubmatch(query); m != nil {
+		return c.db.insert(m, args)
+	}
+	if m := selectPattern.FindStringSubmatch(query); m != nil {
+		return c.db.selectRows(m, args)
+	}
+	return nil, fmt.Errorf("fakedb: unsupported query %q", query)
+}
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.statements = append(c.db.statements, query)
+	switch m := updatePattern.FindStringSubmatch(query); {
+	case m != nil:
+		affected, err := c.db.update(m, args)
+		return driver.RowsAffected(affected), err
+	case strings.HasPrefix(query, "SELECT pg_advisory_xact_lock("):
+		return driver.RowsAffected(0), nil
+	}
+	if m := insertPattern.FindStringSubmatch(query); m != nil {
+		rows, err := c.db.insert(m, args)
+		return driver.RowsAffected(len(rows.(*fakeRows).values)), err
+	}
+	return nil, fmt.Errorf("fakedb: unsupported statement %q", query)
+}
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("fakedb: prepared statements are not supported")
+}
+func (c fakeConn) Close() error { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.statements = append(c.db.statements, "BEGIN")
+	return c, nil
+}
+func (c fakeConn) Commit() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.statements = append(c.db.statements, "COMMIT")
+	return nil
+}
+func (c fakeConn) Rollback() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.statements = append(c.db.statements, "ROLLBACK")
+	return nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/health"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+// models are the entities whose tables the repository reads and writes
+var models = []any{
+	&entities.Merchant{}, &entities.ApiKey{}, &entities.Customer{}, &entities.PaymentMethod{}, &entities.Invoice{},
+	&entities.Payment{}, &entities.Dispute{}, &entities.DisputeEvidence{}, &entities.LedgerEntry{}, &entities.AuditLog{},
+}
+
+// RegisterHealthChecks registers the checks the repository needs to be ready: the database answers
+// and its schema has every table and column of the entities.
+func RegisterHealthChecks(registry *health.Registry, db *gorm.DB) {
+	registry.Register("database", pingCheck(db))
+	registry.Register("migrations", schemaCheck(db))
+}
+
+func pingCheck(db *gorm.DB) health.Check {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// schemaCheck fails while a migration is missing. The schema is only inspected until it is complete,
+// since an applied migration is not undone while the server runs.
+func schemaCheck(db *gorm.DB) health.Check {
+	var complete atomic.Bool
+	return func(ctx context.Context) error {
+		if complete.Load() {
+			return nil
+		}
+		tx := db.WithContext(ctx)
+		migrator := tx.Migrator()
+		for _, model := range models {
+			stmt := &gorm.Statement{DB: tx}
+			if err := stmt.Parse(model); err != nil {
+				return err
+			}
+			if !migrator.HasTable(model) {
+				return fmt.Errorf("table %s is missing", stmt.Table)
+			}
+			for _, field := range stmt.Schema.Fields {
+				if field.DBName != "" && !migrator.HasColumn(model, field.DBName) {
+					return fmt.Errorf("column %s.%s is missing", stmt.Table, field.DBName)
+				}
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		complete.Store(true)
+		return nil
+	}
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"go/payment-processor/pkg/entities"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Invoice sort columns accepted by ListInvoices
+const (
+	InvoiceSortCreatedAt = "created_at"
+	InvoiceSortAmount    = "amount"
+	InvoiceSortID        = "id"
+)
+
+// InvoiceQuery describes a filtered, keyset-paginated invoice listing. Zero values mean "no filter".
+type InvoiceQuery struct {
+	MerchantID  uint
+	CustomerID  uint
+	Status      string
+	Currency    string
+	Mode        string
+	MinAmount   decimal.NullDecimal
+	MaxAmount   decimal.NullDecimal
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	SortBy      string
+	Descending  bool
+	Limit       int
+	After       *InvoiceCursor
+}
+
+// InvoiceCursor is the position of the last invoice of the previous page. The id breaks ties
+// between invoices sharing the same sort value, which keeps pages stable.
+type InvoiceCursor struct {
+	ID        uint            `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Amount    decimal.Decimal `json:"amount"`
+}
+
+func (r *repository) ListInvoices(ctx context.Context, query InvoiceQuery) ([]entities.Invoice, error) {
+	db, err := buildInvoiceQuery(r.db.WithContext(ctx).Model(&entities.Invoice{}), query)
+	if err != nil {
+		return nil, err
+	}
+
+	var invoices []entities.Invoice
+	if err := db.Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+func buildInvoiceQuery(db *gorm.DB, query InvoiceQuery) (*gorm.DB, error) {
+	if query.MerchantID != 0 {
+		db = db.Where("merchant_id = ?", query.MerchantID)
+	}
+	if query.CustomerID != 0 {
+		db = db.Where("customer_id = ?", query.CustomerID)
+	}
+	if query.Status != "" {
+		db = db.Where("invoice_status = ?", query.Status)
+	}
+	if query.Currency != "" {
+		db = db.Where("currency = ?", query.Currency)
+	}
+	if query.Mode != "" {
+		db = db.Where("mode = ?", query.Mode)
+	}
+	if query.MinAmount.Valid {
+		db = db.Where("amount >= ?", query.MinAmount.Decimal)
+	}
+	if query.MaxAmount.Valid {
+		db = db.Where("amount <= ?", query.MaxAmount.Decimal)
+	}
+	if query.CreatedFrom != nil {
+		db = db.Where("created_at >= ?", *query.CreatedFrom)
+	}
+	if query.CreatedTo != nil {
+		db = db.Where("created_at < ?", *query.CreatedTo)
+	}
+
+	direction, comparison := "ASC", ">"
+	if query.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if query.After != nil {
+		switch query.SortBy {
+		case InvoiceSortCreatedAt:
+			db = db.Where(fmt.Sprintf("(created_at %[1]s ?) OR (created_at = ? AND id %[1]s ?)", comparison),
+				query.After.CreatedAt, query.After.CreatedAt, query.After.ID)
+		case InvoiceSortAmount:
+			db = db.Where(fmt.Sprintf("(amount %[1]s ?) OR (amount = ? AND id %[1]s ?)", comparison),
+				query.After.Amount, query.After.Amount, query.After.ID)
+		case InvoiceSortID:
+			db = db.Where(fmt.Sprintf("id %s ?", comparison), query.After.ID)
+		}
+	}
+
+	switch query.SortBy {
+	case InvoiceSortCreatedAt, InvoiceSortAmount:
+		db = db.Order(fmt.Sprintf("%s %s, id %s", query.SortBy, direction, direction))
+	case InvoiceSortID:
+		db = db.Order("id " + direction)
+	default:
+		return nil, fmt.Errorf("unsupported invoice sort column %q", query.SortBy)
+	}
+
+	return db.Limit(query.Limit), nil
+}
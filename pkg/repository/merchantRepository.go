@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"go/payment-processor/pkg/entities"
+
+	"gorm.io/gorm"
+)
+
+// CreateMerchant fails with gorm.ErrDuplicatedKey when another merchant took the merchant code first
+func (r *repository) CreateMerchant(ctx context.Context, merchant *entities.Merchant) (*entities.Merchant, error) {
+	if err := r.db.WithContext(ctx).Create(merchant).Error; err != nil {
+		return nil, translateUniqueViolation(err)
+	}
+	return merchant, nil
+}
+
+// GetMerchantByID returns the merchant unless it has been soft-deleted
+func (r *repository) GetMerchantByID(ctx context.Context, merchantID uint) (*entities.Merchant, error) {
+	var merchant entities.Merchant
+	if err := r.db.WithContext(ctx).Where("id = ? AND is_active = ?", merchantID, true).First(&merchant).Error; err != nil {
+		return nil, err
+	}
+	return &merchant, nil
+}
+
+// GetMerchantByCode also returns soft-deleted merchants, since their codes stay reserved
+func (r *repository) GetMerchantByCode(ctx context.Context, merchantCode string) (*entities.Merchant, error) {
+	var merchant entities.Merchant
+	if err := r.db.WithContext(ctx).Where("merchant_code = ?", merchantCode).First(&merchant).Error; err != nil {
+		return nil, err
+	}
+	return &merchant, nil
+}
+
+// UpdateMerchant fails with gorm.ErrDuplicatedKey when another merchant took the merchant code first
+func (r *repository) UpdateMerchant(ctx context.Context, merchant *entities.Merchant) (*entities.Merchant, error) {
+	if err := r.db.WithContext(ctx).Save(merchant).Error; err != nil {
+		return nil, translateUniqueViolation(err)
+	}
+	return merchant, nil
+}
+
+// DeleteMerchant soft-deletes the merchant by clearing AuditTrail.IsActive
+func (r *repository) DeleteMerchant(ctx context.Context, merchantID uint) error {
+	result := r.db.WithContext(ctx).Model(&entities.Merchant{}).
+		Where("id = ? AND is_active = ?", merchantID, true).
+		Update("is_active", false)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
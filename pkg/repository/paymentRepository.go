@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"go/payment-processor/pkg/entities"
+	"time"
+)
+
+// PaymentCountQuery counts payment attempts created since a point in time. Zero values mean "no filter".
+type PaymentCountQuery struct {
+	SourceFingerprint string
+	CustomerID        uint
+	MerchantID        uint
+	ClientIP          string
+	Statuses          []string
+	Since             time.Time
+}
+
+func (r *repository) CountPayments(ctx context.Context, query PaymentCountQuery) (int64, error) {
+	db := r.db.WithContext(ctx).Model(&entities.Payment{}).Where("created_at >= ?", query.Since)
+	if query.SourceFingerprint != "" {
+		db = db.Where("source_fingerprint = ?", query.SourceFingerprint)
+	}
+	if query.CustomerID != 0 {
+		db = db.Where("customer_id = ?", query.CustomerID)
+	}
+	if query.MerchantID != 0 {
+		db = db.Where("merchant_id = ?", query.MerchantID)
+	}
+	if query.ClientIP != "" {
+		db = db.Where("client_ip = ?", query.ClientIP)
+	}
+	if len(query.Statuses) > 0 {
+		db = db.Where("payment_status IN ?", query.Statuses)
+	}
+
+	var count int64
+	if err := db.Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetPaymentsByStatus returns up to limit payments in the status, oldest first
+func (r *repository) GetPaymentsByStatus(ctx context.Context, status string, merchantID uint, limit int) ([]entities.Payment, error) {
+	db := r.db.WithContext(ctx).Where("payment_status = ?", status)
+	if merchantID != 0 {
+		db = db.Where("merchant_id = ?", merchantID)
+	}
+
+	var payments []entities.Payment
+	if err := db.Order("id").Limit(limit).Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+func (r *repository) UpdatePayment(ctx context.Context, payment *entities.Payment) (*entities.Payment, error) {
+	if err := r.db.WithContext(ctx).Save(payment).Error; err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
@@ -1,23 +1,66 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"github.com/labstack/gommon/log"
 	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/utils"
+	"time"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 type Repository interface {
-	CreateInvoice(invoice *entities.Invoice) (*entities.Invoice, error)
-	GetInvoiceByID(id uint) (*entities.Invoice, error)
-	ProcessPayment(payment *entities.Payment) (*entities.Payment, error)
-	GetPaymentStatus(invoiceID uint) (string, error)
-	DoesMerchantExist(merchantID uint) (*entities.Merchant, error)
-	DoesCustomerExist(customerID uint) (*entities.Customer, error)
-	GetAllowedCurrenciesForMerchant(merchantID uint) (string, error)
-	DoesInvoiceExist(invoiceID uint) (*entities.Invoice, error)
+	CreateInvoice(ctx context.Context, invoice *entities.Invoice) (*entities.Invoice, error)
+	GetInvoiceByID(ctx context.Context, id uint) (*entities.Invoice, error)
+	ListInvoices(ctx context.Context, query InvoiceQuery) ([]entities.Invoice, error)
+	ProcessPayment(ctx context.Context, payment *entities.Payment) (*entities.Payment, error)
+	GetPaymentStatus(ctx context.Context, invoiceID uint) (string, error)
+	DoesMerchantExist(ctx context.Context, merchantID uint) (*entities.Merchant, error)
+	DoesCustomerExist(ctx context.Context, customerID uint) (*entities.Customer, error)
+	GetAllowedCurrenciesForMerchant(ctx context.Context, merchantID uint) (string, error)
+	DoesInvoiceExist(ctx context.Context, invoiceID uint) (*entities.Invoice, error)
+	GetPaymentsCreatedBetween(ctx context.Context, from, to time.Time) ([]entities.Payment, error)
+	UpdatePaymentStatus(ctx context.Context, paymentID uint, status string) error
+	ClaimPayment(ctx context.Context, paymentID uint, fromStatus, toStatus string) error
+	ClaimInvoice(ctx context.Context, invoiceID uint, fromStatus, toStatus string) error
+	GetPaymentByID(ctx context.Context, paymentID uint) (*entities.Payment, error)
+	UpdatePayment(ctx context.Context, payment *entities.Payment) (*entities.Payment, error)
+	CountPayments(ctx context.Context, query PaymentCountQuery) (int64, error)
+	GetPaymentsByStatus(ctx context.Context, status string, merchantID uint, limit int) ([]entities.Payment, error)
+	UpdateInvoiceStatus(ctx context.Context, invoiceID uint, status string) error
+	CreateDispute(ctx context.Context, dispute *entities.Dispute, invoiceStatus string) (*entities.Dispute, error)
+	GetDisputeByID(ctx context.Context, disputeID uint) (*entities.Dispute, error)
+	CountOpenDisputes(ctx context.Context, paymentID uint) (int64, error)
+	GetDisputeEvidence(ctx context.Context, disputeID uint) ([]entities.DisputeEvidence, error)
+	AddDisputeEvidence(ctx context.Context, evidence *entities.DisputeEvidence, disputeStatus string) (*entities.DisputeEvidence, error)
+	ResolveDispute(ctx context.Context, dispute *entities.Dispute, invoiceStatus string, ledgerEntry *entities.LedgerEntry) error
+	CreateMerchant(ctx context.Context, merchant *entities.Merchant) (*entities.Merchant, error)
+	GetMerchantByID(ctx context.Context, merchantID uint) (*entities.Merchant, error)
+	GetMerchantByCode(ctx context.Context, merchantCode string) (*entities.Merchant, error)
+	UpdateMerchant(ctx context.Context, merchant *entities.Merchant) (*entities.Merchant, error)
+	DeleteMerchant(ctx context.Context, merchantID uint) error
+	CreateCustomer(ctx context.Context, customer *entities.Customer) (*entities.Customer, error)
+	GetCustomerByID(ctx context.Context, customerID uint) (*entities.Customer, error)
+	GetCustomerByEmail(ctx context.Context, email string) (*entities.Customer, error)
+	SearchCustomers(ctx context.Context, name, email string, limit int) ([]entities.Customer, error)
+	UpdateCustomer(ctx context.Context, customer *entities.Customer) (*entities.Customer, error)
+	DeleteCustomer(ctx context.Context, customerID uint) error
+	CreatePaymentMethod(ctx context.Context, paymentMethod *entities.PaymentMethod) (*entities.PaymentMethod, error)
+	GetPaymentMethodByID(ctx context.Context, paymentMethodID uint) (*entities.PaymentMethod, error)
+	GetPaymentMethodsByCustomer(ctx context.Context, customerID uint) ([]entities.PaymentMethod, error)
+	DeletePaymentMethod(ctx context.Context, customerID, paymentMethodID uint) error
+	ReencryptPaymentMethods(ctx context.Context, reseal func(encryptedSource string) (string, error)) (int, error)
+	CreateAPIKey(ctx context.Context, apiKey *entities.ApiKey) (*entities.ApiKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*entities.ApiKey, error)
+	GetAPIKeyByID(ctx context.Context, merchantID, apiKeyID uint) (*entities.ApiKey, error)
+	GetAPIKeysByMerchant(ctx context.Context, merchantID uint) ([]entities.ApiKey, error)
+	RevokeAPIKey(ctx context.Context, merchantID, apiKeyID uint) error
+	TouchAPIKey(ctx context.Context, apiKeyID uint) error
+	ListAuditLogs(ctx context.Context, query AuditLogQuery) ([]entities.AuditLog, error)
+	GetAuditLogsAfter(ctx context.Context, afterID uint, limit int) ([]entities.AuditLog, error)
 }
 
 type repository struct {
@@ -26,37 +69,46 @@ type repository struct {
 }
 
 func NewRepository(db *gorm.DB, logger *zap.Logger) Repository {
+	if err := registerAuditTrailCallbacks(db); err != nil {
+		logger.Error("Failed to register audit trail callbacks", zap.Error(err))
+	}
+	if err := registerAuditLogCallbacks(db); err != nil {
+		logger.Error("Failed to register audit log callbacks", zap.Error(err))
+	}
+	if err := registerTracingCallbacks(db); err != nil {
+		logger.Error("Failed to register tracing callbacks", zap.Error(err))
+	}
 	return &repository{
 		db:  db,
 		log: logger}
 }
 
-func (r *repository) CreateInvoice(invoice *entities.Invoice) (*entities.Invoice, error) {
-	if err := r.db.Create(&invoice).Error; err != nil {
+func (r *repository) CreateInvoice(ctx context.Context, invoice *entities.Invoice) (*entities.Invoice, error) {
+	if err := r.db.WithContext(ctx).Create(&invoice).Error; err != nil {
 		return nil, err
 	}
 	return invoice, nil
 }
 
-func (r *repository) GetInvoiceByID(id uint) (*entities.Invoice, error) {
+func (r *repository) GetInvoiceByID(ctx context.Context, id uint) (*entities.Invoice, error) {
 	var invoice entities.Invoice
-	if err := r.db.First(&invoice, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&invoice, id).Error; err != nil {
 		return nil, err
 	}
 	return &invoice, nil
 }
 
-func (r *repository) ProcessPayment(payment *entities.Payment) (*entities.Payment, error) {
+func (r *repository) ProcessPayment(ctx context.Context, payment *entities.Payment) (*entities.Payment, error) {
 	// Example logic for processing a payment
-	if err := r.db.Create(&payment).Error; err != nil {
+	if err := r.db.WithContext(ctx).Create(&payment).Error; err != nil {
 		return nil, err
 	}
 	return payment, nil
 }
 
-func (r *repository) GetPaymentStatus(invoiceID uint) (string, error) {
+func (r *repository) GetPaymentStatus(ctx context.Context, invoiceID uint) (string, error) {
 	var payment entities.Payment
-	if err := r.db.Where("invoice_id = ?", invoiceID).First(&payment).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("invoice_id = ?", invoiceID).First(&payment).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return "", errors.New("payment not found for this invoice")
 		}
@@ -65,9 +117,9 @@ func (r *repository) GetPaymentStatus(invoiceID uint) (string, error) {
 	return payment.PaymentStatus, nil
 }
 
-func (r *repository) DoesMerchantExist(merchantID uint) (*entities.Merchant, error) {
+func (r *repository) DoesMerchantExist(ctx context.Context, merchantID uint) (*entities.Merchant, error) {
 	var merchant entities.Merchant
-	result := r.db.Where("id = ?", merchantID).First(&merchant)
+	result := r.db.WithContext(ctx).Where("id = ?", merchantID).First(&merchant)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			log.Error("No record found in merchant", zap.Error(result.Error))
@@ -77,9 +129,9 @@ func (r *repository) DoesMerchantExist(merchantID uint) (*entities.Merchant, err
 	return &merchant, nil
 }
 
-func (r *repository) DoesCustomerExist(customerID uint) (*entities.Customer, error) {
+func (r *repository) DoesCustomerExist(ctx context.Context, customerID uint) (*entities.Customer, error) {
 	var customer entities.Customer
-	result := r.db.Where("id = ?", customerID).First(&customer)
+	result := r.db.WithContext(ctx).Where("id = ?", customerID).First(&customer)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			log.Error("No record found in customer", zap.Error(result.Error))
@@ -89,9 +141,9 @@ func (r *repository) DoesCustomerExist(customerID uint) (*entities.Customer, err
 	return &customer, nil
 }
 
-func (r *repository) GetAllowedCurrenciesForMerchant(merchantID uint) (string, error) {
+func (r *repository) GetAllowedCurrenciesForMerchant(ctx context.Context, merchantID uint) (string, error) {
 	var currency string
-	err := r.db.Model(&entities.Merchant{}).
+	err := r.db.WithContext(ctx).Model(&entities.Merchant{}).
 		Select("allowed_currency").
 		Where("id = ?", merchantID).
 		Scan(&currency).Error
@@ -99,9 +151,9 @@ func (r *repository) GetAllowedCurrenciesForMerchant(merchantID uint) (string, e
 	return currency, err
 }
 
-func (r *repository) DoesInvoiceExist(invoiceID uint) (*entities.Invoice, error) {
+func (r *repository) DoesInvoiceExist(ctx context.Context, invoiceID uint) (*entities.Invoice, error) {
 	var invoice *entities.Invoice
-	result := r.db.
+	result := r.db.WithContext(ctx).
 		Where("id = ?", invoiceID).Find(&invoice)
 
 	if result.Error != nil {
@@ -112,3 +164,68 @@ func (r *repository) DoesInvoiceExist(invoiceID uint) (*entities.Invoice, error)
 	}
 	return invoice, nil
 }
+
+// GetPaymentsCreatedBetween returns the payments created in [from, to) that were sent to the provider
+func (r *repository) GetPaymentsCreatedBetween(ctx context.Context, from, to time.Time) ([]entities.Payment, error) {
+	var payments []entities.Payment
+	if err := r.db.WithContext(ctx).Where("created_at >= ? AND created_at < ?", from, to).
+		Where("payment_status NOT IN ?", utils.PaymentStatusesNotSubmitted).
+		Order("id").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+func (r *repository) UpdatePaymentStatus(ctx context.Context, paymentID uint, status string) error {
+	result := r.db.WithContext(ctx).Model(&entities.Payment{}).Where("id = ?", paymentID).Update("payment_status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ClaimPayment moves a payment from fromStatus to toStatus in a single conditional update, so that of
+// concurrent requests acting on the same payment only one succeeds. It fails with gorm.ErrRecordNotFound
+// when the payment is no longer in fromStatus.
+func (r *repository) ClaimPayment(ctx context.Context, paymentID uint, fromStatus, toStatus string) error {
+	result := r.db.WithContext(ctx).Model(&entities.Payment{}).
+		Where("id = ? AND payment_status = ?", paymentID, fromStatus).
+		Update("payment_status", toStatus)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ClaimInvoice moves an invoice from fromStatus to toStatus in a single conditional update, like ClaimPayment.
+// It fails with gorm.ErrRecordNotFound when the invoice is no longer in fromStatus.
+func (r *repository) ClaimInvoice(ctx context.Context, invoiceID uint, fromStatus, toStatus string) error {
+	result := r.db.WithContext(ctx).Model(&entities.Invoice{}).
+		Where("id = ? AND invoice_status = ?", invoiceID, fromStatus).
+		Update("invoice_status", toStatus)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *repository) GetPaymentByID(ctx context.Context, paymentID uint) (*entities.Payment, error) {
+	var payment entities.Payment
+	if err := r.db.WithContext(ctx).First(&payment, paymentID).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *repository) UpdateInvoiceStatus(ctx context.Context, invoiceID uint, status string) error {
+	return r.db.WithContext(ctx).Model(&entities.Invoice{}).Where("id = ?", invoiceID).Update("invoice_status", status).Error
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"errors"
+	"go/payment-processor/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	tracingCallback = "tracing"
+	tracingSpanKey  = "tracing:span"
+)
+
+// registerTracingCallbacks wraps every statement in a client span, a child of the span carried by the
+// statement context. It is safe to call for every repository.
+func registerTracingCallbacks(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if callbacks.Create().Get(tracingCallback+":before") != nil {
+		return nil
+	}
+	processors := []struct {
+		operation string
+		before    func(string, func(*gorm.DB)) error
+		after     func(string, func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("*").Register, callbacks.Create().After("*").Register},
+		{"query", callbacks.Query().Before("*").Register, callbacks.Query().After("*").Register},
+		{"update", callbacks.Update().Before("*").Register, callbacks.Update().After("*").Register},
+		{"delete", callbacks.Delete().Before("*").Register, callbacks.Delete().After("*").Register},
+		{"row", callbacks.Row().Before("*").Register, callbacks.Row().After("*").Register},
+		{"raw", callbacks.Raw().Before("*").Register, callbacks.Raw().After("*").Register},
+	}
+	for _, p := range processors {
+		if err := p.before(tracingCallback+":before", startSpan(p.operation)); err != nil {
+			return err
+		}
+		if err := p.after(tracingCallback+":after", endSpan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx, span := tracing.Tracer().Start(db.Statement.Context, "db."+operation, trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBOperationName(operation)))
+		db.Statement.Context = ctx
+		db.InstanceSet(tracingSpanKey, span)
+	}
+}
+
+func endSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(tracingSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	span.SetAttributes(semconv.DBCollectionName(db.Statement.Table), semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected))
+	// A lookup that finds nothing is not a failed query
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		tracing.RecordError(span, err)
+	}
+	span.End()
+}
@@ -0,0 +1,46 @@
+package requestid
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// Header carries the request ID on requests and responses
+const Header = echo.HeaderXRequestID
+
+// maxLength bounds the request IDs accepted from clients
+const maxLength = 128
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the request ID.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx, or an empty string.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// New returns a new request ID.
+func New() string {
+	return uuid.NewString()
+}
+
+// Valid reports whether a request ID sent by a client may be used. It must be at most maxLength
+// printable ASCII characters without spaces, so it cannot forge log lines or break headers.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,18 @@
+package requestid
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid(New()))
+	assert.True(t, Valid("req_01HZX/3:a.b-c"))
+	assert.True(t, Valid(strings.Repeat("a", maxLength)))
+
+	for _, id := range []string{"", strings.Repeat("a", maxLength+1), "req 1", "req\n{\"level\":\"error\"}", "req\t1", "réq"} {
+		assert.False(t, Valid(id), id)
+	}
+}
@@ -0,0 +1,165 @@
+package risk
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Decision constants are the outcomes of a risk assessment
+const (
+	DecisionAllow  = "ALLOW"
+	DecisionReview = "REVIEW"
+	DecisionBlock  = "BLOCK"
+)
+
+// Rule names reported in assessment reasons
+const (
+	RuleCardVelocity       = "card_velocity"
+	RuleCustomerVelocity   = "customer_velocity"
+	RuleIPVelocity         = "ip_velocity"
+	RuleAmountThreshold    = "amount_threshold"
+	RuleBINCountryMismatch = "bin_country_mismatch"
+	RuleRepeatedDeclines   = "repeated_declines"
+)
+
+// binLength is the number of leading card digits identifying the issuer
+const binLength = 6
+
+// Signals are the facts about a payment attempt the rules evaluate. Counts cover the engine's
+// window and do not include the attempt being evaluated.
+type Signals struct {
+	Amount           decimal.Decimal
+	CardNumber       string // empty for non-card payments
+	BillingCountry   string // ISO 3166-1 alpha-2, empty when unknown
+	CardAttempts     int
+	CustomerAttempts int
+	IPAttempts       int
+	CardDeclines     int
+}
+
+// Reason is a rule that contributed to the score.
+type Reason struct {
+	Rule   string `json:"rule"`
+	Score  int    `json:"score"`
+	Detail string `json:"detail"`
+}
+
+// Assessment is the outcome of evaluating a payment attempt.
+type Assessment struct {
+	Decision string
+	Score    int
+	Reasons  []Reason
+}
+
+// Config configures the rules and the score thresholds. A zero threshold disables its rule.
+type Config struct {
+	Window                 time.Duration
+	MaxAttemptsPerCard     int
+	MaxAttemptsPerCustomer int
+	MaxAttemptsPerIP       int
+	MaxDeclinesPerCard     int
+	ReviewAmount           decimal.Decimal
+	BlockAmount            decimal.Decimal
+	BINCountries           map[string]string // card BIN to issuing country
+	ReviewScore            int
+	BlockScore             int
+}
+
+// Rule scores. A single velocity or decline breach sends a payment to review, combined
+// breaches or an amount over the block threshold block it.
+const (
+	velocityScore        = 40
+	cardVelocityScore    = 50
+	reviewAmountScore    = 30
+	blockAmountScore     = 100
+	binMismatchScore     = 40
+	repeatedDeclineScore = 50
+)
+
+// Engine evaluates payment attempts against the configured rules.
+type Engine struct {
+	config Config
+}
+
+func NewEngine(config Config) *Engine {
+	if config.ReviewScore == 0 {
+		config.ReviewScore = 40
+	}
+	if config.BlockScore == 0 {
+		config.BlockScore = 80
+	}
+	if config.Window == 0 {
+		config.Window = time.Hour
+	}
+	return &Engine{config: config}
+}
+
+// Window is the period the attempt and decline counts in Signals should cover.
+func (e *Engine) Window() time.Duration {
+	return e.config.Window
+}
+
+// Evaluate scores the attempt and decides whether it may be sent to the provider.
+func (e *Engine) Evaluate(signals Signals) Assessment {
+	cfg := e.config
+	var reasons []Reason
+	add := func(rule string, score int, format string, args ...interface{}) {
+		reasons = append(reasons, Reason{Rule: rule, Score: score, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	if exceeds(signals.CardAttempts, cfg.MaxAttemptsPerCard) {
+		add(RuleCardVelocity, cardVelocityScore, "%d attempts with this card in %s", signals.CardAttempts, cfg.Window)
+	}
+	if exceeds(signals.CustomerAttempts, cfg.MaxAttemptsPerCustomer) {
+		add(RuleCustomerVelocity, velocityScore, "%d attempts by this customer in %s", signals.CustomerAttempts, cfg.Window)
+	}
+	if exceeds(signals.IPAttempts, cfg.MaxAttemptsPerIP) {
+		add(RuleIPVelocity, velocityScore, "%d attempts from this IP in %s", signals.IPAttempts, cfg.Window)
+	}
+	if exceeds(signals.CardDeclines, cfg.MaxDeclinesPerCard) {
+		add(RuleRepeatedDeclines, repeatedDeclineScore, "%d declines for this card in %s", signals.CardDeclines, cfg.Window)
+	}
+
+	switch {
+	case cfg.BlockAmount.IsPositive() && signals.Amount.GreaterThanOrEqual(cfg.BlockAmount):
+		add(RuleAmountThreshold, blockAmountScore, "amount %s is at or above %s", signals.Amount, cfg.BlockAmount)
+	case cfg.ReviewAmount.IsPositive() && signals.Amount.GreaterThanOrEqual(cfg.ReviewAmount):
+		add(RuleAmountThreshold, reviewAmountScore, "amount %s is at or above %s", signals.Amount, cfg.ReviewAmount)
+	}
+
+	if country := e.binCountry(signals.CardNumber); country != "" && signals.BillingCountry != "" &&
+		!strings.EqualFold(country, signals.BillingCountry) {
+		add(RuleBINCountryMismatch, binMismatchScore, "card issued in %s, billing country %s",
+			country, strings.ToUpper(signals.BillingCountry))
+	}
+
+	assessment := Assessment{Decision: DecisionAllow, Reasons: reasons}
+	for _, reason := range reasons {
+		assessment.Score += reason.Score
+	}
+	sort.SliceStable(assessment.Reasons, func(i, j int) bool { return assessment.Reasons[i].Score > assessment.Reasons[j].Score })
+	switch {
+	case assessment.Score >= cfg.BlockScore:
+		assessment.Decision = DecisionBlock
+	case assessment.Score >= cfg.ReviewScore:
+		assessment.Decision = DecisionReview
+	}
+	return assessment
+}
+
+// binCountry looks up the issuing country of a card by its BIN
+func (e *Engine) binCountry(cardNumber string) string {
+	if len(cardNumber) < binLength {
+		return ""
+	}
+	return e.config.BINCountries[cardNumber[:binLength]]
+}
+
+// exceeds reports whether count reaches max; the current attempt makes it one more than counted
+func exceeds(count, max int) bool {
+	return max > 0 && count >= max
+}
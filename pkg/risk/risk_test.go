@@ -0,0 +1,58 @@
+package risk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func testEngine() *Engine {
+	return NewEngine(Config{
+		Window:                 time.Hour,
+		MaxAttemptsPerCard:     3,
+		MaxAttemptsPerCustomer: 5,
+		MaxAttemptsPerIP:       10,
+		MaxDeclinesPerCard:     2,
+		ReviewAmount:           decimal.NewFromInt(1000),
+		BlockAmount:            decimal.NewFromInt(10000),
+		BINCountries:           map[string]string{"411111": "US"},
+	})
+}
+
+func TestEvaluateAllows(t *testing.T) {
+	assessment := testEngine().Evaluate(Signals{Amount: decimal.NewFromInt(50), CardNumber: "4111111111111111", BillingCountry: "us", CardAttempts: 2})
+	assert.Equal(t, DecisionAllow, assessment.Decision)
+	assert.Zero(t, assessment.Score)
+	assert.Empty(t, assessment.Reasons)
+}
+
+func TestEvaluateReviews(t *testing.T) {
+	assessment := testEngine().Evaluate(Signals{Amount: decimal.NewFromInt(50), CardNumber: "4111111111111111", BillingCountry: "DE"})
+	assert.Equal(t, DecisionReview, assessment.Decision)
+	assert.Equal(t, binMismatchScore, assessment.Score)
+	assert.Equal(t, RuleBINCountryMismatch, assessment.Reasons[0].Rule)
+
+	assessment = testEngine().Evaluate(Signals{Amount: decimal.NewFromInt(50), CardDeclines: 2})
+	assert.Equal(t, DecisionReview, assessment.Decision)
+	assert.Equal(t, RuleRepeatedDeclines, assessment.Reasons[0].Rule)
+}
+
+func TestEvaluateBlocks(t *testing.T) {
+	assessment := testEngine().Evaluate(Signals{Amount: decimal.NewFromInt(10000)})
+	assert.Equal(t, DecisionBlock, assessment.Decision)
+	assert.Equal(t, RuleAmountThreshold, assessment.Reasons[0].Rule)
+
+	// Breaches add up, the highest scoring reason comes first
+	assessment = testEngine().Evaluate(Signals{Amount: decimal.NewFromInt(1500), CardAttempts: 3, IPAttempts: 12})
+	assert.Equal(t, DecisionBlock, assessment.Decision)
+	assert.Equal(t, cardVelocityScore+velocityScore+reviewAmountScore, assessment.Score)
+	assert.Equal(t, RuleCardVelocity, assessment.Reasons[0].Rule)
+	assert.Len(t, assessment.Reasons, 3)
+}
+
+func TestDisabledRules(t *testing.T) {
+	assessment := NewEngine(Config{}).Evaluate(Signals{Amount: decimal.NewFromInt(1000000), CardAttempts: 100, CardDeclines: 100})
+	assert.Equal(t, DecisionAllow, assessment.Decision)
+}
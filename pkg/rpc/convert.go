@@ -0,0 +1,37 @@
+package rpc
+
+import (
+	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/rpc/paymentpb"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toInvoice(invoice *dto.InvoiceResponse) *paymentpb.Invoice {
+	message := &paymentpb.Invoice{
+		Id:         uint64(invoice.ID),
+		MerchantId: uint64(invoice.MerchantID),
+		CustomerId: uint64(invoice.CustomerID),
+		Amount:     invoice.Amount.String(),
+		Currency:   invoice.Currency,
+		Status:     invoice.Status,
+		Mode:       invoice.Mode,
+	}
+	if invoice.CreatedAt != nil {
+		message.CreatedAt = timestamppb.New(*invoice.CreatedAt)
+	}
+	return message
+}
+
+func toPayment(payment *dto.ProcessPaymentResponse) *paymentpb.Payment {
+	return &paymentpb.Payment{
+		Id:            uint64(payment.ID),
+		InvoiceId:     uint64(payment.InvoiceID),
+		Amount:        payment.Amount.String(),
+		Status:        payment.PaymentStatus,
+		DeclineCode:   payment.DeclineCode,
+		Message:       payment.Message,
+		PaymentMethod: payment.PaymentMethod,
+		PaymentSource: payment.PaymentSource,
+	}
+}
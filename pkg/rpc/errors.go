@@ -0,0 +1,95 @@
+package rpc
+
+import (
+	"errors"
+	"go/payment-processor/pkg/apperror"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"gorm.io/gorm"
+)
+
+// errorDomain is the domain of the ErrorInfo details attached to errors
+const errorDomain = "payment-processor"
+
+// toStatus reports an error as a gRPC status carrying the error code as ErrorInfo, the invalid
+// fields as BadRequest and the retry delay as RetryInfo. Errors without a code are reported as
+// internal errors with the given message.
+func toStatus(msg string, err error) error {
+	var appErr *apperror.Error
+	switch {
+	case errors.As(err, &appErr):
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		appErr = apperror.ErrNotFound
+	default:
+		appErr = apperror.ErrInternal.WithMessage(msg)
+	}
+
+	st := status.New(grpcCode(appErr.Status), appErr.Message)
+	info := &errdetails.ErrorInfo{Reason: appErr.Code, Domain: errorDomain}
+	if appErr.DeclineCode != "" {
+		info.Metadata = map[string]string{"decline_code": appErr.DeclineCode}
+	}
+	details := []protoadapt.MessageV1{info}
+	if len(appErr.Fields) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, field := range appErr.Fields {
+			badRequest.FieldViolations = append(badRequest.FieldViolations,
+				&errdetails.BadRequest_FieldViolation{Field: field.Field, Description: field.Message})
+		}
+		details = append(details, badRequest)
+	}
+	if appErr.RetryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(appErr.RetryAfter)})
+	}
+
+	detailed, detailsErr := st.WithDetails(details...)
+	if detailsErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// grpcCode maps the HTTP status of an error to the closest gRPC code
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict, http.StatusUnprocessableEntity, http.StatusPaymentRequired:
+		return codes.FailedPrecondition
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	if httpStatus >= http.StatusInternalServerError {
+		return codes.Internal
+	}
+	return codes.InvalidArgument
+}
+
+// withRequestInfo attaches the request ID to an error as RequestInfo, so callers can quote it
+func withRequestInfo(err error, requestID string) error {
+	if err == nil {
+		return nil
+	}
+	withInfo, detailErr := status.Convert(err).WithDetails(&errdetails.RequestInfo{RequestId: requestID})
+	if detailErr != nil {
+		return err
+	}
+	return withInfo.Err()
+}
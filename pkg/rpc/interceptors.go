@@ -0,0 +1,204 @@
+package rpc
+
+import (
+	"context"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/logging"
+	"go/payment-processor/pkg/requestid"
+	"go/payment-processor/pkg/rpc/paymentpb"
+	services "go/payment-processor/pkg/service"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys, the gRPC counterparts of the HTTP headers
+const (
+	apiKeyMetadata        = "x-api-key"
+	authorizationMetadata = "authorization"
+	requestIDMetadata     = "x-request-id"
+)
+
+// apiKeyBearerPrefix tells merchant API keys apart from JWTs in the authorization metadata
+const apiKeyBearerPrefix = "sk_"
+
+// access is what a caller needs to call an RPC
+type access struct {
+	scope      string
+	permission auth.Permission
+}
+
+// methodAccess lists the scope and permission of every RPC. RPCs missing here are refused.
+var methodAccess = map[string]access{
+	paymentpb.InvoiceService_CreateInvoice_FullMethodName:            {auth.ScopeInvoicesWrite, auth.PermissionInvoicesWrite},
+	paymentpb.InvoiceService_GetInvoice_FullMethodName:               {auth.ScopeRead, auth.PermissionInvoicesRead},
+	paymentpb.InvoiceService_ListInvoices_FullMethodName:             {auth.ScopeRead, auth.PermissionInvoicesRead},
+	paymentpb.PaymentService_ProcessPayment_FullMethodName:           {auth.ScopePaymentsWrite, auth.PermissionPaymentsWrite},
+	paymentpb.PaymentService_CompletePaymentChallenge_FullMethodName: {auth.ScopePaymentsWrite, auth.PermissionPaymentsWrite},
+	paymentpb.PaymentService_GetPaymentStatus_FullMethodName:         {auth.ScopeRead, auth.PermissionInvoicesRead},
+	paymentpb.PaymentService_WatchPaymentStatus_FullMethodName:       {auth.ScopeRead, auth.PermissionInvoicesRead},
+}
+
+type interceptors struct {
+	log           *zap.Logger
+	apiKeyService services.APIKeyService
+	jwtValidator  *auth.JWTValidator
+}
+
+// logUnary tags the call with a request ID, returned as header metadata and in the details of
+// errors, and logs its outcome
+func (i *interceptors) logUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	ctx, requestID := withRequestID(ctx)
+	if err := grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, requestID)); err != nil {
+		logging.FromContext(ctx, i.log).Warn("Failed to set request ID header", zap.Error(err))
+	}
+	resp, err := handler(ctx, req)
+	i.logCall(ctx, info.FullMethod, start, err)
+	return resp, withRequestInfo(err, requestID)
+}
+
+func (i *interceptors) logStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx, requestID := withRequestID(ss.Context())
+	if err := ss.SetHeader(metadata.Pairs(requestIDMetadata, requestID)); err != nil {
+		logging.FromContext(ctx, i.log).Warn("Failed to set request ID header", zap.Error(err))
+	}
+	err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	i.logCall(ctx, info.FullMethod, start, err)
+	return withRequestInfo(err, requestID)
+}
+
+func (i *interceptors) logCall(ctx context.Context, method string, start time.Time, err error) {
+	log := logging.FromContext(ctx, i.log)
+	code := status.Code(err)
+	fields := []zap.Field{zap.String("method", method), zap.String("code", code.String()),
+		zap.Duration("duration", time.Since(start))}
+	switch code {
+	case codes.OK:
+		log.Info("gRPC call", fields...)
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		log.Error("gRPC call failed", append(fields, zap.Error(err))...)
+	default:
+		log.Info("gRPC call rejected", append(fields, zap.Error(err))...)
+	}
+}
+
+// recoverUnary reports a panic as an internal error instead of crashing the server
+func (i *interceptors) recoverUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logging.FromContext(ctx, i.log).Error("Panic in gRPC call", zap.String("method", info.FullMethod), zap.Any("panic", r), zap.Stack("stack"))
+			err = status.Error(codes.Internal, apperror.ErrInternal.Message)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func (i *interceptors) recoverStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logging.FromContext(ss.Context(), i.log).Error("Panic in gRPC stream", zap.String("method", info.FullMethod), zap.Any("panic", r), zap.Stack("stack"))
+			err = status.Error(codes.Internal, apperror.ErrInternal.Message)
+		}
+	}()
+	return handler(srv, ss)
+}
+
+func (i *interceptors) authenticateUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := i.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (i *interceptors) authenticateStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := i.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate resolves the caller like the REST API does and checks that it may call the method.
+// The principal is stored in the returned context, and its merchant added to the logs of the call.
+func (i *interceptors) authenticate(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	rawKey := firstValue(md, apiKeyMetadata)
+	var bearer string
+	if authorization := firstValue(md, authorizationMetadata); rawKey == "" && authorization != "" {
+		scheme, token, found := strings.Cut(authorization, " ")
+		if found && strings.EqualFold(scheme, "Bearer") {
+			bearer = strings.TrimSpace(token)
+		}
+	}
+	if strings.HasPrefix(bearer, apiKeyBearerPrefix) {
+		rawKey, bearer = bearer, ""
+	}
+
+	var principal *auth.Principal
+	var err error
+	switch {
+	case rawKey != "":
+		if principal, err = i.apiKeyService.Authenticate(ctx, rawKey); err != nil {
+			logging.FromContext(ctx, i.log).Warn("API key authentication failed", zap.Error(err))
+			return nil, toStatus("", apperror.ErrUnauthorized.WithMessage("Invalid API key"))
+		}
+	case bearer != "" && i.jwtValidator != nil:
+		if principal, err = i.jwtValidator.Validate(bearer); err != nil {
+			logging.FromContext(ctx, i.log).Warn("Bearer token validation failed", zap.Error(err))
+			return nil, toStatus("", apperror.ErrUnauthorized.WithMessage("Invalid bearer token"))
+		}
+	default:
+		return nil, toStatus("", apperror.ErrUnauthorized.WithMessage("Missing credentials"))
+	}
+
+	required, ok := methodAccess[method]
+	switch {
+	case !ok:
+		return nil, toStatus("", apperror.ErrForbidden.WithMessage("Method not available"))
+	case !principal.HasAnyScope(required.scope):
+		return nil, toStatus("", apperror.ErrForbidden.WithMessage("Insufficient scope"))
+	case !principal.Can(required.permission):
+		return nil, toStatus("", apperror.ErrForbidden.WithMessage("Permission denied"))
+	}
+	if principal.MerchantID != 0 {
+		ctx = logging.With(ctx, zap.Uint("merchant_id", principal.MerchantID))
+	}
+	return auth.ContextWithPrincipal(ctx, principal), nil
+}
+
+// withRequestID carries the request ID sent by the caller, or a new one when it sent none or an
+// unusable one, in the context and in the logs of the call
+func withRequestID(ctx context.Context) (context.Context, string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	id := firstValue(md, requestIDMetadata)
+	if !requestid.Valid(id) {
+		id = requestid.New()
+	}
+	return logging.With(requestid.NewContext(ctx, id), zap.String("request_id", id)), id
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// contextStream replaces the context of a server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
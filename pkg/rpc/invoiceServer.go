@@ -0,0 +1,92 @@
+package rpc
+
+import (
+	"context"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/logging"
+	"go/payment-processor/pkg/rpc/paymentpb"
+	services "go/payment-processor/pkg/service"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+type invoiceServer struct {
+	paymentpb.UnimplementedInvoiceServiceServer
+	log            *zap.Logger
+	validator      *validator.Validate
+	invoiceService services.InvoiceService
+}
+
+func (s *invoiceServer) CreateInvoice(ctx context.Context, req *paymentpb.CreateInvoiceRequest) (*paymentpb.Invoice, error) {
+	principal := auth.PrincipalFromContext(ctx)
+	createReq := dto.CreateInvoiceRequest{MerchantID: uint(req.GetMerchantId()), CustomerID: uint(req.GetCustomerId()),
+		Amount: req.GetAmount(), Currency: req.GetCurrency(), OptionalDescription: req.GetDescription(), Mode: principal.Mode}
+	if createReq.MerchantID == 0 {
+		createReq.MerchantID = principal.MerchantID
+	}
+	if !principal.ActsForAnyMerchant() && createReq.MerchantID != principal.MerchantID {
+		logging.FromContext(ctx, s.log).Warn("Merchant attempted to invoice on behalf of another merchant",
+			zap.Uint("merchant_id", principal.MerchantID), zap.Uint("requested_merchant_id", createReq.MerchantID))
+		return nil, toStatus("", apperror.ErrForbidden.WithMessage("Cannot create invoices for another merchant"))
+	}
+	if err := s.validator.Struct(createReq); err != nil {
+		return nil, toStatus("", apperror.Validation(err))
+	}
+
+	invoice, err := s.invoiceService.CreateInvoice(ctx, &createReq)
+	if err != nil {
+		logging.FromContext(ctx, s.log).Error("Failed to create invoice", zap.Error(err))
+		return nil, toStatus("Failed to create invoice", err)
+	}
+	return toInvoice(invoice), nil
+}
+
+func (s *invoiceServer) GetInvoice(ctx context.Context, req *paymentpb.GetInvoiceRequest) (*paymentpb.Invoice, error) {
+	invoice, err := s.invoiceService.GetInvoiceByID(ctx, uint(req.GetId()))
+	if err != nil || !ownsInvoice(ctx, invoice) {
+		return nil, toStatus("", services.ErrInvoiceNotFound)
+	}
+	return toInvoice(invoice), nil
+}
+
+func (s *invoiceServer) ListInvoices(ctx context.Context, req *paymentpb.ListInvoicesRequest) (*paymentpb.ListInvoicesResponse, error) {
+	listReq := dto.ListInvoicesRequest{MerchantID: uint(req.GetMerchantId()), CustomerID: uint(req.GetCustomerId()),
+		Status: req.GetStatus(), Currency: req.GetCurrency(), MinAmount: req.MinAmount, MaxAmount: req.MaxAmount,
+		CreatedFrom: req.GetCreatedFrom(), CreatedTo: req.GetCreatedTo(), Sort: req.GetSort(), Order: req.GetOrder(),
+		Limit: int(req.GetLimit()), Cursor: req.GetCursor()}
+
+	principal := auth.PrincipalFromContext(ctx)
+	if !principal.ActsForAnyMerchant() {
+		if listReq.MerchantID != 0 && listReq.MerchantID != principal.MerchantID {
+			return nil, toStatus("", apperror.ErrForbidden.WithMessage("Cannot list invoices of another merchant"))
+		}
+		listReq.MerchantID = principal.MerchantID
+	}
+	listReq.Mode = principal.Mode
+	if err := s.validator.Struct(listReq); err != nil {
+		return nil, toStatus("", apperror.Validation(err))
+	}
+
+	page, err := s.invoiceService.ListInvoices(ctx, &listReq)
+	if err != nil {
+		logging.FromContext(ctx, s.log).Error("Failed to list invoices", zap.Error(err))
+		return nil, toStatus("Failed to list invoices", err)
+	}
+	response := &paymentpb.ListInvoicesResponse{NextCursor: page.NextCursor}
+	for _, invoice := range page.Data {
+		response.Invoices = append(response.Invoices, toInvoice(invoice))
+	}
+	return response, nil
+}
+
+// ownsInvoice reports whether the caller may see the invoice, with the rules of the REST API
+func ownsInvoice(ctx context.Context, invoice *dto.InvoiceResponse) bool {
+	principal := auth.PrincipalFromContext(ctx)
+	if principal == nil {
+		return false
+	}
+	return principal.ActsForAnyMerchant() || (principal.MerchantID == invoice.MerchantID && principal.Mode == invoice.Mode)
+}
@@ -0,0 +1,153 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/logging"
+	"go/payment-processor/pkg/mapper"
+	"go/payment-processor/pkg/rpc/paymentpb"
+	services "go/payment-processor/pkg/service"
+	"go/payment-processor/pkg/utils"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+type paymentServer struct {
+	paymentpb.UnimplementedPaymentServiceServer
+	log            *zap.Logger
+	validator      *validator.Validate
+	invoiceService services.InvoiceService
+	paymentService services.PaymentService
+	watchInterval  time.Duration
+	stopping       <-chan struct{}
+}
+
+func (s *paymentServer) ProcessPayment(ctx context.Context, req *paymentpb.ProcessPaymentRequest) (*paymentpb.Payment, error) {
+	paymentReq := dto.ProcessPaymentRequest{InvoiceID: uint(req.GetInvoiceId()), PaymentMethod: req.GetPaymentMethod(),
+		PaymentSource: req.GetPaymentSource(), PaymentMethodID: uint(req.GetPaymentMethodId()),
+		BillingCountry: req.GetBillingCountry(), ClientIP: peerIP(ctx)}
+	if err := s.validator.Struct(paymentReq); err != nil {
+		return nil, toStatus("", apperror.Validation(err))
+	}
+	if err := s.checkInvoice(ctx, paymentReq.InvoiceID); err != nil {
+		return nil, err
+	}
+
+	payment, err := s.paymentService.ProcessPayment(ctx, &paymentReq)
+	if err != nil {
+		logging.FromContext(ctx, s.log).Error("Failed to process payment", zap.Error(err))
+		return nil, toStatus("Failed to process payment", err)
+	}
+	return toPayment(mapper.ToPaymentResponse(payment)), nil
+}
+
+func (s *paymentServer) CompletePaymentChallenge(ctx context.Context, req *paymentpb.CompletePaymentChallengeRequest) (*paymentpb.Payment, error) {
+	challengeReq := dto.CompleteChallengeRequest{InvoiceID: uint(req.GetInvoiceId()), PaymentID: uint(req.GetPaymentId()),
+		Result: req.GetResult()}
+	if err := s.validator.Struct(challengeReq); err != nil {
+		return nil, toStatus("", apperror.Validation(err))
+	}
+	if err := s.checkInvoice(ctx, challengeReq.InvoiceID); err != nil {
+		return nil, err
+	}
+
+	payment, err := s.paymentService.CompletePaymentChallenge(ctx, &challengeReq)
+	if err != nil {
+		logging.FromContext(ctx, s.log).Error("Failed to complete payment challenge", zap.Error(err))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, toStatus("", apperror.ErrNotFound.WithMessage("Payment not found"))
+		}
+		return nil, toStatus("Failed to complete payment challenge", err)
+	}
+	return toPayment(mapper.ToPaymentResponse(payment)), nil
+}
+
+func (s *paymentServer) GetPaymentStatus(ctx context.Context, req *paymentpb.GetPaymentStatusRequest) (*paymentpb.PaymentStatus, error) {
+	invoiceID := uint(req.GetInvoiceId())
+	if err := s.checkInvoice(ctx, invoiceID); err != nil {
+		return nil, err
+	}
+
+	paymentStatus, err := s.paymentService.GetPaymentStatus(ctx, invoiceID)
+	if err != nil {
+		logging.FromContext(ctx, s.log).Error("Payment status not found", zap.Error(err))
+		return nil, toStatus("", apperror.ErrNotFound.WithMessage("Payment status not found"))
+	}
+	return &paymentpb.PaymentStatus{InvoiceId: req.GetInvoiceId(), Status: paymentStatus, Final: finalPaymentStatus(paymentStatus)}, nil
+}
+
+// WatchPaymentStatus polls the payment status of the invoice and sends every change. Polling the
+// stored status keeps the stream correct whichever instance processes the payment. Until the
+// invoice has a payment nothing is sent.
+func (s *paymentServer) WatchPaymentStatus(req *paymentpb.WatchPaymentStatusRequest, stream paymentpb.PaymentService_WatchPaymentStatusServer) error {
+	ctx := stream.Context()
+	invoiceID := uint(req.GetInvoiceId())
+	if err := s.checkInvoice(ctx, invoiceID); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(s.watchInterval)
+	defer ticker.Stop()
+	var last string
+	for {
+		if current, err := s.paymentService.GetPaymentStatus(ctx, invoiceID); err == nil && current != last {
+			final := finalPaymentStatus(current)
+			if err := stream.Send(&paymentpb.PaymentStatus{InvoiceId: req.GetInvoiceId(), Status: current, Final: final}); err != nil {
+				return err
+			}
+			if final {
+				return nil
+			}
+			last = current
+		}
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-s.stopping:
+			// Clients watch again on another instance
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkInvoice reports invoices the caller may not see as not found
+func (s *paymentServer) checkInvoice(ctx context.Context, invoiceID uint) error {
+	invoice, err := s.invoiceService.GetInvoiceByID(ctx, invoiceID)
+	if err != nil || !ownsInvoice(ctx, invoice) {
+		logging.FromContext(ctx, s.log).Error("Invoice not found", zap.Uint("invoice_id", invoiceID), zap.Error(err))
+		return toStatus("", services.ErrInvoiceNotFound)
+	}
+	return nil
+}
+
+// finalPaymentStatus reports whether a payment with the status is done: paid, declined or stopped
+// by the risk engine. Payments waiting for a challenge or a review still change.
+func finalPaymentStatus(paymentStatus string) bool {
+	return paymentStatus == utils.PaymentStatusSuccess || paymentStatus == utils.PaymentStatusBlocked ||
+		paymentStatus == utils.PaymentStatusRejected || slices.Contains(utils.PaymentStatusesDeclined, paymentStatus)
+}
+
+// peerIP is the address of the caller, used like the client IP of REST requests
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
@@ -0,0 +1,50 @@
+// Package rpc serves the invoice and payment operations over gRPC for internal callers, on top of
+// the same services as the REST handlers.
+package rpc
+
+//go:generate buf generate
+
+import (
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/rpc/paymentpb"
+	services "go/payment-processor/pkg/service"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// defaultWatchInterval is how often WatchPaymentStatus checks for a new status
+const defaultWatchInterval = time.Second
+
+// Dependencies are the collaborators of the gRPC server
+type Dependencies struct {
+	Invoices      services.InvoiceService
+	Payments      services.PaymentService
+	APIKeys       services.APIKeyService
+	JWTValidator  *auth.JWTValidator // nil disables bearer tokens for internal services
+	WatchInterval time.Duration      // zero uses defaultWatchInterval
+	// Stopping is closed when the server shuts down, which ends open WatchPaymentStatus streams so
+	// they do not hold up a graceful stop. nil keeps them open.
+	Stopping <-chan struct{}
+}
+
+// NewServer returns a gRPC server with the invoice and payment services registered. Every call is
+// logged, authenticated and authorized, and panics are reported as internal errors.
+func NewServer(logger *zap.Logger, validate *validator.Validate, deps Dependencies, opts ...grpc.ServerOption) *grpc.Server {
+	if deps.WatchInterval <= 0 {
+		deps.WatchInterval = defaultWatchInterval
+	}
+	i := &interceptors{log: logger, apiKeyService: deps.APIKeys, jwtValidator: deps.JWTValidator}
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(i.logUnary, i.recoverUnary, i.authenticateUnary),
+		grpc.ChainStreamInterceptor(i.logStream, i.recoverStream, i.authenticateStream),
+	)
+
+	server := grpc.NewServer(opts...)
+	paymentpb.RegisterInvoiceServiceServer(server, &invoiceServer{log: logger, validator: validate, invoiceService: deps.Invoices})
+	paymentpb.RegisterPaymentServiceServer(server, &paymentServer{log: logger, validator: validate,
+		invoiceService: deps.Invoices, paymentService: deps.Payments, watchInterval: deps.WatchInterval, stopping: deps.Stopping})
+	return server
+}
@@ -0,0 +1,195 @@
+package rpc
+
+import (
+	"context"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/rpc/paymentpb"
+	services "go/payment-processor/pkg/service"
+	"go/payment-processor/pkg/utils"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testAPIKey = "sk_test_rpc"
+
+// The fakes embed the service interfaces and only implement what the tests call
+type fakeAPIKeyService struct{ services.APIKeyService }
+
+func (fakeAPIKeyService) Authenticate(_ context.Context, rawKey string) (*auth.Principal, error) {
+	if rawKey != testAPIKey {
+		return nil, services.ErrInvalidAPIKey
+	}
+	return &auth.Principal{MerchantID: 1, APIKeyID: 1, Mode: utils.APIKeyModeTest, Scopes: []string{auth.ScopeRead, auth.ScopePaymentsWrite},
+		Roles: []string{auth.RoleMerchant}}, nil
+}
+
+type fakeInvoiceService struct{ services.InvoiceService }
+
+// GetInvoiceByID returns invoices of merchant 1, except invoice 2 which belongs to merchant 2
+func (fakeInvoiceService) GetInvoiceByID(_ context.Context, id uint) (*dto.InvoiceResponse, error) {
+	if id == 99 {
+		panic("boom")
+	}
+	merchantID := uint(1)
+	if id == 2 {
+		merchantID = 2
+	}
+	now := time.Now()
+	return &dto.InvoiceResponse{ID: id, MerchantID: merchantID, CustomerID: 3, Amount: decimal.RequireFromString("25.50"),
+		Currency: "USD", Status: utils.InvoiceStatusPending, Mode: utils.APIKeyModeTest, CreatedAt: &now}, nil
+}
+
+type fakePaymentService struct {
+	services.PaymentService
+	statusCalls atomic.Int32
+}
+
+// ProcessPayment declines cards ending in 3434, like the simulated provider
+func (*fakePaymentService) ProcessPayment(_ context.Context, req *dto.ProcessPaymentRequest) (*entities.Payment, error) {
+	status := utils.PaymentStatusSuccess
+	if strings.HasSuffix(req.PaymentSource, "3434") {
+		status = utils.PaymentStatusDeclined
+	}
+	return &entities.Payment{InvoiceID: req.InvoiceID, Amount: decimal.NewFromInt(25), PaymentStatus: status,
+		PaymentMethod: req.PaymentMethod, PaymentSource: req.PaymentSource}, nil
+}
+
+// GetPaymentStatus walks through the statuses of a payment that needs a 3-D Secure challenge
+func (s *fakePaymentService) GetPaymentStatus(context.Context, uint) (string, error) {
+	switch s.statusCalls.Add(1) {
+	case 1:
+		return "", io.EOF // no payment yet
+	case 2, 3:
+		return utils.PaymentStatusRequiresAction, nil
+	}
+	return utils.PaymentStatusSuccess, nil
+}
+
+func newTestClients(t *testing.T) (paymentpb.InvoiceServiceClient, paymentpb.PaymentServiceClient) {
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(zap.NewNop(), validator.New(), Dependencies{Invoices: fakeInvoiceService{}, Payments: &fakePaymentService{},
+		APIKeys: fakeAPIKeyService{}, WatchInterval: time.Millisecond})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return paymentpb.NewInvoiceServiceClient(conn), paymentpb.NewPaymentServiceClient(conn)
+}
+
+func withAPIKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), apiKeyMetadata, key)
+}
+
+func errorReason(err error) string {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+	return ""
+}
+
+func errorRequestID(err error) string {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RequestInfo); ok {
+			return info.RequestId
+		}
+	}
+	return ""
+}
+
+func TestUnaryCalls(t *testing.T) {
+	invoices, payments := newTestClients(t)
+	ctx := withAPIKey(testAPIKey)
+
+	var header metadata.MD
+	invoice, err := invoices.GetInvoice(ctx, &paymentpb.GetInvoiceRequest{Id: 7}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), invoice.Id)
+	assert.Equal(t, "25.5", invoice.Amount)
+	assert.NotEmpty(t, header.Get(requestIDMetadata))
+
+	payment, err := payments.ProcessPayment(ctx, &paymentpb.ProcessPaymentRequest{InvoiceId: 7, PaymentMethod: "credit_card",
+		PaymentSource: "4242424242423434"})
+	assert.NoError(t, err)
+	assert.Equal(t, utils.PaymentStatusDeclined, payment.Status)
+	assert.Equal(t, utils.DeclineCodeGeneric, payment.DeclineCode)
+	assert.Equal(t, "************3434", payment.PaymentSource)
+}
+
+func TestErrors(t *testing.T) {
+	invoices, payments := newTestClients(t)
+
+	var header metadata.MD
+	_, err := invoices.GetInvoice(context.Background(), &paymentpb.GetInvoiceRequest{Id: 7}, grpc.Header(&header))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Equal(t, header.Get(requestIDMetadata), []string{errorRequestID(err)})
+
+	// The request ID sent by the caller is kept
+	ctx := metadata.AppendToOutgoingContext(context.Background(), requestIDMetadata, "req-01HZX")
+	_, err = invoices.GetInvoice(ctx, &paymentpb.GetInvoiceRequest{Id: 7})
+	assert.Equal(t, "req-01HZX", errorRequestID(err))
+
+	// Invoices of other merchants do not exist for the caller
+	_, err = invoices.GetInvoice(withAPIKey(testAPIKey), &paymentpb.GetInvoiceRequest{Id: 2})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, "invoice_not_found", errorReason(err))
+
+	// The key lacks the invoices:write scope
+	_, err = invoices.CreateInvoice(withAPIKey(testAPIKey), &paymentpb.CreateInvoiceRequest{CustomerId: 3, Amount: 10, Currency: "USD"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = payments.ProcessPayment(withAPIKey(testAPIKey), &paymentpb.ProcessPaymentRequest{InvoiceId: 7,
+		PaymentMethod: "credit_card", PaymentSource: "4242", BillingCountry: "usa"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, "validation_failed", errorReason(err))
+
+	// Panics are recovered and reported as internal errors
+	_, err = invoices.GetInvoice(withAPIKey(testAPIKey), &paymentpb.GetInvoiceRequest{Id: 99})
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestWatchPaymentStatus(t *testing.T) {
+	_, payments := newTestClients(t)
+	ctx, cancel := context.WithTimeout(withAPIKey(testAPIKey), 5*time.Second)
+	defer cancel()
+
+	stream, err := payments.WatchPaymentStatus(ctx, &paymentpb.WatchPaymentStatusRequest{InvoiceId: 7})
+	assert.NoError(t, err)
+
+	var received []string
+	for {
+		update, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			return
+		}
+		received = append(received, update.Status)
+		assert.Equal(t, update.Status == utils.PaymentStatusSuccess, update.Final)
+	}
+	assert.Equal(t, []string{utils.PaymentStatusRequiresAction, utils.PaymentStatusSuccess}, received)
+}
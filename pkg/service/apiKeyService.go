@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/logging"
+	"go/payment-processor/pkg/mapper"
+	"go/payment-processor/pkg/repository"
+	"go/payment-processor/pkg/utils"
+	"net/http"
+	"strings"
+)
+
+// API keys look like sk_<mode>_<8 hex id>_<64 hex secret>. Everything up to the id is the
+// prefix, which is stored in clear to find the key; the full key is only stored hashed.
+const (
+	apiKeyScheme      = "sk"
+	apiKeyIDBytes     = 4
+	apiKeySecretBytes = 32
+)
+
+var (
+	ErrInvalidAPIKey = apperror.ErrUnauthorized.WithMessage("Invalid API key")
+	// A key only manages keys of its own mode, so a leaked test key cannot touch live ones
+	ErrAPIKeyModeMismatch = apperror.New(http.StatusForbidden, "api_key_mode_mismatch", "API keys can only manage keys of their own mode")
+)
+
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, apiKeyRequest *dto.CreateAPIKeyRequest) (*dto.APIKeyResponse, error)
+	ListAPIKeys(ctx context.Context, merchantID uint) ([]*dto.APIKeyResponse, error)
+	RevokeAPIKey(ctx context.Context, merchantID, apiKeyID uint, mode string) error
+	Authenticate(ctx context.Context, rawKey string) (*auth.Principal, error)
+}
+
+type apiKeyService struct {
+	log       *zap.Logger
+	repo      repository.Repository
+	validator *validator.Validate
+}
+
+func NewAPIKeyService(log *zap.Logger, repo repository.Repository, validator *validator.Validate) APIKeyService {
+	return &apiKeyService{log: log,
+		repo: repo, validator: validator}
+}
+
+// CreateAPIKey issues a new key for the merchant. The returned response is the only place the full key appears.
+func (as *apiKeyService) CreateAPIKey(ctx context.Context, apiKeyRequest *dto.CreateAPIKeyRequest) (*dto.APIKeyResponse, error) {
+	log := logging.FromContext(ctx, as.log)
+	log.Info("Creating API key", zap.Uint("merchant_id", apiKeyRequest.MerchantID), zap.String("mode", apiKeyRequest.Mode))
+
+	err := authorize(ctx, auth.PermissionAPIKeysManage)
+	if err != nil {
+		log.Warn("Not permitted to create API keys", zap.Error(err))
+		return nil, err
+	}
+
+	merchant, err := as.repo.GetMerchantByID(ctx, apiKeyRequest.MerchantID)
+	if err != nil {
+		log.Error("Merchant not found", zap.Uint("merchant_id", apiKeyRequest.MerchantID), zap.Error(err))
+		return nil, err
+	}
+
+	id := make([]byte, apiKeyIDBytes)
+	secret := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	prefix := strings.Join([]string{apiKeyScheme, apiKeyRequest.Mode, hex.EncodeToString(id)}, "_")
+	rawKey := prefix + "_" + hex.EncodeToString(secret)
+
+	apiKey := &entities.ApiKey{
+		MerchantID: merchant.ID,
+		Name:       apiKeyRequest.Name,
+		Prefix:     prefix,
+		KeyHash:    hashAPIKey(rawKey),
+		Mode:       apiKeyRequest.Mode,
+	}
+	apiKey.IsActive = true
+
+	createdKey, err := as.repo.CreateAPIKey(ctx, apiKey)
+	if err != nil {
+		log.Error("Failed to create API key", zap.Error(err))
+		return nil, err
+	}
+
+	log.Info("API key created", zap.Uint("merchant_id", merchant.ID), zap.String("prefix", prefix))
+	response := mapper.ToAPIKeyResponse(createdKey)
+	response.Key = rawKey
+	return response, nil
+}
+
+func (as *apiKeyService) ListAPIKeys(ctx context.Context, merchantID uint) ([]*dto.APIKeyResponse, error) {
+	log := logging.FromContext(ctx, as.log)
+	apiKeys, err := as.repo.GetAPIKeysByMerchant(ctx, merchantID)
+	if err != nil {
+		log.Error("Failed to list API keys", zap.Uint("merchant_id", merchantID), zap.Error(err))
+		return nil, err
+	}
+
+	responses := make([]*dto.APIKeyResponse, 0, len(apiKeys))
+	for i := range apiKeys {
+		responses = append(responses, mapper.ToAPIKeyResponse(&apiKeys[i]))
+	}
+	return responses, nil
+}
+
+// RevokeAPIKey revokes one of the merchant's keys of the given mode; keys of the other mode fail
+// with ErrAPIKeyModeMismatch
+func (as *apiKeyService) RevokeAPIKey(ctx context.Context, merchantID, apiKeyID uint, mode string) error {
+	log := logging.FromContext(ctx, as.log)
+	log.Info("Revoking API key", zap.Uint("merchant_id", merchantID), zap.Uint("api_key_id", apiKeyID))
+
+	err := authorize(ctx, auth.PermissionAPIKeysManage)
+	if err != nil {
+		log.Warn("Not permitted to revoke API keys", zap.Error(err))
+		return err
+	}
+
+	apiKey, err := as.repo.GetAPIKeyByID(ctx, merchantID, apiKeyID)
+	if err != nil {
+		log.Error("API key not found", zap.Uint("api_key_id", apiKeyID), zap.Error(err))
+		return err
+	}
+	if apiKey.Mode != mode {
+		log.Warn("Refusing to revoke an API key of another mode", zap.Uint("api_key_id", apiKeyID),
+			zap.String("mode", mode), zap.String("key_mode", apiKey.Mode))
+		return ErrAPIKeyModeMismatch
+	}
+
+	if err := as.repo.RevokeAPIKey(ctx, merchantID, apiKeyID); err != nil {
+		log.Error("Failed to revoke API key", zap.Uint("api_key_id", apiKeyID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Authenticate resolves a raw key to the merchant it belongs to. Unknown, malformed and revoked keys,
+// as well as keys of deleted or suspended merchants, all fail with ErrInvalidAPIKey.
+func (as *apiKeyService) Authenticate(ctx context.Context, rawKey string) (*auth.Principal, error) {
+	log := logging.FromContext(ctx, as.log)
+	prefix, ok := apiKeyPrefix(rawKey)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := as.repo.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		log.Warn("Unknown API key", zap.String("prefix", prefix), zap.Error(err))
+		return nil, ErrInvalidAPIKey
+	}
+	if subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(hashAPIKey(rawKey))) != 1 {
+		log.Warn("API key secret mismatch", zap.String("prefix", prefix))
+		return nil, ErrInvalidAPIKey
+	}
+	if apiKey.RevokedAt != nil {
+		log.Warn("Revoked API key used", zap.String("prefix", prefix))
+		return nil, ErrInvalidAPIKey
+	}
+
+	merchant, err := as.repo.GetMerchantByID(ctx, apiKey.MerchantID)
+	if err != nil || merchant.MerchantStatus == utils.MerchantStatusSuspended {
+		log.Warn("API key belongs to an inactive merchant", zap.String("prefix", prefix))
+		return nil, ErrInvalidAPIKey
+	}
+
+	if err := as.repo.TouchAPIKey(ctx, apiKey.ID); err != nil {
+		log.Warn("Failed to record API key usage", zap.String("prefix", prefix), zap.Error(err))
+	}
+
+	return &auth.Principal{MerchantID: apiKey.MerchantID, APIKeyID: apiKey.ID, Mode: apiKey.Mode,
+		Scopes: auth.MerchantScopes, Roles: []string{auth.RoleMerchant}}, nil
+}
+
+// apiKeyPrefix extracts sk_<mode>_<id> from a raw key
+func apiKeyPrefix(rawKey string) (string, bool) {
+	parts := strings.Split(rawKey, "_")
+	if len(parts) != 4 || parts[0] != apiKeyScheme ||
+		(parts[1] != utils.APIKeyModeTest && parts[1] != utils.APIKeyModeLive) ||
+		len(parts[2]) != 2*apiKeyIDBytes || len(parts[3]) != 2*apiKeySecretBytes {
+		return "", false
+	}
+	return strings.Join(parts[:3], "_"), true
+}
+
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
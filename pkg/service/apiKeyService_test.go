@@ -0,0 +1,37 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyPrefix(t *testing.T) {
+	secret := strings.Repeat("ab", apiKeySecretBytes)
+
+	prefix, ok := apiKeyPrefix("sk_live_0123abcd_" + secret)
+	assert.True(t, ok)
+	assert.Equal(t, "sk_live_0123abcd", prefix)
+
+	prefix, ok = apiKeyPrefix("sk_test_0123abcd_" + secret)
+	assert.True(t, ok)
+	assert.Equal(t, "sk_test_0123abcd", prefix)
+}
+
+func TestAPIKeyPrefixRejectsMalformedKeys(t *testing.T) {
+	secret := strings.Repeat("ab", apiKeySecretBytes)
+
+	for _, key := range []string{
+		"",
+		"sk_live_0123abcd",
+		"pk_live_0123abcd_" + secret,
+		"sk_prod_0123abcd_" + secret,
+		"sk_live_0123_" + secret,
+		"sk_live_0123abcd_short",
+		"sk_live_0123abcd_" + secret + "_extra",
+	} {
+		_, ok := apiKeyPrefix(key)
+		assert.False(t, ok, key)
+	}
+}
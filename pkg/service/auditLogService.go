@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"go/payment-processor/pkg/audit"
+	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/logging"
+	"go/payment-processor/pkg/mapper"
+	"go/payment-processor/pkg/repository"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultAuditLogPageSize applies when a listing does not ask for a page size
+	defaultAuditLogPageSize = 50
+	// auditLogVerifyBatchSize is how many entries are loaded at a time while verifying the chain
+	auditLogVerifyBatchSize = 500
+)
+
+type AuditLogService interface {
+	ListAuditLogs(ctx context.Context, listRequest *dto.ListAuditLogsRequest) (*dto.ListAuditLogsResponse, error)
+	VerifyAuditLog(ctx context.Context) (*dto.AuditLogVerificationResponse, error)
+}
+
+type auditLogService struct {
+	log       *zap.Logger
+	repo      repository.Repository
+	validator *validator.Validate
+}
+
+func NewAuditLogService(log *zap.Logger, repo repository.Repository, validator *validator.Validate) AuditLogService {
+	return &auditLogService{log: log,
+		repo: repo, validator: validator}
+}
+
+// ListAuditLogs returns one page of audit log entries, newest first. The cursor is the ID of the
+// last entry of the previous page.
+func (as *auditLogService) ListAuditLogs(ctx context.Context, listRequest *dto.ListAuditLogsRequest) (*dto.ListAuditLogsResponse, error) {
+	log := logging.FromContext(ctx, as.log)
+	log.Info("Listing audit logs", zap.Any("filters", listRequest))
+
+	query := repository.AuditLogQuery{
+		EntityType: listRequest.EntityType,
+		EntityID:   listRequest.EntityID,
+		Actor:      listRequest.Actor,
+		RequestID:  listRequest.RequestID,
+		Limit:      listRequest.Limit,
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultAuditLogPageSize
+	}
+	if listRequest.CreatedFrom != "" {
+		createdFrom, err := time.Parse(time.RFC3339, listRequest.CreatedFrom)
+		if err != nil {
+			return nil, err
+		}
+		query.CreatedFrom = &createdFrom
+	}
+	if listRequest.CreatedTo != "" {
+		createdTo, err := time.Parse(time.RFC3339, listRequest.CreatedTo)
+		if err != nil {
+			return nil, err
+		}
+		query.CreatedTo = &createdTo
+	}
+	if listRequest.Cursor != "" {
+		beforeID, err := strconv.ParseUint(listRequest.Cursor, 10, 64)
+		if err != nil || beforeID == 0 {
+			return nil, ErrInvalidCursor
+		}
+		query.BeforeID = uint(beforeID)
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	pageSize := query.Limit
+	query.Limit++
+	logs, err := as.repo.ListAuditLogs(ctx, query)
+	if err != nil {
+		log.Error("Failed to list audit logs", zap.Error(err))
+		return nil, err
+	}
+
+	response := &dto.ListAuditLogsResponse{Data: make([]*dto.AuditLogResponse, 0, pageSize)}
+	if len(logs) > pageSize {
+		logs = logs[:pageSize]
+		response.NextCursor = strconv.FormatUint(uint64(logs[pageSize-1].ID), 10)
+	}
+	for i := range logs {
+		response.Data = append(response.Data, mapper.ToAuditLogResponse(&logs[i]))
+	}
+	return response, nil
+}
+
+// VerifyAuditLog recomputes the whole hash chain and reports the first entry that does not match
+func (as *auditLogService) VerifyAuditLog(ctx context.Context) (*dto.AuditLogVerificationResponse, error) {
+	log := logging.FromContext(ctx, as.log)
+	log.Info("Verifying audit log hash chain")
+
+	response := &dto.AuditLogVerificationResponse{Valid: true}
+	var afterID uint
+	for {
+		logs, err := as.repo.GetAuditLogsAfter(ctx, afterID, auditLogVerifyBatchSize)
+		if err != nil {
+			log.Error("Failed to load audit logs", zap.Error(err))
+			return nil, err
+		}
+		if len(logs) == 0 {
+			break
+		}
+
+		lastHash, broken := audit.Verify(response.LastHash, logs)
+		if broken != nil {
+			log.Error("Audit log hash chain is broken", zap.Uint("audit_log_id", broken.ID))
+			response.Valid = false
+			response.BrokenAt = &broken.ID
+			for i := range logs {
+				if logs[i].ID == broken.ID {
+					response.Checked += i
+				}
+			}
+			return response, nil
+		}
+		response.LastHash = lastHash
+		response.Checked += len(logs)
+		afterID = logs[len(logs)-1].ID
+	}
+
+	log.Info("Audit log hash chain verified", zap.Int("checked", response.Checked))
+	return response, nil
+}
@@ -0,0 +1,19 @@
+package services
+
+import (
+	"context"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/auth"
+	"net/http"
+)
+
+var ErrPermissionDenied = apperror.New(http.StatusForbidden, "permission_denied", "permission denied")
+
+// authorize checks that the principal carried by ctx holds the permission. Repository writes made
+// with ctx record the principal as their author.
+func authorize(ctx context.Context, permission auth.Permission) error {
+	if !auth.PrincipalFromContext(ctx).Can(permission) {
+		return ErrPermissionDenied
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/logging"
+	"go/payment-processor/pkg/mapper"
+	"go/payment-processor/pkg/repository"
+	"go/payment-processor/pkg/utils"
+	"go/payment-processor/pkg/vault"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// defaultCustomerSearchLimit applies when a search does not ask for a page size
+const defaultCustomerSearchLimit = 20
+
+var (
+	ErrCustomerEmailTaken         = apperror.New(http.StatusConflict, "customer_email_taken", "customer email is already in use")
+	ErrPaymentMethodsUnavailable  = apperror.New(http.StatusServiceUnavailable, "payment_methods_unavailable", "saved payment methods are not configured")
+	ErrPaymentMethodAlreadyExists = apperror.New(http.StatusConflict, "payment_method_exists", "payment method is already saved for this customer")
+	ErrInvalidPaymentSource       = apperror.New(http.StatusBadRequest, "invalid_payment_source", "payment source is not a valid card number or bank account")
+)
+
+type CustomerService interface {
+	CreateCustomer(ctx context.Context, customerRequest *dto.CreateCustomerRequest) (*dto.CustomerResponse, error)
+	GetCustomerByID(ctx context.Context, id uint) (*dto.CustomerResponse, error)
+	SearchCustomers(ctx context.Context, searchRequest *dto.SearchCustomersRequest) ([]*dto.CustomerResponse, error)
+	UpdateCustomer(ctx context.Context, id uint, customerRequest *dto.UpdateCustomerRequest) (*dto.CustomerResponse, error)
+	DeleteCustomer(ctx context.Context, id uint) error
+	AddPaymentMethod(ctx context.Context, paymentMethodRequest *dto.CreatePaymentMethodRequest) (*dto.PaymentMethodResponse, error)
+	GetPaymentMethods(ctx context.Context, customerID uint) ([]*dto.PaymentMethodResponse, error)
+	DeletePaymentMethod(ctx context.Context, customerID, paymentMethodID uint) error
+}
+
+type customerService struct {
+	log       *zap.Logger
+	repo      repository.Repository
+	validator *validator.Validate
+	vault     *vault.Vault
+	// fingerprints identifies saved sources so that one is not saved twice
+	fingerprints *vault.Fingerprinter
+}
+
+// NewCustomerService builds the customer service. Without a vault customers can still be managed,
+// but payment methods cannot be saved.
+func NewCustomerService(log *zap.Logger, repo repository.Repository, validator *validator.Validate, vault *vault.Vault,
+	fingerprints *vault.Fingerprinter) CustomerService {
+	return &customerService{log: log,
+		repo: repo, validator: validator, vault: vault, fingerprints: fingerprints}
+}
+
+func (cs *customerService) CreateCustomer(ctx context.Context, customerRequest *dto.CreateCustomerRequest) (*dto.CustomerResponse, error) {
+	log := logging.FromContext(ctx, cs.log)
+	log.Info("Attempting to create a new customer", zap.String("customer_name", customerRequest.CustomerName))
+
+	err := authorize(ctx, auth.PermissionCustomersWrite)
+	if err != nil {
+		log.Warn("Not permitted to create customers", zap.Error(err))
+		return nil, err
+	}
+
+	customer := mapper.ToCustomerEntity(customerRequest)
+	if err := cs.ensureEmailAvailable(ctx, customer.CustomerEmail, 0); err != nil {
+		return nil, err
+	}
+
+	createdCustomer, err := cs.repo.CreateCustomer(ctx, customer)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// Another request took the email between the check and the insert
+		log.Warn("Customer email already in use")
+		return nil, ErrCustomerEmailTaken
+	}
+	if err != nil {
+		log.Error("Failed to create customer", zap.Error(err))
+		return nil, err
+	}
+
+	log.Info("Customer created successfully", zap.Uint("customer_id", createdCustomer.ID))
+	return mapper.ToCustomerResponse(createdCustomer), nil
+}
+
+func (cs *customerService) GetCustomerByID(ctx context.Context, id uint) (*dto.CustomerResponse, error) {
+	log := logging.FromContext(ctx, cs.log)
+	log.Info("Fetching customer by ID", zap.Uint("customer_id", id))
+
+	customer, err := cs.repo.GetCustomerByID(ctx, id)
+	if err != nil {
+		log.Error("Customer not found", zap.Uint("customer_id", id), zap.Error(err))
+		return nil, err
+	}
+
+	return mapper.ToCustomerResponse(customer), nil
+}
+
+func (cs *customerService) SearchCustomers(ctx context.Context, searchRequest *dto.SearchCustomersRequest) ([]*dto.CustomerResponse, error) {
+	log := logging.FromContext(ctx, cs.log)
+	log.Info("Searching customers", zap.Any("search", searchRequest))
+
+	limit := searchRequest.Limit
+	if limit == 0 {
+		limit = defaultCustomerSearchLimit
+	}
+
+	customers, err := cs.repo.SearchCustomers(ctx, strings.TrimSpace(searchRequest.Name), strings.TrimSpace(searchRequest.Email), limit)
+	if err != nil {
+		log.Error("Failed to search customers", zap.Error(err))
+		return nil, err
+	}
+
+	responses := make([]*dto.CustomerResponse, 0, len(customers))
+	for i := range customers {
+		responses = append(responses, mapper.ToCustomerResponse(&customers[i]))
+	}
+	return responses, nil
+}
+
+func (cs *customerService) UpdateCustomer(ctx context.Context, id uint, customerRequest *dto.UpdateCustomerRequest) (*dto.CustomerResponse, error) {
+	log := logging.FromContext(ctx, cs.log)
+	log.Info("Updating customer", zap.Uint("customer_id", id))
+
+	err := authorize(ctx, auth.PermissionCustomersWrite)
+	if err != nil {
+		log.Warn("Not permitted to update customers", zap.Error(err))
+		return nil, err
+	}
+
+	customer, err := cs.repo.GetCustomerByID(ctx, id)
+	if err != nil {
+		log.Error("Customer not found", zap.Uint("customer_id", id), zap.Error(err))
+		return nil, err
+	}
+
+	if customerRequest.CustomerName != nil {
+		customer.CustomerName = *customerRequest.CustomerName
+	}
+	if customerRequest.CustomerEmail != nil {
+		email := strings.ToLower(strings.TrimSpace(*customerRequest.CustomerEmail))
+		if email != customer.CustomerEmail {
+			if err := cs.ensureEmailAvailable(ctx, email, customer.ID); err != nil {
+				return nil, err
+			}
+			customer.CustomerEmail = email
+		}
+	}
+	if customerRequest.CustomerAddress != nil {
+		customer.CustomerAddress = *customerRequest.CustomerAddress
+	}
+
+	updatedCustomer, err := cs.repo.UpdateCustomer(ctx, customer)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		log.Warn("Customer email already in use", zap.Uint("customer_id", id))
+		return nil, ErrCustomerEmailTaken
+	}
+	if err != nil {
+		log.Error("Failed to update customer", zap.Uint("customer_id", id), zap.Error(err))
+		return nil, err
+	}
+
+	log.Info("Customer updated successfully", zap.Uint("customer_id", id))
+	return mapper.ToCustomerResponse(updatedCustomer), nil
+}
+
+// DeleteCustomer soft-deletes the customer and detaches its saved payment methods
+func (cs *customerService) DeleteCustomer(ctx context.Context, id uint) error {
+	log := logging.FromContext(ctx, cs.log)
+	log.Info("Deleting customer", zap.Uint("customer_id", id))
+
+	err := authorize(ctx, auth.PermissionCustomersWrite)
+	if err != nil {
+		log.Warn("Not permitted to delete customers", zap.Error(err))
+		return err
+	}
+
+	if err := cs.repo.DeleteCustomer(ctx, id); err != nil {
+		log.Error("Failed to delete customer", zap.Uint("customer_id", id), zap.Error(err))
+		return err
+	}
+
+	log.Info("Customer deleted successfully", zap.Uint("customer_id", id))
+	return nil
+}
+
+// AddPaymentMethod tokenizes a payment source and saves it against the customer
+func (cs *customerService) AddPaymentMethod(ctx context.Context, paymentMethodRequest *dto.CreatePaymentMethodRequest) (*dto.PaymentMethodResponse, error) {
+	log := logging.FromContext(ctx, cs.log)
+	log.Info("Saving payment method",
+		zap.Uint("customer_id", paymentMethodRequest.CustomerID),
+		zap.String("method_type", paymentMethodRequest.MethodType))
+
+	err := authorize(ctx, auth.PermissionCustomersWrite)
+	if err != nil {
+		log.Warn("Not permitted to save payment methods", zap.Error(err))
+		return nil, err
+	}
+
+	if cs.vault == nil {
+		return nil, ErrPaymentMethodsUnavailable
+	}
+
+	if _, err := cs.repo.GetCustomerByID(ctx, paymentMethodRequest.CustomerID); err != nil {
+		log.Error("Customer not found", zap.Uint("customer_id", paymentMethodRequest.CustomerID), zap.Error(err))
+		return nil, err
+	}
+
+	source, err := normalizePaymentSource(paymentMethodRequest.MethodType, paymentMethodRequest.PaymentSource)
+	if err != nil {
+		log.Warn("Invalid payment source", zap.Error(err))
+		return nil, err
+	}
+	fingerprint := cs.fingerprints.Fingerprint(source)
+	existing, err := cs.repo.GetPaymentMethodsByCustomer(ctx, paymentMethodRequest.CustomerID)
+	if err != nil {
+		log.Error("Failed to fetch saved payment methods", zap.Error(err))
+		return nil, err
+	}
+	for _, paymentMethod := range existing {
+		if paymentMethod.Fingerprint == fingerprint {
+			return nil, ErrPaymentMethodAlreadyExists
+		}
+	}
+
+	encryptedSource, err := cs.vault.Seal(source)
+	if err != nil {
+		log.Error("Failed to encrypt payment source", zap.Error(err))
+		return nil, err
+	}
+
+	paymentMethod := &entities.PaymentMethod{
+		CustomerID:      paymentMethodRequest.CustomerID,
+		MethodType:      paymentMethodRequest.MethodType,
+		EncryptedSource: encryptedSource,
+		Fingerprint:     fingerprint,
+		Last4:           source[len(source)-4:],
+		HolderName:      paymentMethodRequest.HolderName,
+		Expiry:          paymentMethodRequest.Expiry,
+	}
+	paymentMethod.IsActive = true
+
+	createdPaymentMethod, err := cs.repo.CreatePaymentMethod(ctx, paymentMethod)
+	if err != nil {
+		log.Error("Failed to save payment method", zap.Error(err))
+		return nil, err
+	}
+
+	log.Info("Payment method saved", zap.Uint("payment_method_id", createdPaymentMethod.ID))
+	return mapper.ToPaymentMethodResponse(createdPaymentMethod), nil
+}
+
+func (cs *customerService) GetPaymentMethods(ctx context.Context, customerID uint) ([]*dto.PaymentMethodResponse, error) {
+	log := logging.FromContext(ctx, cs.log)
+	log.Info("Fetching saved payment methods", zap.Uint("customer_id", customerID))
+
+	if _, err := cs.repo.GetCustomerByID(ctx, customerID); err != nil {
+		log.Error("Customer not found", zap.Uint("customer_id", customerID), zap.Error(err))
+		return nil, err
+	}
+
+	paymentMethods, err := cs.repo.GetPaymentMethodsByCustomer(ctx, customerID)
+	if err != nil {
+		log.Error("Failed to fetch saved payment methods", zap.Error(err))
+		return nil, err
+	}
+
+	responses := make([]*dto.PaymentMethodResponse, 0, len(paymentMethods))
+	for i := range paymentMethods {
+		responses = append(responses, mapper.ToPaymentMethodResponse(&paymentMethods[i]))
+	}
+	return responses, nil
+}
+
+func (cs *customerService) DeletePaymentMethod(ctx context.Context, customerID, paymentMethodID uint) error {
+	log := logging.FromContext(ctx, cs.log)
+	log.Info("Deleting payment method", zap.Uint("customer_id", customerID), zap.Uint("payment_method_id", paymentMethodID))
+
+	err := authorize(ctx, auth.PermissionCustomersWrite)
+	if err != nil {
+		log.Warn("Not permitted to delete payment methods", zap.Error(err))
+		return err
+	}
+
+	if err := cs.repo.DeletePaymentMethod(ctx, customerID, paymentMethodID); err != nil {
+		log.Error("Failed to delete payment method", zap.Uint("payment_method_id", paymentMethodID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// normalizePaymentSource strips the spaces of a card number or bank account and checks it is a valid
+// card number, IBAN or domestic account number
+func normalizePaymentSource(methodType, source string) (string, error) {
+	source = strings.ToUpper(strings.ReplaceAll(source, " ", ""))
+	valid := utils.ValidBankAccount(source)
+	if methodType == utils.PaymentMethodCreditCard {
+		valid = utils.ValidCardNumber(source)
+	}
+	if !valid {
+		return "", ErrInvalidPaymentSource
+	}
+	return source, nil
+}
+
+// ensureEmailAvailable fails if another customer (including a soft-deleted one) already uses the email
+func (cs *customerService) ensureEmailAvailable(ctx context.Context, email string, customerID uint) error {
+	log := logging.FromContext(ctx, cs.log)
+	existing, err := cs.repo.GetCustomerByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		log.Error("Error checking customer email", zap.Error(err))
+		return err
+	}
+	if existing.ID != customerID {
+		return ErrCustomerEmailTaken
+	}
+	return nil
+}
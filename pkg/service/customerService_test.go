@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/repository"
+	"go/payment-processor/pkg/utils"
+	"go/payment-processor/pkg/vault"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// fakeCustomerRepository holds a single customer and the payment methods saved against it. Emails in
+// racing are taken by another request between the availability check and the write.
+type fakeCustomerRepository struct {
+	repository.Repository
+	paymentMethods []entities.PaymentMethod
+	racing         map[string]bool
+}
+
+func (r *fakeCustomerRepository) GetCustomerByID(_ context.Context, customerID uint) (*entities.Customer, error) {
+	customer := &entities.Customer{}
+	customer.ID = customerID
+	return customer, nil
+}
+
+func (r *fakeCustomerRepository) GetCustomerByEmail(context.Context, string) (*entities.Customer, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeCustomerRepository) CreateCustomer(_ context.Context, customer *entities.Customer) (*entities.Customer, error) {
+	if r.racing[customer.CustomerEmail] {
+		return nil, gorm.ErrDuplicatedKey
+	}
+	customer.ID = 1
+	return customer, nil
+}
+
+func (r *fakeCustomerRepository) UpdateCustomer(_ context.Context, customer *entities.Customer) (*entities.Customer, error) {
+	if r.racing[customer.CustomerEmail] {
+		return nil, gorm.ErrDuplicatedKey
+	}
+	return customer, nil
+}
+
+func (r *fakeCustomerRepository) GetPaymentMethodsByCustomer(context.Context, uint) ([]entities.PaymentMethod, error) {
+	return r.paymentMethods, nil
+}
+
+func (r *fakeCustomerRepository) CreatePaymentMethod(_ context.Context, paymentMethod *entities.PaymentMethod) (*entities.PaymentMethod, error) {
+	paymentMethod.ID = uint(len(r.paymentMethods) + 1)
+	r.paymentMethods = append(r.paymentMethods, *paymentMethod)
+	return paymentMethod, nil
+}
+
+func newTestCustomerService(t *testing.T) CustomerService {
+	v, err := vault.NewFromHex("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	assert.NoError(t, err)
+	return NewCustomerService(zap.NewNop(), &fakeCustomerRepository{}, validator.New(), v, vault.NewEphemeralFingerprinter())
+}
+
+func TestAddPaymentMethodNormalizesSource(t *testing.T) {
+	service := newTestCustomerService(t)
+	ctx := auth.ContextWithPrincipal(context.Background(), auth.SystemPrincipal("test"))
+
+	card, err := service.AddPaymentMethod(ctx, &dto.CreatePaymentMethodRequest{
+		CustomerID: 1, MethodType: utils.PaymentMethodCreditCard, PaymentSource: "4242 4242 4242 4242"})
+	assert.NoError(t, err)
+	assert.Equal(t, "4242", card.Last4)
+
+	account, err := service.AddPaymentMethod(ctx, &dto.CreatePaymentMethodRequest{
+		CustomerID: 1, MethodType: utils.PaymentMethodBankTransfer, PaymentSource: "gb82 west 1234 5698 7654 32"})
+	assert.NoError(t, err)
+	assert.Equal(t, "5432", account.Last4)
+
+	_, err = service.AddPaymentMethod(ctx, &dto.CreatePaymentMethodRequest{
+		CustomerID: 1, MethodType: utils.PaymentMethodCreditCard, PaymentSource: "4242424242424242"})
+	assert.ErrorIs(t, err, ErrPaymentMethodAlreadyExists)
+}
+
+func TestAddPaymentMethodRejectsInvalidSource(t *testing.T) {
+	service := newTestCustomerService(t)
+	ctx := auth.ContextWithPrincipal(context.Background(), auth.SystemPrincipal("test"))
+
+	for _, request := range []dto.CreatePaymentMethodRequest{
+		{MethodType: utils.PaymentMethodCreditCard, PaymentSource: "1 2 "},
+		{MethodType: utils.PaymentMethodCreditCard, PaymentSource: "4242 4242 4242 4241"},
+		{MethodType: utils.PaymentMethodCreditCard, PaymentSource: "4242-4242-4242-4242"},
+		{MethodType: utils.PaymentMethodBankTransfer, PaymentSource: "1 2 "},
+		{MethodType: utils.PaymentMethodBankTransfer, PaymentSource: "GB82 WEST 1234 5698 7654 33"},
+		{MethodType: utils.PaymentMethodBankTransfer, PaymentSource: "acct-1234"},
+	} {
+		request.CustomerID = 1
+		_, err := service.AddPaymentMethod(ctx, &request)
+		assert.ErrorIs(t, err, ErrInvalidPaymentSource, request.PaymentSource)
+	}
+}
+
+func TestCustomerEmailTakenConcurrently(t *testing.T) {
+	repo := &fakeCustomerRepository{racing: map[string]bool{"raced@example.com": true}}
+	service := NewCustomerService(zap.NewNop(), repo, validator.New(), nil, vault.NewEphemeralFingerprinter())
+	ctx := auth.ContextWithPrincipal(context.Background(), auth.SystemPrincipal("test"))
+
+	// Taken by a concurrent request after the availability check, caught by the unique constraint
+	_, err := service.CreateCustomer(ctx, &dto.CreateCustomerRequest{CustomerName: "Jane", CustomerEmail: "raced@example.com"})
+	assert.ErrorIs(t, err, ErrCustomerEmailTaken)
+
+	email := "raced@example.com"
+	_, err = service.UpdateCustomer(ctx, 1, &dto.UpdateCustomerRequest{CustomerEmail: &email})
+	assert.ErrorIs(t, err, ErrCustomerEmailTaken)
+}
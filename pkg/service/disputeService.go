@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/logging"
+	"go/payment-processor/pkg/mapper"
+	"go/payment-processor/pkg/repository"
+	"go/payment-processor/pkg/utils"
+	"gorm.io/gorm"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrPaymentNotDisputable  = apperror.New(http.StatusConflict, "payment_not_disputable", "only successful payments can be disputed")
+	ErrDisputeAlreadyOpen    = apperror.New(http.StatusConflict, "dispute_already_open", "payment already has an open dispute")
+	ErrDisputeAmountExceeded = apperror.New(http.StatusBadRequest, "dispute_amount_exceeded", "dispute amount exceeds the payment amount")
+	ErrDisputeClosed         = apperror.New(http.StatusConflict, "dispute_closed", "dispute is already resolved")
+	ErrDisputeDeadlinePassed = apperror.New(http.StatusConflict, "dispute_deadline_passed", "evidence deadline for this dispute has passed")
+	ErrDisputeNotReviewable  = apperror.New(http.StatusConflict, "dispute_not_reviewable", "dispute can only be resolved once evidence is under review or the deadline has passed")
+)
+
+type DisputeService interface {
+	OpenDispute(ctx context.Context, disputeRequest *dto.CreateDisputeRequest) (*dto.DisputeResponse, error)
+	GetDisputeByID(ctx context.Context, id uint) (*dto.DisputeResponse, error)
+	SubmitEvidence(ctx context.Context, evidenceRequest *dto.SubmitDisputeEvidenceRequest) (*dto.DisputeEvidenceResponse, error)
+	ResolveDispute(ctx context.Context, id uint, resolveRequest *dto.ResolveDisputeRequest) (*dto.DisputeResponse, error)
+}
+
+type disputeService struct {
+	log       *zap.Logger
+	repo      repository.Repository
+	validator *validator.Validate
+}
+
+func NewDisputeService(log *zap.Logger, repo repository.Repository, validator *validator.Validate) DisputeService {
+	return &disputeService{log: log,
+		repo: repo, validator: validator}
+}
+
+// OpenDispute records a chargeback against a successful payment and flags its invoice as disputed
+func (ds *disputeService) OpenDispute(ctx context.Context, disputeRequest *dto.CreateDisputeRequest) (*dto.DisputeResponse, error) {
+	log := logging.FromContext(ctx, ds.log)
+	log.Info("Opening dispute", zap.Any("dispute", disputeRequest))
+
+	err := authorize(ctx, auth.PermissionDisputesOpen)
+	if err != nil {
+		log.Warn("Not permitted to open disputes", zap.Error(err))
+		return nil, err
+	}
+
+	payment, err := ds.repo.GetPaymentByID(ctx, disputeRequest.PaymentID)
+	if err != nil {
+		log.Error("Payment not found", zap.Uint("payment_id", disputeRequest.PaymentID), zap.Error(err))
+		return nil, err
+	}
+	if payment.PaymentStatus != utils.PaymentStatusSuccess {
+		return nil, ErrPaymentNotDisputable
+	}
+
+	openDisputes, err := ds.repo.CountOpenDisputes(ctx, payment.ID)
+	if err != nil {
+		log.Error("Error checking open disputes", zap.Error(err))
+		return nil, err
+	}
+	if openDisputes > 0 {
+		return nil, ErrDisputeAlreadyOpen
+	}
+
+	amount := payment.Amount
+	if disputeRequest.Amount > 0 {
+		amount = utils.ConvertFloat64ToDecimal(disputeRequest.Amount)
+		if amount.GreaterThan(payment.Amount) {
+			return nil, ErrDisputeAmountExceeded
+		}
+	}
+
+	invoice, err := ds.repo.GetInvoiceByID(ctx, payment.InvoiceID)
+	if err != nil {
+		log.Error("Invoice not found for disputed payment", zap.Uint("invoice_id", payment.InvoiceID), zap.Error(err))
+		return nil, err
+	}
+
+	respondBy := time.Now().Add(utils.DisputeResponseWindow)
+	if disputeRequest.RespondBy != nil {
+		respondBy = *disputeRequest.RespondBy
+	}
+
+	dispute := &entities.Dispute{
+		PaymentID:     payment.ID,
+		InvoiceID:     payment.InvoiceID,
+		MerchantID:    payment.MerchantID,
+		Amount:        amount,
+		Currency:      invoice.Currency,
+		ReasonCode:    disputeRequest.ReasonCode,
+		DisputeStatus: utils.DisputeStatusNeedsResponse,
+		RespondBy:     &respondBy,
+	}
+	dispute.IsActive = true
+
+	// The count above answers the common case; a dispute opened concurrently is caught by the unique index
+	createdDispute, err := ds.repo.CreateDispute(ctx, dispute, utils.InvoiceStatusDisputed)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		log.Warn("Dispute was opened concurrently", zap.Uint("payment_id", payment.ID))
+		return nil, ErrDisputeAlreadyOpen
+	}
+	if err != nil {
+		log.Error("Failed to create dispute", zap.Error(err))
+		return nil, err
+	}
+
+	log.Info("Dispute opened", zap.Uint("dispute_id", createdDispute.ID), zap.Uint("payment_id", payment.ID))
+	return mapper.ToDisputeResponse(createdDispute, nil), nil
+}
+
+func (ds *disputeService) GetDisputeByID(ctx context.Context, id uint) (*dto.DisputeResponse, error) {
+	log := logging.FromContext(ctx, ds.log)
+	log.Info("Fetching dispute by ID", zap.Uint("dispute_id", id))
+
+	dispute, err := ds.repo.GetDisputeByID(ctx, id)
+	if err != nil {
+		log.Error("Dispute not found", zap.Uint("dispute_id", id), zap.Error(err))
+		return nil, err
+	}
+
+	evidence, err := ds.repo.GetDisputeEvidence(ctx, id)
+	if err != nil {
+		log.Error("Failed to fetch dispute evidence", zap.Uint("dispute_id", id), zap.Error(err))
+		return nil, err
+	}
+
+	return mapper.ToDisputeResponse(dispute, evidence), nil
+}
+
+// SubmitEvidence attaches merchant evidence to an open dispute and moves it under review
+func (ds *disputeService) SubmitEvidence(ctx context.Context, evidenceRequest *dto.SubmitDisputeEvidenceRequest) (*dto.DisputeEvidenceResponse, error) {
+	log := logging.FromContext(ctx, ds.log)
+	log.Info("Submitting dispute evidence",
+		zap.Uint("dispute_id", evidenceRequest.DisputeID),
+		zap.String("evidence_type", evidenceRequest.EvidenceType),
+		zap.String("file_name", evidenceRequest.FileName))
+
+	err := authorize(ctx, auth.PermissionDisputesRespond)
+	if err != nil {
+		log.Warn("Not permitted to respond to disputes", zap.Error(err))
+		return nil, err
+	}
+
+	dispute, err := ds.repo.GetDisputeByID(ctx, evidenceRequest.DisputeID)
+	if err != nil {
+		log.Error("Dispute not found", zap.Uint("dispute_id", evidenceRequest.DisputeID), zap.Error(err))
+		return nil, err
+	}
+	if isDisputeResolved(dispute) {
+		return nil, ErrDisputeClosed
+	}
+	if dispute.RespondBy != nil && time.Now().After(*dispute.RespondBy) {
+		return nil, ErrDisputeDeadlinePassed
+	}
+
+	evidence := mapper.ToDisputeEvidenceEntity(evidenceRequest)
+	evidence.IsActive = true
+	createdEvidence, err := ds.repo.AddDisputeEvidence(ctx, evidence, utils.DisputeStatusUnderReview)
+	if err != nil {
+		log.Error("Failed to store dispute evidence", zap.Error(err))
+		return nil, err
+	}
+
+	log.Info("Dispute evidence stored", zap.Uint("dispute_id", dispute.ID), zap.Uint("evidence_id", createdEvidence.ID))
+	return mapper.ToDisputeEvidenceResponse(createdEvidence), nil
+}
+
+// ResolveDispute closes a dispute. A lost dispute charges the amount back to the merchant in the ledger
+// and marks the invoice as charged back; a won dispute returns the invoice to paid.
+func (ds *disputeService) ResolveDispute(ctx context.Context, id uint, resolveRequest *dto.ResolveDisputeRequest) (*dto.DisputeResponse, error) {
+	log := logging.FromContext(ctx, ds.log)
+	log.Info("Resolving dispute", zap.Uint("dispute_id", id), zap.String("outcome", resolveRequest.Outcome))
+
+	err := authorize(ctx, auth.PermissionDisputesResolve)
+	if err != nil {
+		log.Warn("Not permitted to resolve disputes", zap.Error(err))
+		return nil, err
+	}
+
+	dispute, err := ds.repo.GetDisputeByID(ctx, id)
+	if err != nil {
+		log.Error("Dispute not found", zap.Uint("dispute_id", id), zap.Error(err))
+		return nil, err
+	}
+	if isDisputeResolved(dispute) {
+		return nil, ErrDisputeClosed
+	}
+	deadlinePassed := dispute.RespondBy != nil && time.Now().After(*dispute.RespondBy)
+	if dispute.DisputeStatus != utils.DisputeStatusUnderReview && !deadlinePassed {
+		return nil, ErrDisputeNotReviewable
+	}
+
+	now := time.Now()
+	dispute.DisputeStatus = resolveRequest.Outcome
+	dispute.ResolvedAt = &now
+
+	invoiceStatus := utils.InvoiceStatusPaid
+	var ledgerEntry *entities.LedgerEntry
+	if dispute.DisputeStatus == utils.DisputeStatusLost {
+		invoiceStatus = utils.InvoiceStatusChargedBack
+		ledgerEntry = &entities.LedgerEntry{
+			MerchantID: dispute.MerchantID,
+			PaymentID:  dispute.PaymentID,
+			DisputeID:  &dispute.ID,
+			EntryType:  utils.LedgerEntryTypeChargeback,
+			Amount:     dispute.Amount.Mul(decimal.NewFromInt(-1)),
+			Currency:   dispute.Currency,
+		}
+		ledgerEntry.IsActive = true
+	}
+
+	err = ds.repo.ResolveDispute(ctx, dispute, invoiceStatus, ledgerEntry)
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, gorm.ErrDuplicatedKey) {
+		log.Warn("Dispute was resolved concurrently", zap.Uint("dispute_id", id))
+		return nil, ErrDisputeClosed
+	}
+	if err != nil {
+		log.Error("Failed to resolve dispute", zap.Uint("dispute_id", id), zap.Error(err))
+		return nil, err
+	}
+
+	log.Info("Dispute resolved", zap.Uint("dispute_id", id), zap.String("status", dispute.DisputeStatus))
+	return ds.GetDisputeByID(ctx, id)
+}
+
+func isDisputeResolved(dispute *entities.Dispute) bool {
+	return dispute.DisputeStatus == utils.DisputeStatusWon || dispute.DisputeStatus == utils.DisputeStatusLost
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/repository"
+	"go/payment-processor/pkg/utils"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// fakeDisputeRepository keeps one paid invoice with its successful payment, enforcing one open dispute
+// per payment and resolving disputes only while open, like the database. Disputes in stale are read as
+// they were before another request changed them, and staleCount hides open disputes from the count.
+type fakeDisputeRepository struct {
+	repository.Repository
+	payment    entities.Payment
+	invoice    entities.Invoice
+	disputes   map[uint]*entities.Dispute
+	stale      map[uint]entities.Dispute
+	evidence   []entities.DisputeEvidence
+	ledger     []entities.LedgerEntry
+	staleCount bool
+}
+
+func newFakeDisputeRepository() *fakeDisputeRepository {
+	invoice := entities.Invoice{Amount: decimal.NewFromInt(25), Currency: "USD", InvoiceStatus: utils.InvoiceStatusPaid}
+	invoice.ID = 1
+	payment := entities.Payment{InvoiceID: 1, MerchantID: 1, Amount: decimal.NewFromInt(25), PaymentStatus: utils.PaymentStatusSuccess}
+	payment.ID = 1
+	return &fakeDisputeRepository{payment: payment, invoice: invoice, disputes: map[uint]*entities.Dispute{},
+		stale: map[uint]entities.Dispute{}}
+}
+
+func (r *fakeDisputeRepository) GetPaymentByID(_ context.Context, id uint) (*entities.Payment, error) {
+	if id != r.payment.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	payment := r.payment
+	return &payment, nil
+}
+
+func (r *fakeDisputeRepository) GetInvoiceByID(context.Context, uint) (*entities.Invoice, error) {
+	invoice := r.invoice
+	return &invoice, nil
+}
+
+func (r *fakeDisputeRepository) CountOpenDisputes(_ context.Context, paymentID uint) (int64, error) {
+	if r.staleCount {
+		return 0, nil
+	}
+	return r.openDisputes(paymentID), nil
+}
+
+func (r *fakeDisputeRepository) openDisputes(paymentID uint) int64 {
+	var count int64
+	for _, dispute := range r.disputes {
+		if dispute.PaymentID == paymentID && !isDisputeResolved(dispute) {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *fakeDisputeRepository) CreateDispute(_ context.Context, dispute *entities.Dispute, invoiceStatus string) (*entities.Dispute, error) {
+	if r.openDisputes(dispute.PaymentID) > 0 {
+		return nil, gorm.ErrDuplicatedKey
+	}
+	dispute.ID = uint(len(r.disputes) + 1)
+	stored := *dispute
+	r.disputes[dispute.ID] = &stored
+	r.invoice.InvoiceStatus = invoiceStatus
+	return dispute, nil
+}
+
+func (r *fakeDisputeRepository) GetDisputeByID(_ context.Context, id uint) (*entities.Dispute, error) {
+	if dispute, ok := r.stale[id]; ok {
+		return &dispute, nil
+	}
+	dispute, ok := r.disputes[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	read := *dispute
+	return &read, nil
+}
+
+func (r *fakeDisputeRepository) GetDisputeEvidence(_ context.Context, disputeID uint) ([]entities.DisputeEvidence, error) {
+	var evidence []entities.DisputeEvidence
+	for _, e := range r.evidence {
+		if e.DisputeID == disputeID {
+			evidence = append(evidence, e)
+		}
+	}
+	return evidence, nil
+}
+
+func (r *fakeDisputeRepository) AddDisputeEvidence(_ context.Context, evidence *entities.DisputeEvidence, disputeStatus string) (*entities.DisputeEvidence, error) {
+	evidence.ID = uint(len(r.evidence) + 1)
+	r.evidence = append(r.evidence, *evidence)
+	r.disputes[evidence.DisputeID].DisputeStatus = disputeStatus
+	return evidence, nil
+}
+
+func (r *fakeDisputeRepository) ResolveDispute(_ context.Context, dispute *entities.Dispute, invoiceStatus string, ledgerEntry *entities.LedgerEntry) error {
+	stored := r.disputes[dispute.ID]
+	if isDisputeResolved(stored) {
+		return gorm.ErrRecordNotFound
+	}
+	stored.DisputeStatus = dispute.DisputeStatus
+	stored.ResolvedAt = dispute.ResolvedAt
+	r.invoice.InvoiceStatus = invoiceStatus
+	if ledgerEntry != nil {
+		r.ledger = append(r.ledger, *ledgerEntry)
+	}
+	return nil
+}
+
+// openDisputeUnderReview opens a dispute as the back office and answers it with evidence as the merchant
+func openDisputeUnderReview(ctx context.Context, t *testing.T, service DisputeService) *dto.DisputeResponse {
+	dispute, err := service.OpenDispute(ctx, &dto.CreateDisputeRequest{PaymentID: 1, ReasonCode: "fraudulent", Amount: 20})
+	assert.NoError(t, err)
+	assert.Equal(t, utils.DisputeStatusNeedsResponse, dispute.DisputeStatus)
+
+	merchant := &auth.Principal{MerchantID: 1, Mode: utils.APIKeyModeTest, Scopes: auth.MerchantScopes, Roles: []string{auth.RoleMerchant}}
+	_, err = service.SubmitEvidence(auth.ContextWithPrincipal(ctx, merchant), &dto.SubmitDisputeEvidenceRequest{
+		DisputeID: dispute.ID, EvidenceType: "receipt", FileName: "receipt.pdf", ContentType: "application/pdf", Content: []byte("%PDF")})
+	assert.NoError(t, err)
+	return dispute
+}
+
+func TestResolveDispute(t *testing.T) {
+	ctx := auth.ContextWithPrincipal(context.Background(), auth.SystemPrincipal("test"))
+
+	t.Run("won", func(t *testing.T) {
+		repo := newFakeDisputeRepository()
+		service := NewDisputeService(zap.NewNop(), repo, validator.New())
+		dispute := openDisputeUnderReview(ctx, t, service)
+		assert.Equal(t, utils.InvoiceStatusDisputed, repo.invoice.InvoiceStatus)
+
+		resolved, err := service.ResolveDispute(ctx, dispute.ID, &dto.ResolveDisputeRequest{Outcome: utils.DisputeStatusWon})
+		assert.NoError(t, err)
+		assert.Equal(t, utils.DisputeStatusWon, resolved.DisputeStatus)
+		assert.NotNil(t, resolved.ResolvedAt)
+		assert.Len(t, resolved.Evidence, 1)
+		assert.Equal(t, utils.InvoiceStatusPaid, repo.invoice.InvoiceStatus)
+		assert.Empty(t, repo.ledger)
+	})
+
+	t.Run("lost", func(t *testing.T) {
+		repo := newFakeDisputeRepository()
+		service := NewDisputeService(zap.NewNop(), repo, validator.New())
+		dispute := openDisputeUnderReview(ctx, t, service)
+
+		resolved, err := service.ResolveDispute(ctx, dispute.ID, &dto.ResolveDisputeRequest{Outcome: utils.DisputeStatusLost})
+		assert.NoError(t, err)
+		assert.Equal(t, utils.DisputeStatusLost, resolved.DisputeStatus)
+		assert.Equal(t, utils.InvoiceStatusChargedBack, repo.invoice.InvoiceStatus)
+		if assert.Len(t, repo.ledger, 1) {
+			entry := repo.ledger[0]
+			assert.Equal(t, utils.LedgerEntryTypeChargeback, entry.EntryType)
+			assert.Equal(t, dispute.ID, *entry.DisputeID)
+			assert.True(t, decimal.NewFromInt(-20).Equal(entry.Amount), entry.Amount.String())
+			assert.Equal(t, "USD", entry.Currency)
+		}
+	})
+
+	t.Run("resolved twice", func(t *testing.T) {
+		repo := newFakeDisputeRepository()
+		service := NewDisputeService(zap.NewNop(), repo, validator.New())
+		dispute := openDisputeUnderReview(ctx, t, service)
+
+		_, err := service.ResolveDispute(ctx, dispute.ID, &dto.ResolveDisputeRequest{Outcome: utils.DisputeStatusLost})
+		assert.NoError(t, err)
+		_, err = service.ResolveDispute(ctx, dispute.ID, &dto.ResolveDisputeRequest{Outcome: utils.DisputeStatusWon})
+		assert.ErrorIs(t, err, ErrDisputeClosed)
+
+		// A concurrent resolution still reading the dispute as under review loses at the conditional update
+		repo.stale[dispute.ID] = entities.Dispute{AuditTrail: entities.AuditTrail{ID: dispute.ID}, PaymentID: 1, InvoiceID: 1,
+			DisputeStatus: utils.DisputeStatusUnderReview}
+		_, err = service.ResolveDispute(ctx, dispute.ID, &dto.ResolveDisputeRequest{Outcome: utils.DisputeStatusLost})
+		assert.ErrorIs(t, err, ErrDisputeClosed)
+
+		assert.Len(t, repo.ledger, 1)
+		assert.Equal(t, utils.InvoiceStatusChargedBack, repo.invoice.InvoiceStatus)
+		assert.Equal(t, utils.DisputeStatusLost, repo.disputes[dispute.ID].DisputeStatus)
+	})
+}
+
+func TestOpenDisputeOncePerPayment(t *testing.T) {
+	ctx := auth.ContextWithPrincipal(context.Background(), auth.SystemPrincipal("test"))
+	repo := newFakeDisputeRepository()
+	service := NewDisputeService(zap.NewNop(), repo, validator.New())
+	request := &dto.CreateDisputeRequest{PaymentID: 1, ReasonCode: "fraudulent"}
+
+	_, err := service.OpenDispute(ctx, request)
+	assert.NoError(t, err)
+
+	// Already open before the request, caught by the count
+	_, err = service.OpenDispute(ctx, request)
+	assert.ErrorIs(t, err, ErrDisputeAlreadyOpen)
+
+	// Opened by a concurrent request after the count, caught by the unique index
+	repo.staleCount = true
+	_, err = service.OpenDispute(ctx, request)
+	assert.ErrorIs(t, err, ErrDisputeAlreadyOpen)
+	assert.Len(t, repo.disputes, 1)
+}
@@ -1,113 +1,273 @@
 package services
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"github.com/go-playground/validator/v10"
 	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/auth"
 	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/logging"
 
 	"go/payment-processor/pkg/mapper"
+	"go/payment-processor/pkg/metrics"
 	"go/payment-processor/pkg/repository"
+	"go/payment-processor/pkg/tracing"
 	"go/payment-processor/pkg/utils"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// defaultInvoicePageSize applies when a listing does not ask for a page size
+const defaultInvoicePageSize = 20
+
+var (
+	ErrInvalidCursor      = apperror.New(http.StatusBadRequest, "invalid_cursor", "invalid pagination cursor")
+	ErrInvalidInvoice     = apperror.New(http.StatusBadRequest, "invalid_invoice", "invalid invoice data")
+	ErrInvoiceNotFound    = apperror.New(http.StatusNotFound, "invoice_not_found", "invoice not found")
+	ErrUnknownMerchant    = apperror.New(http.StatusUnprocessableEntity, "unknown_merchant", "merchant does not exist")
+	ErrMerchantInactive   = apperror.New(http.StatusUnprocessableEntity, "merchant_inactive", "merchant is not active")
+	ErrUnknownCustomer    = apperror.New(http.StatusUnprocessableEntity, "unknown_customer", "customer does not exist")
+	ErrCustomerInactive   = apperror.New(http.StatusUnprocessableEntity, "customer_inactive", "customer is not active")
+	ErrCurrencyNotAllowed = apperror.New(http.StatusUnprocessableEntity, "currency_not_allowed", "currency is not allowed for this merchant")
 )
 
 type InvoiceService interface {
-	CreateInvoice(invoice *dto.CreateInvoiceRequest) (*dto.InvoiceResponse, error)
-	GetInvoiceByID(id uint) (*dto.InvoiceResponse, error)
-	ValidateInvoiceRequest(invoiceRequest *dto.CreateInvoiceRequest) error
+	CreateInvoice(ctx context.Context, invoice *dto.CreateInvoiceRequest) (*dto.InvoiceResponse, error)
+	GetInvoiceByID(ctx context.Context, id uint) (*dto.InvoiceResponse, error)
+	ListInvoices(ctx context.Context, listRequest *dto.ListInvoicesRequest) (*dto.ListInvoicesResponse, error)
+	ValidateInvoiceRequest(ctx context.Context, invoiceRequest *dto.CreateInvoiceRequest) error
 }
 type invoiceService struct {
 	log       *zap.Logger
 	repo      repository.Repository
 	validator *validator.Validate
+	metrics   *metrics.Metrics
 }
 
-func NewInvoiceService(log *zap.Logger, repo repository.Repository, validator *validator.Validate) InvoiceService {
+// NewInvoiceService builds the invoice service. Created invoices are counted in m, which may be nil.
+func NewInvoiceService(log *zap.Logger, repo repository.Repository, validator *validator.Validate, m *metrics.Metrics) InvoiceService {
 	return &invoiceService{log: log,
-		repo: repo, validator: validator}
+		repo: repo, validator: validator, metrics: m}
 }
 
-func (is *invoiceService) CreateInvoice(invoiceRequest *dto.CreateInvoiceRequest) (*dto.InvoiceResponse, error) {
-	is.log.Info("Attempting to create a new invoice", zap.Any("invoice", invoiceRequest))
+func (is *invoiceService) CreateInvoice(ctx context.Context, invoiceRequest *dto.CreateInvoiceRequest) (_ *dto.InvoiceResponse, err error) {
+	ctx = logging.With(ctx, zap.Uint("merchant_id", invoiceRequest.MerchantID))
+	log := logging.FromContext(ctx, is.log)
+	log.Info("Attempting to create a new invoice", zap.Any("invoice", invoiceRequest))
+	ctx, span := tracing.Start(ctx, "InvoiceService.CreateInvoice", attribute.Int64("merchant.id", int64(invoiceRequest.MerchantID)))
+	defer tracing.End(span, &err)
+
+	err = authorize(ctx, auth.PermissionInvoicesWrite)
+	if err != nil {
+		log.Warn("Not permitted to create invoices", zap.Error(err))
+		return nil, err
+	}
 
 	// Validate inputs
 	if invoiceRequest.MerchantID == 0 || invoiceRequest.CustomerID == 0 ||
 		utils.ConvertFloat64ToDecimal(invoiceRequest.Amount).LessThanOrEqual(decimal.NewFromInt(0)) {
-		err := errors.New("invalid invoice data")
-		is.log.Error("Invalid invoice data", zap.Error(err))
-		return nil, err
+		log.Error("Invalid invoice data", zap.Error(ErrInvalidInvoice))
+		return nil, ErrInvalidInvoice
 	}
 
-	err := is.ValidateInvoiceRequest(invoiceRequest)
+	err = is.ValidateInvoiceRequest(ctx, invoiceRequest)
 	if err != nil {
-		is.log.Error("Validation failed for create invoice", zap.Error(err))
+		log.Error("Validation failed for create invoice", zap.Error(err))
 		return nil, err
 	}
 	invoice := mapper.ToInvoiceEntity(invoiceRequest)
 
 	// Call repository to create the invoice
-	createdInvoice, err := is.repo.CreateInvoice(invoice)
+	createdInvoice, err := is.repo.CreateInvoice(ctx, invoice)
 	if err != nil {
-		is.log.Error("Failed to create invoice", zap.Error(err))
+		log.Error("Failed to create invoice", zap.Error(err))
 		return nil, err
 	}
 
-	is.log.Info("Invoice created successfully", zap.Uint("invoice_id", createdInvoice.ID))
+	is.metrics.InvoiceCreated(createdInvoice.MerchantID, createdInvoice.Currency)
+	log.Info("Invoice created successfully", zap.Uint("invoice_id", createdInvoice.ID))
 	return mapper.ToInvoiceResponse(createdInvoice), nil
 }
 
-func (is *invoiceService) GetInvoiceByID(id uint) (*dto.InvoiceResponse, error) {
-	is.log.Info("Fetching invoice by ID", zap.Uint("invoice_id", id))
+func (is *invoiceService) GetInvoiceByID(ctx context.Context, id uint) (*dto.InvoiceResponse, error) {
+	ctx = logging.With(ctx, zap.Uint("invoice_id", id))
+	log := logging.FromContext(ctx, is.log)
+	log.Info("Fetching invoice by ID")
 
-	invoice, err := is.repo.GetInvoiceByID(id)
+	invoice, err := is.repo.GetInvoiceByID(ctx, id)
 	if err != nil {
-		is.log.Error("Invoice not found", zap.Uint("invoice_id", id), zap.Error(err))
+		log.Error("Invoice not found", zap.Error(err))
 		return nil, err
 	}
 
-	is.log.Info("Successfully fetched invoice", zap.Uint("invoice_id", id))
+	log.Info("Successfully fetched invoice")
 	return mapper.ToInvoiceResponse(invoice), nil
 }
 
-func (is *invoiceService) ValidateInvoiceRequest(invoiceRequest *dto.CreateInvoiceRequest) error {
+// ListInvoices returns one page of invoices matching the filters, ordered by the requested column
+// with the invoice ID as tie-breaker so that cursors stay stable while new invoices are created.
+func (is *invoiceService) ListInvoices(ctx context.Context, listRequest *dto.ListInvoicesRequest) (*dto.ListInvoicesResponse, error) {
+	log := logging.FromContext(ctx, is.log)
+	log.Info("Listing invoices", zap.Any("filters", listRequest))
+
+	query := repository.InvoiceQuery{
+		MerchantID: listRequest.MerchantID,
+		CustomerID: listRequest.CustomerID,
+		Status:     listRequest.Status,
+		Currency:   listRequest.Currency,
+		Mode:       listRequest.Mode,
+		SortBy:     listRequest.Sort,
+		Descending: listRequest.Order != "asc",
+		Limit:      listRequest.Limit,
+	}
+	if query.SortBy == "" {
+		query.SortBy = repository.InvoiceSortCreatedAt
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultInvoicePageSize
+	}
+	if listRequest.MinAmount != nil {
+		query.MinAmount = decimal.NewNullDecimal(utils.ConvertFloat64ToDecimal(*listRequest.MinAmount))
+	}
+	if listRequest.MaxAmount != nil {
+		query.MaxAmount = decimal.NewNullDecimal(utils.ConvertFloat64ToDecimal(*listRequest.MaxAmount))
+	}
+	if listRequest.CreatedFrom != "" {
+		createdFrom, err := time.Parse(time.RFC3339, listRequest.CreatedFrom)
+		if err != nil {
+			return nil, err
+		}
+		query.CreatedFrom = &createdFrom
+	}
+	if listRequest.CreatedTo != "" {
+		createdTo, err := time.Parse(time.RFC3339, listRequest.CreatedTo)
+		if err != nil {
+			return nil, err
+		}
+		query.CreatedTo = &createdTo
+	}
+	if listRequest.Cursor != "" {
+		cursor, err := decodeInvoiceCursor(listRequest.Cursor, query.SortBy, query.Descending)
+		if err != nil {
+			log.Warn("Invalid invoice cursor", zap.Error(err))
+			return nil, ErrInvalidCursor
+		}
+		query.After = cursor
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	pageSize := query.Limit
+	query.Limit++
+	invoices, err := is.repo.ListInvoices(ctx, query)
+	if err != nil {
+		log.Error("Failed to list invoices", zap.Error(err))
+		return nil, err
+	}
+
+	response := &dto.ListInvoicesResponse{Data: make([]*dto.InvoiceResponse, 0, pageSize)}
+	if len(invoices) > pageSize {
+		invoices = invoices[:pageSize]
+		last := invoices[pageSize-1]
+		response.NextCursor = encodeInvoiceCursor(&last, query.SortBy, query.Descending)
+	}
+	for i := range invoices {
+		response.Data = append(response.Data, mapper.ToInvoiceResponse(&invoices[i]))
+	}
+	return response, nil
+}
+
+// invoiceCursorToken is the opaque next_cursor handed to clients. The sort column and direction
+// are embedded so a cursor cannot be replayed against a differently ordered listing.
+type invoiceCursorToken struct {
+	Sort       string                   `json:"s"`
+	Descending bool                     `json:"d"`
+	Position   repository.InvoiceCursor `json:"p"`
+}
+
+func encodeInvoiceCursor(invoice *entities.Invoice, sortBy string, descending bool) string {
+	token := invoiceCursorToken{Sort: sortBy, Descending: descending,
+		Position: repository.InvoiceCursor{ID: invoice.ID, Amount: invoice.Amount}}
+	if invoice.CreatedAt != nil {
+		token.Position.CreatedAt = *invoice.CreatedAt
+	}
+	raw, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeInvoiceCursor(cursor, sortBy string, descending bool) (*repository.InvoiceCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	var token invoiceCursorToken
+	if err := json.Unmarshal(raw, &token); err != nil {
+		return nil, err
+	}
+	if token.Sort != sortBy || token.Descending != descending {
+		return nil, errors.New("cursor was issued for a different sort order")
+	}
+	return &token.Position, nil
+}
+
+func (is *invoiceService) ValidateInvoiceRequest(ctx context.Context, invoiceRequest *dto.CreateInvoiceRequest) error {
+	log := logging.FromContext(logging.With(ctx, zap.Uint("merchant_id", invoiceRequest.MerchantID)), is.log)
 	// Validate required fields
 	if invoiceRequest.MerchantID == 0 || invoiceRequest.CustomerID == 0 {
-		return errors.New("merchant ID and customer ID must be provided")
+		return ErrInvalidInvoice.WithMessage("merchant ID and customer ID must be provided")
 	}
 	if utils.ConvertFloat64ToDecimal(invoiceRequest.Amount).LessThanOrEqual(decimal.NewFromInt(0)) {
-		return errors.New("invoice amount must be greater than zero")
+		return ErrInvalidInvoice.WithMessage("invoice amount must be greater than zero")
 	}
 
 	// Check if merchant exists
-	merchantExists, err := is.repo.DoesMerchantExist(invoiceRequest.MerchantID)
+	merchantExists, err := is.repo.DoesMerchantExist(ctx, invoiceRequest.MerchantID)
 	if err != nil {
-		is.log.Error("Error checking merchant existence", zap.Error(err))
-		return errors.New("internal error while validating merchant ID")
+		log.Error("Error checking merchant existence", zap.Error(err))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUnknownMerchant
+		}
+		return apperror.ErrInternal.WithMessage("internal error while validating merchant ID").WithCause(err)
+	}
+	log.Info("Merchant Found against Merchant ID: ", zap.Any("Customer ID", merchantExists.ID))
+	if !merchantExists.IsActive || merchantExists.MerchantStatus == utils.MerchantStatusSuspended {
+		log.Warn("Merchant cannot accept invoices", zap.String("merchant_status", merchantExists.MerchantStatus))
+		return ErrMerchantInactive
 	}
-	is.log.Info("Merchant Found against Merchant ID: ", zap.Any("Customer ID", merchantExists.ID))
 
 	// Check if customer exists
-	customerExists, err := is.repo.DoesCustomerExist(invoiceRequest.CustomerID)
+	customerExists, err := is.repo.DoesCustomerExist(ctx, invoiceRequest.CustomerID)
 	if err != nil {
-		is.log.Error("Error checking customer existence", zap.Error(err))
-		return errors.New("internal error while validating customer ID")
+		log.Error("Error checking customer existence", zap.Error(err))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUnknownCustomer
+		}
+		return apperror.ErrInternal.WithMessage("internal error while validating customer ID").WithCause(err)
 	}
 
-	is.log.Info("Customer Found against Customer ID: ", zap.Any("Customer ID", customerExists.ID))
+	log.Info("Customer Found against Customer ID: ", zap.Any("Customer ID", customerExists.ID))
+	if !customerExists.IsActive {
+		log.Warn("Customer has been deleted", zap.Uint("customer_id", customerExists.ID))
+		return ErrCustomerInactive
+	}
 
-	allowedCurrencies, err := is.repo.GetAllowedCurrenciesForMerchant(invoiceRequest.MerchantID)
+	allowedCurrencies, err := is.repo.GetAllowedCurrenciesForMerchant(ctx, invoiceRequest.MerchantID)
 	if err != nil {
-		is.log.Error("Error fetching allowed currencies for merchant", zap.Error(err))
-		return errors.New("internal error while validating currency")
+		log.Error("Error fetching allowed currencies for merchant", zap.Error(err))
+		return apperror.ErrInternal.WithMessage("internal error while validating currency").WithCause(err)
 	}
 
-	if invoiceRequest.Currency != allowedCurrencies {
-		is.log.Warn("Currency is not allowed for the merchant",
-			zap.Uint("merchant_id", invoiceRequest.MerchantID),
-			zap.String("currency", invoiceRequest.Currency),
-		)
-		return errors.New("currency is not allowed for this merchant")
+	if !utils.IsCurrencyAllowed(invoiceRequest.Currency, utils.SplitCurrencies(allowedCurrencies)) {
+		log.Warn("Currency is not allowed for the merchant", zap.String("currency", invoiceRequest.Currency))
+		return ErrCurrencyNotAllowed
 	}
 
 	return nil
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go/payment-processor/pkg/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvoiceCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC)
+	invoice := entities.Invoice{Amount: decimal.RequireFromString("12.34")}
+	invoice.ID = 42
+	invoice.CreatedAt = &createdAt
+
+	cursor := encodeInvoiceCursor(&invoice, "amount", true)
+	position, err := decodeInvoiceCursor(cursor, "amount", true)
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint(42), position.ID)
+	assert.True(t, createdAt.Equal(position.CreatedAt))
+	assert.True(t, invoice.Amount.Equal(position.Amount))
+}
+
+func TestInvoiceCursorRejectsDifferentOrder(t *testing.T) {
+	invoice := entities.Invoice{}
+	invoice.ID = 1
+
+	cursor := encodeInvoiceCursor(&invoice, "created_at", true)
+
+	_, err := decodeInvoiceCursor(cursor, "created_at", false)
+	assert.NotNil(t, err)
+	_, err = decodeInvoiceCursor(cursor, "amount", true)
+	assert.NotNil(t, err)
+	_, err = decodeInvoiceCursor("not a cursor", "created_at", true)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/logging"
+	"go/payment-processor/pkg/mapper"
+	"go/payment-processor/pkg/repository"
+	"go/payment-processor/pkg/utils"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var ErrMerchantCodeTaken = apperror.New(http.StatusConflict, "merchant_code_taken", "merchant code is already in use")
+
+type MerchantService interface {
+	CreateMerchant(ctx context.Context, merchantRequest *dto.CreateMerchantRequest) (*dto.MerchantResponse, error)
+	GetMerchantByID(ctx context.Context, id uint) (*dto.MerchantResponse, error)
+	UpdateMerchant(ctx context.Context, id uint, merchantRequest *dto.UpdateMerchantRequest) (*dto.MerchantResponse, error)
+	DeleteMerchant(ctx context.Context, id uint) error
+}
+
+type merchantService struct {
+	log       *zap.Logger
+	repo      repository.Repository
+	validator *validator.Validate
+}
+
+func NewMerchantService(log *zap.Logger, repo repository.Repository, validator *validator.Validate) MerchantService {
+	return &merchantService{log: log,
+		repo: repo, validator: validator}
+}
+
+func (ms *merchantService) CreateMerchant(ctx context.Context, merchantRequest *dto.CreateMerchantRequest) (*dto.MerchantResponse, error) {
+	log := logging.FromContext(ctx, ms.log)
+	log.Info("Attempting to create a new merchant", zap.Any("merchant", merchantRequest))
+
+	err := authorize(ctx, auth.PermissionMerchantsWrite)
+	if err != nil {
+		log.Warn("Not permitted to create merchants", zap.Error(err))
+		return nil, err
+	}
+
+	merchantRequest.MerchantCode = strings.ToUpper(strings.TrimSpace(merchantRequest.MerchantCode))
+	if err := ms.ensureMerchantCodeAvailable(ctx, merchantRequest.MerchantCode, 0); err != nil {
+		return nil, err
+	}
+
+	createdMerchant, err := ms.repo.CreateMerchant(ctx, mapper.ToMerchantEntity(merchantRequest))
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// Another request took the code between the check and the insert
+		log.Warn("Merchant code already in use", zap.String("merchant_code", merchantRequest.MerchantCode))
+		return nil, ErrMerchantCodeTaken
+	}
+	if err != nil {
+		log.Error("Failed to create merchant", zap.Error(err))
+		return nil, err
+	}
+
+	log.Info("Merchant created successfully", zap.Uint("merchant_id", createdMerchant.ID))
+	return mapper.ToMerchantResponse(createdMerchant), nil
+}
+
+func (ms *merchantService) GetMerchantByID(ctx context.Context, id uint) (*dto.MerchantResponse, error) {
+	log := logging.FromContext(ctx, ms.log)
+	log.Info("Fetching merchant by ID", zap.Uint("merchant_id", id))
+
+	merchant, err := ms.repo.GetMerchantByID(ctx, id)
+	if err != nil {
+		log.Error("Merchant not found", zap.Uint("merchant_id", id), zap.Error(err))
+		return nil, err
+	}
+
+	return mapper.ToMerchantResponse(merchant), nil
+}
+
+func (ms *merchantService) UpdateMerchant(ctx context.Context, id uint, merchantRequest *dto.UpdateMerchantRequest) (*dto.MerchantResponse, error) {
+	log := logging.FromContext(ctx, ms.log)
+	log.Info("Updating merchant", zap.Uint("merchant_id", id), zap.Any("merchant", merchantRequest))
+
+	err := authorize(ctx, auth.PermissionMerchantsWrite)
+	if err != nil {
+		log.Warn("Not permitted to update merchants", zap.Error(err))
+		return nil, err
+	}
+
+	merchant, err := ms.repo.GetMerchantByID(ctx, id)
+	if err != nil {
+		log.Error("Merchant not found", zap.Uint("merchant_id", id), zap.Error(err))
+		return nil, err
+	}
+
+	if merchantRequest.MerchantName != nil {
+		merchant.MerchantName = *merchantRequest.MerchantName
+	}
+	if merchantRequest.MerchantCode != nil {
+		code := strings.ToUpper(strings.TrimSpace(*merchantRequest.MerchantCode))
+		if code != merchant.MerchantCode {
+			if err := ms.ensureMerchantCodeAvailable(ctx, code, merchant.ID); err != nil {
+				return nil, err
+			}
+			merchant.MerchantCode = code
+		}
+	}
+	if merchantRequest.AllowedCurrencies != nil {
+		merchant.AllowedCurrency = utils.JoinCurrencies(merchantRequest.AllowedCurrencies)
+	}
+	if merchantRequest.MerchantStatus != nil {
+		merchant.MerchantStatus = *merchantRequest.MerchantStatus
+	}
+
+	updatedMerchant, err := ms.repo.UpdateMerchant(ctx, merchant)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		log.Warn("Merchant code already in use", zap.String("merchant_code", merchant.MerchantCode))
+		return nil, ErrMerchantCodeTaken
+	}
+	if err != nil {
+		log.Error("Failed to update merchant", zap.Uint("merchant_id", id), zap.Error(err))
+		return nil, err
+	}
+
+	log.Info("Merchant updated successfully", zap.Uint("merchant_id", id))
+	return mapper.ToMerchantResponse(updatedMerchant), nil
+}
+
+// DeleteMerchant soft-deletes the merchant; its invoices and payments are kept
+func (ms *merchantService) DeleteMerchant(ctx context.Context, id uint) error {
+	log := logging.FromContext(ctx, ms.log)
+	log.Info("Deleting merchant", zap.Uint("merchant_id", id))
+
+	err := authorize(ctx, auth.PermissionMerchantsWrite)
+	if err != nil {
+		log.Warn("Not permitted to delete merchants", zap.Error(err))
+		return err
+	}
+
+	if err := ms.repo.DeleteMerchant(ctx, id); err != nil {
+		log.Error("Failed to delete merchant", zap.Uint("merchant_id", id), zap.Error(err))
+		return err
+	}
+
+	log.Info("Merchant deleted successfully", zap.Uint("merchant_id", id))
+	return nil
+}
+
+// ensureMerchantCodeAvailable fails if another merchant (including a soft-deleted one) already uses the code
+func (ms *merchantService) ensureMerchantCodeAvailable(ctx context.Context, code string, merchantID uint) error {
+	log := logging.FromContext(ctx, ms.log)
+	existing, err := ms.repo.GetMerchantByCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		log.Error("Error checking merchant code", zap.Error(err))
+		return err
+	}
+	if existing.ID != merchantID {
+		log.Warn("Merchant code already in use", zap.String("merchant_code", code))
+		return ErrMerchantCodeTaken
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/repository"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// fakeMerchantRepository stores merchants by code. Codes in racing are taken by another request
+// between the availability check and the insert.
+type fakeMerchantRepository struct {
+	repository.Repository
+	merchants map[string]*entities.Merchant
+	racing    map[string]bool
+}
+
+func (r *fakeMerchantRepository) GetMerchantByCode(_ context.Context, code string) (*entities.Merchant, error) {
+	if merchant, ok := r.merchants[code]; ok {
+		return merchant, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeMerchantRepository) CreateMerchant(_ context.Context, merchant *entities.Merchant) (*entities.Merchant, error) {
+	if _, ok := r.merchants[merchant.MerchantCode]; ok || r.racing[merchant.MerchantCode] {
+		return nil, gorm.ErrDuplicatedKey
+	}
+	merchant.ID = uint(len(r.merchants) + 1)
+	r.merchants[merchant.MerchantCode] = merchant
+	return merchant, nil
+}
+
+func TestCreateMerchant(t *testing.T) {
+	repo := &fakeMerchantRepository{merchants: map[string]*entities.Merchant{}, racing: map[string]bool{"RACED": true}}
+	service := NewMerchantService(zap.NewNop(), repo, validator.New())
+	ctx := auth.ContextWithPrincipal(context.Background(), auth.SystemPrincipal("test"))
+	request := func(code string) *dto.CreateMerchantRequest {
+		return &dto.CreateMerchantRequest{MerchantName: "Acme", MerchantCode: code, AllowedCurrencies: []string{"USD"}}
+	}
+
+	merchant, err := service.CreateMerchant(ctx, request(" acme1 "))
+	assert.NoError(t, err)
+	assert.Equal(t, "ACME1", merchant.MerchantCode)
+
+	// Taken before the request, caught by the availability check
+	_, err = service.CreateMerchant(ctx, request("ACME1"))
+	assert.ErrorIs(t, err, ErrMerchantCodeTaken)
+
+	// Taken by a concurrent request, caught by the unique constraint
+	_, err = service.CreateMerchant(ctx, request("RACED"))
+	assert.ErrorIs(t, err, ErrMerchantCodeTaken)
+}
@@ -1,90 +1,674 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"go/payment-processor/pkg/apperror"
+	"go/payment-processor/pkg/auth"
 	"go/payment-processor/pkg/dto"
 	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/idempotency"
+	"go/payment-processor/pkg/logging"
 	"go/payment-processor/pkg/mapper"
+	"go/payment-processor/pkg/metrics"
+	"go/payment-processor/pkg/payments/inflight"
+	"go/payment-processor/pkg/payments/provider"
 	"go/payment-processor/pkg/repository"
+	"go/payment-processor/pkg/risk"
+	"go/payment-processor/pkg/tracing"
 	"go/payment-processor/pkg/utils"
+	"go/payment-processor/pkg/vault"
+	"go/payment-processor/pkg/velocity"
+	"net/http"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// defaultPaymentReviewPageSize applies when the review queue is listed without a page size
+const defaultPaymentReviewPageSize = 50
+
+var (
+	ErrInvalidPayment          = apperror.New(http.StatusBadRequest, "invalid_payment", "invalid payment data")
+	ErrPaymentNotPendingReview = apperror.New(http.StatusConflict, "payment_not_pending_review", "payment is not pending review")
+	ErrInvoiceNotPayable       = apperror.New(http.StatusConflict, "invoice_not_payable", "invoice is no longer awaiting payment")
+	ErrInvoiceAlreadyPaid      = apperror.New(http.StatusConflict, "invoice_already_paid", "invoice has already been paid")
+	// ErrPaymentInProgress is returned while another payment for the invoice is with the provider
+	ErrPaymentInProgress = apperror.New(http.StatusConflict, "payment_in_progress", "another payment for this invoice is in progress")
+	// ErrProviderUnavailable is returned when the payment provider could not be reached; the payment was not charged
+	ErrProviderUnavailable = apperror.New(http.StatusServiceUnavailable, "provider_unavailable", "payment provider is unavailable, try again later")
+	// ErrPaymentOutcomeUnknown is returned when the provider call failed without saying whether the payment was
+	// charged; the payment is kept for reconciliation against the provider
+	ErrPaymentOutcomeUnknown = apperror.New(http.StatusBadGateway, "payment_outcome_unknown",
+		"payment provider did not confirm the outcome, the payment may have been charged and will be reconciled")
+	// ErrTooManyDeclines is returned while a card, customer or merchant is temporarily blocked after repeated declines
+	ErrTooManyDeclines         = apperror.New(http.StatusTooManyRequests, "too_many_declines", "too many declined payments, try again later")
+	ErrPaymentNoActionRequired = apperror.New(http.StatusConflict, "payment_no_action_required", "payment is not waiting for a challenge")
 )
 
 type PaymentService interface {
-	ProcessPayment(paymentRequest *dto.ProcessPaymentRequest) (*entities.Payment, error)
-	GetPaymentStatus(invoiceID uint) (string, error)
-	GetPaymentStatusBasedOnPaymentSource(paymentSource string) string
+	ProcessPayment(ctx context.Context, paymentRequest *dto.ProcessPaymentRequest) (*entities.Payment, error)
+	GetPaymentStatus(ctx context.Context, invoiceID uint) (string, error)
+	ListPaymentReviews(ctx context.Context, listRequest *dto.ListPaymentReviewsRequest) ([]*dto.PaymentReviewResponse, error)
+	ReviewPayment(ctx context.Context, reviewRequest *dto.ReviewPaymentRequest) (*entities.Payment, error)
+	VelocityMetrics() *velocity.Metrics
+	CompletePaymentChallenge(ctx context.Context, challengeRequest *dto.CompleteChallengeRequest) (*entities.Payment, error)
+}
+
+// PaymentGateway is the part of the payment provider the services depend on.
+type PaymentGateway interface {
+	Pay(ctx context.Context, details provider.PaymentDetails) (provider.Payment, error)
+	CompleteChallenge(ctx context.Context, id uuid.UUID, authenticated bool) (provider.Payment, error)
+	ByID(id uuid.UUID) (provider.PaymentStatus, bool)
+	ByReferenceID(id uuid.UUID) (provider.PaymentStatus, bool)
 }
 
 type paymentService struct {
 	log       *zap.Logger
 	repo      repository.Repository
 	validator *validator.Validate
+	gateway   PaymentGateway
+	vault     *vault.Vault
+	// fingerprints identifies the payment source for the velocity rules
+	fingerprints *vault.Fingerprinter
+	risk         *risk.Engine
+	velocity     *velocity.Guard
+	inFlight     *inflight.Tracker
+	metrics      *metrics.Metrics
 }
 
-func NewPaymentService(log *zap.Logger, repo repository.Repository, validator *validator.Validate) PaymentService {
+// NewPaymentService builds the payment service. Without a risk engine every payment is sent to the
+// provider, without a velocity guard declines are never throttled. Payments being authorized are
+// recorded in inFlight and attempts and provider calls in m, both of which may be nil.
+func NewPaymentService(log *zap.Logger, repo repository.Repository, validator *validator.Validate, gateway PaymentGateway,
+	vault *vault.Vault, fingerprints *vault.Fingerprinter, riskEngine *risk.Engine, velocityGuard *velocity.Guard, inFlight *inflight.Tracker, m *metrics.Metrics) PaymentService {
 	return &paymentService{log: log,
-		repo: repo, validator: validator}
+		repo: repo, validator: validator, gateway: gateway, vault: vault, fingerprints: fingerprints, risk: riskEngine, velocity: velocityGuard, inFlight: inFlight,
+		metrics: m}
 }
 
 // ProcessPayment - Business logic for processing payments
-func (s *paymentService) ProcessPayment(paymentRequest *dto.ProcessPaymentRequest) (*entities.Payment, error) {
-	s.log.Info("Processing payment", zap.Any("payment", paymentRequest))
+func (s *paymentService) ProcessPayment(ctx context.Context, paymentRequest *dto.ProcessPaymentRequest) (_ *entities.Payment, err error) {
+	ctx = logging.With(ctx, zap.Uint("invoice_id", paymentRequest.InvoiceID))
+	log := logging.FromContext(ctx, s.log)
+	log.Info("Processing payment", zap.Any("payment", paymentRequest))
+	ctx, span := tracing.Start(ctx, "PaymentService.ProcessPayment", attribute.Int64("invoice.id", int64(paymentRequest.InvoiceID)),
+		attribute.String("payment.method", paymentRequest.PaymentMethod))
+	defer tracing.End(span, &err)
 
-	if paymentRequest.InvoiceID == 0 {
-		err := errors.New("invalid payment data")
-		s.log.Error("Invalid payment data", zap.Error(err))
+	err = authorize(ctx, auth.PermissionPaymentsWrite)
+	if err != nil {
+		log.Warn("Not permitted to process payments", zap.Error(err))
 		return nil, err
 	}
 
-	invoice, err := s.repo.DoesInvoiceExist(paymentRequest.InvoiceID)
+	if paymentRequest.InvoiceID == 0 || (paymentRequest.PaymentSource == "" && paymentRequest.PaymentMethodID == 0) {
+		log.Error("Invalid payment data", zap.Error(ErrInvalidPayment))
+		return nil, ErrInvalidPayment
+	}
+
+	invoice, err := s.repo.DoesInvoiceExist(ctx, paymentRequest.InvoiceID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && (invoice == nil || invoice.ID == 0)) {
+		log.Warn("Invoice not found")
+		return nil, ErrInvoiceNotFound
+	}
 	if err != nil {
-		s.log.Error("Error checking invoice existence", zap.Error(err))
-		return nil, errors.New("internal error while validating invoice ID")
+		log.Error("Error checking invoice existence", zap.Error(err))
+		return nil, apperror.ErrInternal.WithMessage("internal error while validating invoice ID").WithCause(err)
+	}
+	if err := checkInvoicePayable(invoice); err != nil {
+		log.Warn("Invoice cannot be paid", zap.String("invoice_status", invoice.InvoiceStatus))
+		return nil, err
 	}
 
 	//TO DO: Implement Encryption/Decryption logic for Payment Source (BAN, Card Number)
 	payment := mapper.ToPaymentEntity(paymentRequest)
-	payment.PaymentStatus = s.GetPaymentStatusBasedOnPaymentSource(paymentRequest.PaymentSource)
 	payment.Amount = invoice.Amount
 	payment.InvoiceID = invoice.ID
 	payment.CustomerID = invoice.CustomerID
 	payment.MerchantID = invoice.MerchantID
-	processedPayment, err := s.repo.ProcessPayment(payment)
+	payment.ClientIP = paymentRequest.ClientIP
+	if paymentRequest.PaymentMethodID != 0 {
+		if err := s.applySavedPaymentMethod(ctx, payment, paymentRequest.PaymentMethodID, invoice.CustomerID); err != nil {
+			return nil, err
+		}
+	}
+	source := payment.PaymentSource
+	payment.SourceFingerprint = s.fingerprints.Fingerprint(source)
+	if payment.PaymentMethodID != nil {
+		// Saved sources never leave the vault unmasked
+		payment.PaymentSource = utils.MaskPaymentSource(source)
+	}
+
+	if err := s.checkVelocity(ctx, payment); err != nil {
+		return nil, err
+	}
+
+	if s.risk != nil {
+		assessment, err := s.assessRisk(ctx, payment, source, paymentRequest.BillingCountry)
+		if err != nil {
+			return nil, err
+		}
+		if assessment.Decision != risk.DecisionAllow {
+			return s.holdPayment(ctx, payment, assessment.Decision)
+		}
+	}
+
+	if err := s.claimInvoice(ctx, invoice.ID); err != nil {
+		return nil, err
+	}
+	return s.authorizePayment(ctx, payment, source, invoice.Currency)
+}
+
+// authorizePayment sends the payment to the provider, stores the outcome and marks the invoice paid on success.
+// The caller must have claimed the invoice, which is released again if nothing was charged.
+// Once the provider is called the payment is finished even if the caller goes away, so a charge is never
+// left without its payment row.
+func (s *paymentService) authorizePayment(ctx context.Context, payment *entities.Payment, source, currency string) (*entities.Payment, error) {
+	log := logging.FromContext(ctx, s.log)
+	referenceID, err := uuid.NewV7()
 	if err != nil {
-		s.log.Error("Failed to process payment", zap.Error(err))
+		log.Error("Failed to generate payment reference", zap.Error(err))
 		return nil, err
 	}
+	stored := payment.PaymentSource
+	payment.PaymentSource = source
+	details := mapper.ToPaymentDetails(payment, referenceID, currency)
+	payment.PaymentSource = stored
+	payment.ReferenceID = referenceID.String()
+
+	ctx = context.WithoutCancel(ctx)
+	done := s.inFlight.Begin(*payment)
+	defer done()
+
+	start := time.Now()
+	providerPayment, err := s.gateway.Pay(ctx, details)
+	s.metrics.ObserveProviderCall("pay", time.Since(start), err)
+	if !errors.Is(err, provider.ErrUnavailable) {
+		// The payment may have been charged, so a retry must not send it again
+		idempotency.Commit(ctx)
+	}
+	if err != nil {
+		log.Error("Payment provider call failed", zap.Error(err))
+		if errors.Is(err, provider.ErrUnavailable) {
+			s.releaseInvoice(ctx, payment.InvoiceID)
+		}
+		return nil, s.providerFailed(ctx, payment, err)
+	}
+	payment.ProviderPaymentID = providerPayment.ID.String()
+	return s.settlePayment(ctx, payment, providerPayment.Status)
+}
+
+// settlePayment stores the provider outcome of a payment, counts declines and marks the invoice paid on success.
+// A declined payment releases the invoice for the next attempt.
+func (s *paymentService) settlePayment(ctx context.Context, payment *entities.Payment, status provider.PaymentStatus) (*entities.Payment, error) {
+	log := logging.FromContext(ctx, s.log)
+	payment.PaymentStatus = mapper.ToPaymentStatus(status)
 
-	s.log.Info("Payment processed successfully", zap.Uint("payment_id", processedPayment.ID))
+	var processedPayment *entities.Payment
+	var err error
+	if payment.ID == 0 {
+		processedPayment, err = s.repo.ProcessPayment(ctx, payment)
+	} else {
+		processedPayment, err = s.repo.UpdatePayment(ctx, payment)
+	}
+	if err != nil {
+		log.Error("Failed to process payment", zap.Error(err))
+		return nil, err
+	}
+
+	s.countAttempt(processedPayment)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("payment.status", processedPayment.PaymentStatus))
+	if isDeclined(processedPayment.PaymentStatus) {
+		s.recordDecline(ctx, processedPayment)
+		s.releaseInvoice(ctx, processedPayment.InvoiceID)
+	}
+
+	if processedPayment.PaymentStatus == utils.PaymentStatusSuccess {
+		if err := s.repo.UpdateInvoiceStatus(ctx, processedPayment.InvoiceID, utils.InvoiceStatusPaid); err != nil {
+			log.Error("Failed to mark invoice as paid", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	log.Info("Payment processed successfully", zap.Uint("payment_id", processedPayment.ID),
+		zap.String("status", processedPayment.PaymentStatus))
 	return processedPayment, nil
 }
 
-func (s *paymentService) GetPaymentStatus(invoiceID uint) (string, error) {
-	s.log.Info("Fetching payment status", zap.Uint("invoice_id", invoiceID))
+// CompletePaymentChallenge reports the outcome of the 3-D Secure challenge of a payment to the
+// provider, which then authorizes or fails the payment.
+func (s *paymentService) CompletePaymentChallenge(ctx context.Context, challengeRequest *dto.CompleteChallengeRequest) (_ *entities.Payment, err error) {
+	ctx = logging.With(ctx, zap.Uint("invoice_id", challengeRequest.InvoiceID))
+	log := logging.FromContext(ctx, s.log)
+	log.Info("Completing payment challenge", zap.Uint("payment_id", challengeRequest.PaymentID), zap.String("result", challengeRequest.Result))
+	ctx, span := tracing.Start(ctx, "PaymentService.CompletePaymentChallenge", attribute.Int64("payment.id", int64(challengeRequest.PaymentID)))
+	defer tracing.End(span, &err)
+
+	err = authorize(ctx, auth.PermissionPaymentsWrite)
+	if err != nil {
+		log.Warn("Not permitted to complete payment challenges", zap.Error(err))
+		return nil, err
+	}
+
+	payment, err := s.repo.GetPaymentByID(ctx, challengeRequest.PaymentID)
+	if err != nil || payment.InvoiceID != challengeRequest.InvoiceID {
+		log.Error("Payment not found", zap.Uint("payment_id", challengeRequest.PaymentID), zap.Error(err))
+		return nil, gorm.ErrRecordNotFound
+	}
+	if payment.PaymentStatus != utils.PaymentStatusRequiresAction {
+		return nil, ErrPaymentNoActionRequired
+	}
+	providerPaymentID, err := uuid.Parse(payment.ProviderPaymentID)
+	if err != nil {
+		log.Error("Payment has no valid provider ID", zap.Uint("payment_id", payment.ID), zap.Error(err))
+		return nil, err
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	done := s.inFlight.Begin(*payment)
+	defer done()
+
+	start := time.Now()
+	providerPayment, err := s.gateway.CompleteChallenge(ctx, providerPaymentID, challengeRequest.Result == utils.ChallengeResultSuccess)
+	s.metrics.ObserveProviderCall("complete_challenge", time.Since(start), err)
+	if !errors.Is(err, provider.ErrUnavailable) && !errors.Is(err, provider.ErrChallengeNotFound) {
+		idempotency.Commit(ctx)
+	}
+	if err != nil {
+		log.Error("Payment provider challenge completion failed", zap.Uint("payment_id", payment.ID), zap.Error(err))
+		if errors.Is(err, provider.ErrChallengeNotFound) {
+			return nil, ErrPaymentNoActionRequired
+		}
+		return nil, s.providerFailed(ctx, payment, err)
+	}
+	return s.settlePayment(ctx, payment, providerPayment.Status)
+}
+
+// providerFailed turns a failed provider call into the error returned to the client. Only
+// provider.ErrUnavailable means nothing was charged; after any other failure the payment may have gone
+// through, so it is marked for reconciliation against the provider instead of being reported as not charged.
+func (s *paymentService) providerFailed(ctx context.Context, payment *entities.Payment, err error) error {
+	log := logging.FromContext(ctx, s.log)
+	if errors.Is(err, provider.ErrUnavailable) {
+		return ErrProviderUnavailable.WithCause(err)
+	}
+
+	log.Warn("Payment outcome unknown, marking it for reconciliation", zap.Uint("payment_id", payment.ID),
+		zap.String("reference_id", payment.ReferenceID))
+	var markErr error
+	if payment.ID == 0 {
+		payment.PaymentStatus = utils.PaymentStatusPendingReconciliation
+		_, markErr = s.repo.ProcessPayment(ctx, payment)
+	} else {
+		markErr = s.repo.UpdatePaymentStatus(ctx, payment.ID, utils.PaymentStatusPendingReconciliation)
+	}
+	if markErr != nil {
+		log.Error("Failed to mark payment for reconciliation", zap.String("reference_id", payment.ReferenceID), zap.Error(markErr))
+	}
+	return ErrPaymentOutcomeUnknown.WithCause(err)
+}
+
+// checkInvoicePayable fails unless the invoice is still awaiting payment
+func checkInvoicePayable(invoice *entities.Invoice) error {
+	switch invoice.InvoiceStatus {
+	case utils.InvoiceStatusPending:
+		return nil
+	case utils.InvoiceStatusProcessing:
+		return ErrPaymentInProgress
+	case utils.InvoiceStatusPaid:
+		return ErrInvoiceAlreadyPaid
+	default:
+		return ErrInvoiceNotPayable
+	}
+}
+
+// claimInvoice moves a pending invoice to processing before a payment for it is sent to the provider.
+// Of concurrent payments for the same invoice only one claims it, the others fail as checkInvoicePayable would.
+func (s *paymentService) claimInvoice(ctx context.Context, invoiceID uint) error {
+	log := logging.FromContext(ctx, s.log)
+	err := s.repo.ClaimInvoice(ctx, invoiceID, utils.InvoiceStatusPending, utils.InvoiceStatusProcessing)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Warn("Invoice is being paid concurrently", zap.Uint("invoice_id", invoiceID))
+		invoice, err := s.repo.GetInvoiceByID(ctx, invoiceID)
+		if err != nil {
+			return ErrPaymentInProgress
+		}
+		if err := checkInvoicePayable(invoice); err != nil {
+			return err
+		}
+		return ErrPaymentInProgress
+	}
+	if err != nil {
+		log.Error("Failed to claim invoice for payment", zap.Uint("invoice_id", invoiceID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// releaseInvoice returns a claimed invoice to pending once its payment is known not to have been charged
+func (s *paymentService) releaseInvoice(ctx context.Context, invoiceID uint) {
+	log := logging.FromContext(ctx, s.log)
+	err := s.repo.ClaimInvoice(context.WithoutCancel(ctx), invoiceID, utils.InvoiceStatusProcessing, utils.InvoiceStatusPending)
+	if err != nil {
+		log.Error("Failed to release invoice", zap.Uint("invoice_id", invoiceID), zap.Error(err))
+	}
+}
+
+// checkVelocity fails with ErrTooManyDeclines while the card, customer or merchant of the payment is blocked
+func (s *paymentService) checkVelocity(ctx context.Context, payment *entities.Payment) error {
+	log := logging.FromContext(ctx, s.log)
+	if s.velocity == nil {
+		return nil
+	}
+
+	block, err := s.velocity.Check(ctx, velocitySubject(payment))
+	if err != nil {
+		// A failing counter store must not stop payments
+		log.Warn("Failed to check decline velocity", zap.Error(err))
+		return nil
+	}
+	if block != nil {
+		log.Warn("Payment attempt blocked after repeated declines", zap.String("dimension", block.Dimension),
+			zap.Time("until", block.Until))
+		return ErrTooManyDeclines.WithRetryAfter(time.Until(block.Until))
+	}
+	return nil
+}
+
+// recordDecline counts a declined payment towards the velocity limits
+func (s *paymentService) recordDecline(ctx context.Context, payment *entities.Payment) {
+	log := logging.FromContext(ctx, s.log)
+	if s.velocity == nil {
+		return
+	}
+
+	blocks, err := s.velocity.RecordDecline(ctx, velocitySubject(payment))
+	if err != nil {
+		log.Warn("Failed to record declined payment", zap.Uint("payment_id", payment.ID), zap.Error(err))
+	}
+	for _, block := range blocks {
+		log.Warn("Temporarily blocking payments after repeated declines", zap.Uint("payment_id", payment.ID),
+			zap.String("dimension", block.Dimension), zap.Time("until", block.Until))
+	}
+}
+
+// VelocityMetrics returns the decline and block counters, or nil without a velocity guard
+func (s *paymentService) VelocityMetrics() *velocity.Metrics {
+	if s.velocity == nil {
+		return nil
+	}
+	metrics := s.velocity.Metrics()
+	return &metrics
+}
+
+func velocitySubject(payment *entities.Payment) velocity.Subject {
+	return velocity.Subject{CardFingerprint: payment.SourceFingerprint, CustomerID: payment.CustomerID, MerchantID: payment.MerchantID}
+}
+
+func isDeclined(status string) bool {
+	for _, declined := range utils.PaymentStatusesDeclined {
+		if status == declined {
+			return true
+		}
+	}
+	return false
+}
+
+// assessRisk gathers the velocity signals of the payment, evaluates them and records the assessment on the payment
+func (s *paymentService) assessRisk(ctx context.Context, payment *entities.Payment, source, billingCountry string) (risk.Assessment, error) {
+	log := logging.FromContext(ctx, s.log)
+	since := time.Now().Add(-s.risk.Window())
+	signals := risk.Signals{Amount: payment.Amount, BillingCountry: billingCountry}
+	if !strings.EqualFold(payment.PaymentMethod, utils.PaymentMethodBankTransfer) {
+		signals.CardNumber = source
+	}
+
+	type signalCount struct {
+		target *int
+		query  repository.PaymentCountQuery
+	}
+	counts := []signalCount{
+		{&signals.CardAttempts, repository.PaymentCountQuery{SourceFingerprint: payment.SourceFingerprint, Since: since}},
+		{&signals.CustomerAttempts, repository.PaymentCountQuery{CustomerID: payment.CustomerID, Since: since}},
+		{&signals.CardDeclines, repository.PaymentCountQuery{SourceFingerprint: payment.SourceFingerprint, Statuses: utils.PaymentStatusesDeclined, Since: since}},
+	}
+	if payment.ClientIP != "" {
+		counts = append(counts, signalCount{&signals.IPAttempts, repository.PaymentCountQuery{ClientIP: payment.ClientIP, Since: since}})
+	}
+	for _, count := range counts {
+		n, err := s.repo.CountPayments(ctx, count.query)
+		if err != nil {
+			log.Error("Failed to count recent payments for risk assessment", zap.Error(err))
+			return risk.Assessment{}, err
+		}
+		*count.target = int(n)
+	}
+
+	assessment := s.risk.Evaluate(signals)
+	reasons, err := json.Marshal(assessment.Reasons)
+	if err != nil {
+		return risk.Assessment{}, err
+	}
+	payment.RiskDecision = assessment.Decision
+	payment.RiskScore = assessment.Score
+	payment.RiskReasons = string(reasons)
+
+	log.Info("Payment risk assessed", zap.String("decision", assessment.Decision), zap.Int("score", assessment.Score))
+	return assessment, nil
+}
+
+// holdPayment stores a payment the risk engine did not allow without sending it to the provider
+func (s *paymentService) holdPayment(ctx context.Context, payment *entities.Payment, decision string) (*entities.Payment, error) {
+	log := logging.FromContext(ctx, s.log)
+	payment.PaymentStatus = utils.PaymentStatusBlocked
+	if decision == risk.DecisionReview {
+		payment.PaymentStatus = utils.PaymentStatusPendingReview
+	}
+
+	heldPayment, err := s.repo.ProcessPayment(ctx, payment)
+	if err != nil {
+		log.Error("Failed to store held payment", zap.Error(err))
+		return nil, err
+	}
+
+	s.countAttempt(heldPayment)
+	log.Warn("Payment held by risk engine", zap.Uint("payment_id", heldPayment.ID), zap.String("status", heldPayment.PaymentStatus))
+	return heldPayment, nil
+}
+
+// countAttempt records the status a payment reached, with the decline code the client sees
+func (s *paymentService) countAttempt(payment *entities.Payment) {
+	s.metrics.PaymentAttempt(payment.PaymentMethod, payment.PaymentStatus, mapper.ToDeclineCode(payment.PaymentStatus))
+}
+
+// ListPaymentReviews returns the manual review queue, oldest first
+func (s *paymentService) ListPaymentReviews(ctx context.Context, listRequest *dto.ListPaymentReviewsRequest) ([]*dto.PaymentReviewResponse, error) {
+	log := logging.FromContext(ctx, s.log)
+	limit := listRequest.Limit
+	if limit == 0 {
+		limit = defaultPaymentReviewPageSize
+	}
 
-	status, err := s.repo.GetPaymentStatus(invoiceID)
+	payments, err := s.repo.GetPaymentsByStatus(ctx, utils.PaymentStatusPendingReview, listRequest.MerchantID, limit)
 	if err != nil {
-		s.log.Error("Failed to fetch payment status", zap.Uint("invoice_id", invoiceID), zap.Error(err))
+		log.Error("Failed to list payments pending review", zap.Error(err))
+		return nil, err
+	}
+
+	responses := make([]*dto.PaymentReviewResponse, 0, len(payments))
+	for i := range payments {
+		responses = append(responses, mapper.ToPaymentReviewResponse(&payments[i]))
+	}
+	return responses, nil
+}
+
+// ReviewPayment settles a payment held for review. Approved payments are sent to the provider,
+// rejected ones are never charged.
+func (s *paymentService) ReviewPayment(ctx context.Context, reviewRequest *dto.ReviewPaymentRequest) (_ *entities.Payment, err error) {
+	log := logging.FromContext(ctx, s.log)
+	log.Info("Reviewing payment", zap.Uint("payment_id", reviewRequest.PaymentID), zap.String("decision", reviewRequest.Decision))
+	ctx, span := tracing.Start(ctx, "PaymentService.ReviewPayment", attribute.Int64("payment.id", int64(reviewRequest.PaymentID)))
+	defer tracing.End(span, &err)
+
+	err = authorize(ctx, auth.PermissionPaymentsReview)
+	if err != nil {
+		log.Warn("Not permitted to review payments", zap.Error(err))
+		return nil, err
+	}
+
+	payment, err := s.repo.GetPaymentByID(ctx, reviewRequest.PaymentID)
+	if err != nil {
+		log.Error("Payment not found", zap.Uint("payment_id", reviewRequest.PaymentID), zap.Error(err))
+		return nil, err
+	}
+	// Reviewers are not tied to a merchant, the payment tells which merchant and invoice the review concerns
+	ctx = logging.With(ctx, zap.Uint("merchant_id", payment.MerchantID), zap.Uint("invoice_id", payment.InvoiceID))
+	log = logging.FromContext(ctx, s.log)
+	if payment.PaymentStatus != utils.PaymentStatusPendingReview {
+		return nil, ErrPaymentNotPendingReview
+	}
+	payment.ReviewNote = reviewRequest.Note
+
+	if reviewRequest.Decision == utils.ReviewDecisionReject {
+		if err := s.claimReview(ctx, payment.ID, utils.PaymentStatusRejected); err != nil {
+			return nil, err
+		}
+		payment.PaymentStatus = utils.PaymentStatusRejected
+		rejectedPayment, err := s.repo.UpdatePayment(ctx, payment)
+		if err != nil {
+			log.Error("Failed to reject payment", zap.Uint("payment_id", payment.ID), zap.Error(err))
+			return nil, err
+		}
+		log.Info("Payment rejected after review", zap.Uint("payment_id", payment.ID))
+		return rejectedPayment, nil
+	}
+
+	invoice, err := s.repo.GetInvoiceByID(ctx, payment.InvoiceID)
+	if err != nil {
+		log.Error("Invoice not found", zap.Error(err))
+		return nil, err
+	}
+	if err := checkInvoicePayable(invoice); err != nil {
+		return nil, err
+	}
+
+	source := payment.PaymentSource
+	if payment.PaymentMethodID != nil {
+		if source, err = s.savedPaymentSource(ctx, *payment.PaymentMethodID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Until the provider outcome is stored the approved payment waits for reconciliation, as it would
+	// if the server stopped while it was sent to the provider
+	if err := s.claimReview(ctx, payment.ID, utils.PaymentStatusPendingReconciliation); err != nil {
+		return nil, err
+	}
+	if err := s.claimInvoice(ctx, invoice.ID); err != nil {
+		s.returnToReview(ctx, payment.ID)
+		return nil, err
+	}
+	authorizedPayment, err := s.authorizePayment(ctx, payment, source, invoice.Currency)
+	if errors.Is(err, ErrProviderUnavailable) {
+		s.returnToReview(ctx, payment.ID)
+	}
+	return authorizedPayment, err
+}
+
+// returnToReview puts an approved payment that was not charged back into the review queue
+func (s *paymentService) returnToReview(ctx context.Context, paymentID uint) {
+	log := logging.FromContext(ctx, s.log)
+	if err := s.repo.UpdatePaymentStatus(context.WithoutCancel(ctx), paymentID, utils.PaymentStatusPendingReview); err != nil {
+		log.Error("Failed to return payment to review", zap.Uint("payment_id", paymentID), zap.Error(err))
+	}
+}
+
+// claimReview moves a payment out of review into status. Only one of several reviewers deciding on
+// the same payment at once succeeds, the others fail with ErrPaymentNotPendingReview.
+func (s *paymentService) claimReview(ctx context.Context, paymentID uint, status string) error {
+	log := logging.FromContext(ctx, s.log)
+	err := s.repo.ClaimPayment(ctx, paymentID, utils.PaymentStatusPendingReview, status)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Warn("Payment was reviewed concurrently", zap.Uint("payment_id", paymentID))
+		return ErrPaymentNotPendingReview
+	}
+	if err != nil {
+		log.Error("Failed to claim payment for review", zap.Uint("payment_id", paymentID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (s *paymentService) GetPaymentStatus(ctx context.Context, invoiceID uint) (string, error) {
+	ctx = logging.With(ctx, zap.Uint("invoice_id", invoiceID))
+	log := logging.FromContext(ctx, s.log)
+	log.Info("Fetching payment status")
+
+	status, err := s.repo.GetPaymentStatus(ctx, invoiceID)
+	if err != nil {
+		log.Error("Failed to fetch payment status", zap.Error(err))
 		return "", err
 	}
 
-	s.log.Info("Successfully fetched payment status", zap.Uint("invoice_id", invoiceID), zap.String("status", status))
+	log.Info("Successfully fetched payment status", zap.String("status", status))
 	return status, nil
 }
 
-func (s *paymentService) GetPaymentStatusBasedOnPaymentSource(paymentSource string) string {
-	if strings.HasSuffix(paymentSource, "1212") {
-		return utils.PaymentStatusInsufficientFunds
-	} else if strings.HasSuffix(paymentSource, "2323") {
-		return utils.PaymentStatusDoNotHonor
-	} else if strings.HasSuffix(paymentSource, "3434") {
-		return utils.PaymentStatusDeclined
-	} else if strings.HasSuffix(paymentSource, "4545") {
-		time.Sleep(10000 * time.Hour) // Simulating an infinite delay
+// applySavedPaymentMethod fills the payment source from a payment method saved against the invoice's customer
+func (s *paymentService) applySavedPaymentMethod(ctx context.Context, payment *entities.Payment, paymentMethodID, customerID uint) error {
+	log := logging.FromContext(ctx, s.log)
+	if s.vault == nil {
+		return ErrPaymentMethodsUnavailable
+	}
+
+	paymentMethod, err := s.repo.GetPaymentMethodByID(ctx, paymentMethodID)
+	if err != nil {
+		log.Error("Saved payment method not found", zap.Uint("payment_method_id", paymentMethodID), zap.Error(err))
+		return err
+	}
+	if paymentMethod.CustomerID != customerID {
+		log.Warn("Saved payment method belongs to another customer",
+			zap.Uint("payment_method_id", paymentMethodID), zap.Uint("customer_id", customerID))
+		return gorm.ErrRecordNotFound
+	}
+
+	source, err := s.vault.Open(paymentMethod.EncryptedSource)
+	if err != nil {
+		log.Error("Failed to decrypt saved payment method", zap.Uint("payment_method_id", paymentMethodID), zap.Error(err))
+		return err
+	}
+
+	payment.PaymentMethod = paymentMethod.MethodType
+	payment.PaymentSource = source
+	payment.PaymentMethodID = &paymentMethod.ID
+	return nil
+}
+
+// savedPaymentSource decrypts the source of a saved payment method
+func (s *paymentService) savedPaymentSource(ctx context.Context, paymentMethodID uint) (string, error) {
+	log := logging.FromContext(ctx, s.log)
+	if s.vault == nil {
+		return "", ErrPaymentMethodsUnavailable
+	}
+
+	paymentMethod, err := s.repo.GetPaymentMethodByID(ctx, paymentMethodID)
+	if err != nil {
+		log.Error("Saved payment method not found", zap.Uint("payment_method_id", paymentMethodID), zap.Error(err))
+		return "", err
+	}
+
+	source, err := s.vault.Open(paymentMethod.EncryptedSource)
+	if err != nil {
+		log.Error("Failed to decrypt saved payment method", zap.Uint("payment_method_id", paymentMethodID), zap.Error(err))
+		return "", err
 	}
-	return utils.PaymentStatusSuccess
+	return source, nil
 }
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/dto"
+	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/payments/provider"
+	"go/payment-processor/pkg/repository"
+	"go/payment-processor/pkg/utils"
+	"go/payment-processor/pkg/vault"
+	"sync"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// fakePaymentRepository keeps one pending invoice and the payments made against it. Payments in stale
+// are read as they were before another request changed them. The invoice is guarded by mu for
+// concurrent payments.
+type fakePaymentRepository struct {
+	repository.Repository
+	mu       sync.Mutex
+	invoice  entities.Invoice
+	payments map[uint]*entities.Payment
+	stale    map[uint]entities.Payment
+}
+
+func newFakePaymentRepository() *fakePaymentRepository {
+	invoice := entities.Invoice{Amount: decimal.NewFromInt(10), Currency: "USD", InvoiceStatus: utils.InvoiceStatusPending}
+	invoice.ID = 1
+	return &fakePaymentRepository{invoice: invoice, payments: map[uint]*entities.Payment{}, stale: map[uint]entities.Payment{}}
+}
+
+func (r *fakePaymentRepository) DoesInvoiceExist(context.Context, uint) (*entities.Invoice, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	invoice := r.invoice
+	return &invoice, nil
+}
+
+func (r *fakePaymentRepository) GetInvoiceByID(ctx context.Context, id uint) (*entities.Invoice, error) {
+	return r.DoesInvoiceExist(ctx, id)
+}
+
+func (r *fakePaymentRepository) UpdateInvoiceStatus(_ context.Context, _ uint, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.invoice.InvoiceStatus = status
+	return nil
+}
+
+func (r *fakePaymentRepository) ClaimInvoice(_ context.Context, _ uint, fromStatus, toStatus string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.invoice.InvoiceStatus != fromStatus {
+		return gorm.ErrRecordNotFound
+	}
+	r.invoice.InvoiceStatus = toStatus
+	return nil
+}
+
+func (r *fakePaymentRepository) ProcessPayment(_ context.Context, payment *entities.Payment) (*entities.Payment, error) {
+	stored := *payment
+	stored.ID = uint(len(r.payments) + 1)
+	r.payments[stored.ID] = &stored
+	return &stored, nil
+}
+
+func (r *fakePaymentRepository) UpdatePayment(_ context.Context, payment *entities.Payment) (*entities.Payment, error) {
+	stored := *payment
+	r.payments[stored.ID] = &stored
+	return &stored, nil
+}
+
+func (r *fakePaymentRepository) GetPaymentByID(_ context.Context, paymentID uint) (*entities.Payment, error) {
+	if stale, ok := r.stale[paymentID]; ok {
+		return &stale, nil
+	}
+	payment, ok := r.payments[paymentID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	stored := *payment
+	return &stored, nil
+}
+
+func (r *fakePaymentRepository) UpdatePaymentStatus(_ context.Context, paymentID uint, status string) error {
+	payment, ok := r.payments[paymentID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	payment.PaymentStatus = status
+	return nil
+}
+
+func (r *fakePaymentRepository) ClaimPayment(_ context.Context, paymentID uint, fromStatus, toStatus string) error {
+	payment, ok := r.payments[paymentID]
+	if !ok || payment.PaymentStatus != fromStatus {
+		return gorm.ErrRecordNotFound
+	}
+	payment.PaymentStatus = toStatus
+	return nil
+}
+
+// fakeGateway answers every call with status, or fails it with err. With hold set, payments wait
+// until hold is closed.
+type fakeGateway struct {
+	PaymentGateway
+	status provider.PaymentStatus
+	err    error
+	calls  int
+	hold   chan struct{}
+}
+
+func (g *fakeGateway) Pay(context.Context, provider.PaymentDetails) (provider.Payment, error) {
+	if g.hold != nil {
+		<-g.hold
+	}
+	g.calls++
+	if g.err != nil {
+		return provider.Payment{}, g.err
+	}
+	return provider.Payment{ID: uuid.New(), Status: g.status}, nil
+}
+
+func (g *fakeGateway) CompleteChallenge(_ context.Context, id uuid.UUID, _ bool) (provider.Payment, error) {
+	g.calls++
+	if g.err != nil {
+		return provider.Payment{}, g.err
+	}
+	return provider.Payment{ID: id, Status: g.status}, nil
+}
+
+func newTestPaymentService(repo repository.Repository, gateway PaymentGateway) PaymentService {
+	return NewPaymentService(zap.NewNop(), repo, validator.New(), gateway, nil, vault.NewEphemeralFingerprinter(), nil, nil, nil, nil)
+}
+
+func paymentRequest() *dto.ProcessPaymentRequest {
+	return &dto.ProcessPaymentRequest{InvoiceID: 1, PaymentMethod: utils.PaymentMethodCreditCard, PaymentSource: "4242424242424242"}
+}
+
+func TestProcessPaymentProviderUnavailable(t *testing.T) {
+	repo := newFakePaymentRepository()
+	service := newTestPaymentService(repo, &fakeGateway{err: provider.ErrUnavailable})
+	ctx := auth.ContextWithPrincipal(context.Background(), auth.SystemPrincipal("test"))
+
+	_, err := service.ProcessPayment(ctx, paymentRequest())
+
+	// Nothing was charged, so nothing is left to reconcile and the invoice can be paid again
+	assert.ErrorIs(t, err, ErrProviderUnavailable)
+	assert.Empty(t, repo.payments)
+	assert.Equal(t, utils.InvoiceStatusPending, repo.invoice.InvoiceStatus)
+}
+
+func TestProcessPaymentOutcomeUnknown(t *testing.T) {
+	repo := newFakePaymentRepository()
+	service := newTestPaymentService(repo, &fakeGateway{err: context.DeadlineExceeded})
+	ctx := auth.ContextWithPrincipal(context.Background(), auth.SystemPrincipal("test"))
+
+	_, err := service.ProcessPayment(ctx, paymentRequest())
+
+	assert.ErrorIs(t, err, ErrPaymentOutcomeUnknown)
+	assert.False(t, errors.Is(err, ErrProviderUnavailable))
+	if assert.Len(t, repo.payments, 1) {
+		assert.Equal(t, utils.PaymentStatusPendingReconciliation, repo.payments[1].PaymentStatus)
+		assert.NotEmpty(t, repo.payments[1].ReferenceID)
+	}
+	// The payment may have been charged, so the invoice stays claimed until it is reconciled
+	assert.Equal(t, utils.InvoiceStatusProcessing, repo.invoice.InvoiceStatus)
+}
+
+func TestProcessPaymentDeclinedReleasesInvoice(t *testing.T) {
+	repo := newFakePaymentRepository()
+	gateway := &fakeGateway{status: provider.PaymentStatusDoNotHonor}
+	service := newTestPaymentService(repo, gateway)
+	ctx := auth.ContextWithPrincipal(context.Background(), auth.SystemPrincipal("test"))
+
+	payment, err := service.ProcessPayment(ctx, paymentRequest())
+	assert.NoError(t, err)
+	assert.Equal(t, utils.PaymentStatusDoNotHonor, payment.PaymentStatus)
+	assert.Equal(t, utils.InvoiceStatusPending, repo.invoice.InvoiceStatus)
+
+	gateway.status = provider.PaymentStatusSuccess
+	payment, err = service.ProcessPayment(ctx, paymentRequest())
+	assert.NoError(t, err)
+	assert.Equal(t, utils.PaymentStatusSuccess, payment.PaymentStatus)
+	assert.Equal(t, utils.InvoiceStatusPaid, repo.invoice.InvoiceStatus)
+}
+
+func TestConcurrentPaymentsChargeInvoiceOnce(t *testing.T) {
+	repo := newFakePaymentRepository()
+	gateway := &fakeGateway{status: provider.PaymentStatusSuccess, hold: make(chan struct{})}
+	service := newTestPaymentService(repo, gateway)
+	ctx := auth.ContextWithPrincipal(context.Background(), auth.SystemPrincipal("test"))
+
+	// Whichever payment claims the invoice is held by the provider until the other one has finished
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := service.ProcessPayment(ctx, paymentRequest())
+			errs <- err
+		}()
+	}
+	assert.ErrorIs(t, <-errs, ErrPaymentInProgress)
+	close(gateway.hold)
+	assert.NoError(t, <-errs)
+
+	assert.Equal(t, 1, gateway.calls)
+	assert.Len(t, repo.payments, 1)
+	assert.Equal(t, utils.InvoiceStatusPaid, repo.invoice.InvoiceStatus)
+}
+
+func TestCompletePaymentChallengeOutcomeUnknown(t *testing.T) {
+	repo := newFakePaymentRepository()
+	gateway := &fakeGateway{status: provider.PaymentStatusRequiresAction}
+	service := newTestPaymentService(repo, gateway)
+	ctx := auth.ContextWithPrincipal(context.Background(), auth.SystemPrincipal("test"))
+	payment, err := service.ProcessPayment(ctx, paymentRequest())
+	assert.NoError(t, err)
+
+	gateway.err = errors.New("connection reset by peer")
+	_, err = service.CompletePaymentChallenge(ctx, &dto.CompleteChallengeRequest{InvoiceID: 1, PaymentID: payment.ID,
+		Result: utils.ChallengeResultSuccess})
+
+	assert.ErrorIs(t, err, ErrPaymentOutcomeUnknown)
+	assert.Equal(t, utils.PaymentStatusPendingReconciliation, repo.payments[payment.ID].PaymentStatus)
+}
+
+// heldPayment stores a payment held for review and returns its ID
+func heldPayment(repo *fakePaymentRepository) uint {
+	payment := &entities.Payment{InvoiceID: 1, PaymentMethod: utils.PaymentMethodCreditCard, PaymentSource: "4242424242424242",
+		PaymentStatus: utils.PaymentStatusPendingReview}
+	stored, _ := repo.ProcessPayment(context.Background(), payment)
+	return stored.ID
+}
+
+func TestReviewPaymentIsDecidedOnce(t *testing.T) {
+	repo := newFakePaymentRepository()
+	gateway := &fakeGateway{status: provider.PaymentStatusSuccess}
+	service := newTestPaymentService(repo, gateway)
+	ctx := auth.ContextWithPrincipal(context.Background(), auth.SystemPrincipal("test"))
+	paymentID := heldPayment(repo)
+	held := *repo.payments[paymentID]
+
+	payment, err := service.ReviewPayment(ctx, &dto.ReviewPaymentRequest{PaymentID: paymentID, Decision: utils.ReviewDecisionApprove})
+	assert.NoError(t, err)
+	assert.Equal(t, utils.PaymentStatusSuccess, payment.PaymentStatus)
+
+	// A concurrent reviewer that read the payment and its invoice before it was approved must not charge it again
+	repo.stale[paymentID] = held
+	repo.invoice.InvoiceStatus = utils.InvoiceStatusPending
+	for _, decision := range []string{utils.ReviewDecisionApprove, utils.ReviewDecisionReject} {
+		_, err = service.ReviewPayment(ctx, &dto.ReviewPaymentRequest{PaymentID: paymentID, Decision: decision})
+		assert.ErrorIs(t, err, ErrPaymentNotPendingReview)
+	}
+	assert.Equal(t, 1, gateway.calls)
+	assert.Equal(t, utils.PaymentStatusSuccess, repo.payments[paymentID].PaymentStatus)
+}
+
+func TestReviewPaymentReturnsToReviewWhenProviderUnavailable(t *testing.T) {
+	repo := newFakePaymentRepository()
+	service := newTestPaymentService(repo, &fakeGateway{err: provider.ErrUnavailable})
+	ctx := auth.ContextWithPrincipal(context.Background(), auth.SystemPrincipal("test"))
+	paymentID := heldPayment(repo)
+
+	_, err := service.ReviewPayment(ctx, &dto.ReviewPaymentRequest{PaymentID: paymentID, Decision: utils.ReviewDecisionApprove})
+
+	assert.ErrorIs(t, err, ErrProviderUnavailable)
+	assert.Equal(t, utils.PaymentStatusPendingReview, repo.payments[paymentID].PaymentStatus)
+	assert.Equal(t, utils.InvoiceStatusPending, repo.invoice.InvoiceStatus)
+}
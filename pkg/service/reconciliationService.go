@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"go/payment-processor/pkg/auth"
+	"go/payment-processor/pkg/entities"
+	"go/payment-processor/pkg/logging"
+	"go/payment-processor/pkg/mapper"
+	"go/payment-processor/pkg/reconciliation"
+	"go/payment-processor/pkg/repository"
+	"go/payment-processor/pkg/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// reconciliationPrincipal is recorded as the author of automatic status corrections
+var reconciliationPrincipal = auth.SystemPrincipal("reconciliation")
+
+// interruptedBatchSize caps the interrupted payments added to each reconciliation against the provider
+const interruptedBatchSize = 500
+
+type ReconciliationService interface {
+	ReconcileSettlementReport(ctx context.Context, records []reconciliation.Record, from, to time.Time, autoCorrect bool) (*reconciliation.Report, error)
+	ReconcileWithProvider(ctx context.Context, from, to time.Time, autoCorrect bool) (*reconciliation.Report, error)
+	RunScheduled(ctx context.Context, interval time.Duration, autoCorrect bool)
+	MarkInterrupted(ctx context.Context, payments []entities.Payment) error
+}
+
+type reconciliationService struct {
+	log     *zap.Logger
+	repo    repository.Repository
+	gateway PaymentGateway
+}
+
+func NewReconciliationService(log *zap.Logger, repo repository.Repository, gateway PaymentGateway) ReconciliationService {
+	return &reconciliationService{log: log, repo: repo, gateway: gateway}
+}
+
+// ReconcileSettlementReport matches the payments created in [from, to) against a provider settlement report
+func (rs *reconciliationService) ReconcileSettlementReport(ctx context.Context, records []reconciliation.Record, from, to time.Time, autoCorrect bool) (*reconciliation.Report, error) {
+	log := logging.FromContext(ctx, rs.log)
+	log.Info("Reconciling payments against settlement report",
+		zap.Time("from", from), zap.Time("to", to), zap.Int("records", len(records)))
+
+	payments, err := rs.repo.GetPaymentsCreatedBetween(ctx, from, to)
+	if err != nil {
+		log.Error("Failed to fetch payments for reconciliation", zap.Error(err))
+		return nil, err
+	}
+
+	return rs.finish(ctx, reconciliation.Match(payments, records), autoCorrect), nil
+}
+
+// ReconcileWithProvider looks up every payment created in [from, to) with the provider by ID, along with
+// the payments still pending reconciliation after a shutdown interrupted them
+func (rs *reconciliationService) ReconcileWithProvider(ctx context.Context, from, to time.Time, autoCorrect bool) (*reconciliation.Report, error) {
+	log := logging.FromContext(ctx, rs.log)
+	log.Info("Reconciling payments against provider", zap.Time("from", from), zap.Time("to", to))
+
+	payments, err := rs.repo.GetPaymentsCreatedBetween(ctx, from, to)
+	if err != nil {
+		log.Error("Failed to fetch payments for reconciliation", zap.Error(err))
+		return nil, err
+	}
+	interrupted, err := rs.repo.GetPaymentsByStatus(ctx, utils.PaymentStatusPendingReconciliation, 0, interruptedBatchSize)
+	if err != nil {
+		log.Error("Failed to fetch interrupted payments for reconciliation", zap.Error(err))
+		return nil, err
+	}
+	payments = appendMissing(payments, interrupted)
+
+	return rs.finish(ctx, reconciliation.Match(payments, rs.lookupRecords(payments)), autoCorrect), nil
+}
+
+// RunScheduled reconciles the previous interval against the provider until ctx is cancelled
+func (rs *reconciliationService) RunScheduled(ctx context.Context, interval time.Duration, autoCorrect bool) {
+	log := logging.FromContext(ctx, rs.log)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := rs.ReconcileWithProvider(ctx, now.Add(-interval), now, autoCorrect); err != nil {
+				log.Error("Scheduled reconciliation failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// MarkInterrupted flags payments whose provider call was still running when the server shut down, so the
+// next reconciliation against the provider settles them. Payments that were not stored yet are created.
+func (rs *reconciliationService) MarkInterrupted(ctx context.Context, payments []entities.Payment) error {
+	log := logging.FromContext(ctx, rs.log)
+	ctx = auth.ContextWithPrincipal(ctx, reconciliationPrincipal)
+	var errs []error
+	for _, payment := range payments {
+		log.Warn("Payment interrupted by shutdown, marking it for reconciliation", zap.Uint("payment_id", payment.ID),
+			zap.Uint("invoice_id", payment.InvoiceID), zap.String("reference_id", payment.ReferenceID))
+		var err error
+		if payment.ID == 0 {
+			payment.PaymentStatus = utils.PaymentStatusPendingReconciliation
+			_, err = rs.repo.ProcessPayment(ctx, &payment)
+		} else {
+			err = rs.repo.UpdatePaymentStatus(ctx, payment.ID, utils.PaymentStatusPendingReconciliation)
+		}
+		if err != nil {
+			log.Error("Failed to mark interrupted payment", zap.String("reference_id", payment.ReferenceID), zap.Error(err))
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// lookupRecords asks the provider about every payment, by reference ID when it never got a provider ID
+func (rs *reconciliationService) lookupRecords(payments []entities.Payment) []reconciliation.Record {
+	records := make([]reconciliation.Record, 0, len(payments))
+	for _, payment := range payments {
+		lookup, raw := rs.gateway.ByID, payment.ProviderPaymentID
+		if raw == "" {
+			lookup, raw = rs.gateway.ByReferenceID, payment.ReferenceID
+		}
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			continue
+		}
+		status, ok := lookup(id)
+		if !ok {
+			continue
+		}
+		records = append(records, reconciliation.Record{
+			ProviderPaymentID: payment.ProviderPaymentID,
+			ReferenceID:       payment.ReferenceID,
+			Status:            mapper.ToPaymentStatus(status),
+		})
+	}
+	return records
+}
+
+// finish applies status corrections if requested and logs the outcome of the run
+func (rs *reconciliationService) finish(ctx context.Context, report *reconciliation.Report, autoCorrect bool) *reconciliation.Report {
+	log := logging.FromContext(ctx, rs.log)
+	if autoCorrect {
+		for i := range report.Entries {
+			entry := &report.Entries[i]
+			if entry.Outcome != reconciliation.OutcomeStatusMismatch {
+				continue
+			}
+			if err := rs.correctStatus(ctx, entry); err != nil {
+				log.Error("Failed to correct payment status",
+					zap.Uint("payment_id", entry.PaymentID), zap.Error(err))
+				continue
+			}
+			entry.Corrected = true
+			report.Corrected++
+		}
+	}
+
+	log.Info("Reconciliation completed",
+		zap.Int("matched", report.Matched),
+		zap.Int("missing", report.Missing),
+		zap.Int("amount_mismatched", report.AmountMismatched),
+		zap.Int("status_mismatched", report.StatusMismatched),
+		zap.Int("corrected", report.Corrected))
+	return report
+}
+
+// correctStatus stores the provider status of a payment. An interrupted payment the provider charged
+// also marks its invoice paid, which the shutdown left undone, and one it declined releases its invoice.
+func (rs *reconciliationService) correctStatus(ctx context.Context, entry *reconciliation.Entry) error {
+	ctx = auth.ContextWithPrincipal(ctx, reconciliationPrincipal)
+	if err := rs.repo.UpdatePaymentStatus(ctx, entry.PaymentID, entry.ProviderStatus); err != nil {
+		return err
+	}
+	if entry.LocalStatus != utils.PaymentStatusPendingReconciliation {
+		return nil
+	}
+	payment, err := rs.repo.GetPaymentByID(ctx, entry.PaymentID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case entry.ProviderStatus == utils.PaymentStatusSuccess:
+		return rs.repo.UpdateInvoiceStatus(ctx, payment.InvoiceID, utils.InvoiceStatusPaid)
+	case isDeclined(entry.ProviderStatus):
+		err := rs.repo.ClaimInvoice(ctx, payment.InvoiceID, utils.InvoiceStatusProcessing, utils.InvoiceStatusPending)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// appendMissing appends the payments of extra that are not in payments yet
+func appendMissing(payments, extra []entities.Payment) []entities.Payment {
+	included := make(map[uint]bool, len(payments))
+	for _, payment := range payments {
+		included[payment.ID] = true
+	}
+	for _, payment := range extra {
+		if !included[payment.ID] {
+			payments = append(payments, payment)
+		}
+	}
+	return payments
+}
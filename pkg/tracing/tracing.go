@@ -0,0 +1,104 @@
+// Package tracing sets up OpenTelemetry tracing and starts the spans of the handlers, services,
+// repository and payment provider. Until a Provider is installed spans are not recorded.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go/payment-processor/pkg/health"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer of the payment processor
+const instrumentationName = "go/payment-processor"
+
+// Exporters a Provider can send spans to
+const (
+	ExporterOTLP   = "otlp"    // OTLP over gRPC, configured by the standard OTEL_EXPORTER_OTLP_* variables
+	ExporterStdout = "console" // pretty-printed JSON on stdout, for local debugging
+)
+
+// ServiceName is the service.name of the spans unless OTEL_SERVICE_NAME is set
+const ServiceName = "payment-processor"
+
+// Provider records spans and sends them to an exporter.
+type Provider struct {
+	tracerProvider *sdktrace.TracerProvider
+}
+
+// New installs a provider sending spans to exporter as the global tracer provider, and W3C trace
+// context and baggage as the propagators. The sampler follows OTEL_TRACES_SAMPLER, sampling every
+// trace by default.
+func New(ctx context.Context, exporter string) (*Provider, error) {
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case ExporterOTLP:
+		spanExporter, err = otlptracegrpc.New(ctx)
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(ServiceName), semconv.ServiceVersion(health.Version)))
+	if err != nil {
+		return nil, err
+	}
+	// Attributes from OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES take precedence
+	if res, err = resource.Merge(res, resource.Environment()); err != nil {
+		return nil, err
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(spanExporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return &Provider{tracerProvider: tracerProvider}, nil
+}
+
+// Shutdown sends the spans still buffered and stops the provider. A nil Provider has nothing to send.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	return p.tracerProvider.Shutdown(ctx)
+}
+
+// Tracer returns the tracer of the payment processor from the global tracer provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start starts an internal span as a child of the span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends span, marking it failed when err is not nil. It is meant to be deferred with a pointer to
+// the named error result of the traced function.
+func End(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		RecordError(span, *err)
+	}
+	span.End()
+}
+
+// RecordError marks span failed with err.
+func RecordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
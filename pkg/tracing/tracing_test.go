@@ -0,0 +1,48 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpansRecordErrors(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	traced := func(ctx context.Context, fail bool) (err error) {
+		ctx, span := Start(ctx, "parent")
+		defer End(span, &err)
+		_, child := Start(ctx, "child")
+		child.End()
+		if fail {
+			return errors.New("provider unavailable")
+		}
+		return nil
+	}
+	assert.NoError(t, traced(context.Background(), false))
+	assert.Error(t, traced(context.Background(), true))
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 4) {
+		assert.Equal(t, "child", spans[0].Name())
+		assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+		assert.Equal(t, codes.Unset, spans[1].Status().Code)
+		assert.Equal(t, codes.Error, spans[3].Status().Code)
+		assert.Equal(t, "provider unavailable", spans[3].Status().Description)
+	}
+}
+
+func TestNewRejectsUnknownExporters(t *testing.T) {
+	_, err := New(context.Background(), "zipkin")
+	assert.Error(t, err)
+
+	var provider *Provider
+	assert.NoError(t, provider.Shutdown(context.Background()))
+}
@@ -1,5 +1,7 @@
 package utils
 
+import "time"
+
 const (
 	DB_CONNECTION_URL = "host=%s user=%s password=%s dbname=%s port=%s sslmode=disable"
 )
@@ -10,4 +12,92 @@ const (
 	PaymentStatusInsufficientFunds = "INSUFFICIENT_FUNDS"
 	PaymentStatusDoNotHonor        = "DO_NOT_HONOR"
 	PaymentStatusDeclined          = "DECLINED"
+	// A payment waiting for the cardholder to complete a 3-D Secure challenge, and one whose challenge failed
+	PaymentStatusRequiresAction       = "REQUIRES_ACTION"
+	PaymentStatusAuthenticationFailed = "AUTHENTICATION_FAILED"
+	// Payments stopped by the risk engine, which were never sent to the provider
+	PaymentStatusPendingReview = "PENDING_REVIEW"
+	PaymentStatusBlocked       = "BLOCKED"
+	PaymentStatusRejected      = "REJECTED"
+	// A payment whose provider outcome is unknown, because the server shut down or the provider call failed
+	// without saying whether it was charged, until reconciliation settles it
+	PaymentStatusPendingReconciliation = "PENDING_RECONCILIATION"
+)
+
+// PaymentStatusesDeclined are the provider outcomes counted as declines
+var PaymentStatusesDeclined = []string{PaymentStatusInsufficientFunds, PaymentStatusDoNotHonor, PaymentStatusDeclined,
+	PaymentStatusAuthenticationFailed}
+
+// PaymentStatusesNotSubmitted are the statuses of payments the provider has never seen
+var PaymentStatusesNotSubmitted = []string{PaymentStatusPendingReview, PaymentStatusBlocked, PaymentStatusRejected}
+
+// Normalized decline codes returned to clients. Risk engine decisions are reported as generic
+// declines so they do not reveal the rules that stopped a payment.
+const (
+	DeclineCodeInsufficientFunds    = "insufficient_funds"
+	DeclineCodeDoNotHonor           = "do_not_honor"
+	DeclineCodeAuthenticationFailed = "authentication_failed"
+	DeclineCodeGeneric              = "generic_decline"
+)
+
+// Outcomes of a 3-D Secure challenge
+const (
+	ChallengeResultSuccess = "success"
+	ChallengeResultFailure = "failure"
+)
+
+// Manual review decisions for payments held by the risk engine
+const (
+	ReviewDecisionApprove = "approve"
+	ReviewDecisionReject  = "reject"
+)
+
+// Payment method constants
+const (
+	PaymentMethodCreditCard   = "credit_card"
+	PaymentMethodBankTransfer = "bank_transfer"
+)
+
+// Merchant status constants
+const (
+	MerchantStatusActive    = "active"
+	MerchantStatusSuspended = "suspended"
+)
+
+// API key mode constants
+const (
+	APIKeyModeTest = "test"
+	APIKeyModeLive = "live"
+)
+
+// Invoice status constants
+const (
+	InvoiceStatusPending     = "PENDING"
+	InvoiceStatusProcessing  = "PROCESSING" // A payment for the invoice is with the provider
+	InvoiceStatusPaid        = "PAID"
+	InvoiceStatusDisputed    = "DISPUTED"
+	InvoiceStatusChargedBack = "CHARGED_BACK"
+)
+
+// Dispute status constants
+const (
+	DisputeStatusNeedsResponse = "NEEDS_RESPONSE"
+	DisputeStatusUnderReview   = "UNDER_REVIEW"
+	DisputeStatusWon           = "WON"
+	DisputeStatusLost          = "LOST"
+)
+
+// DisputeResponseWindow is how long a merchant has to submit evidence when no deadline is given
+const DisputeResponseWindow = 7 * 24 * time.Hour
+
+// Ledger entry type constants
+const (
+	LedgerEntryTypeChargeback = "CHARGEBACK"
+)
+
+// Audit log actions
+const (
+	AuditActionCreate = "CREATE"
+	AuditActionUpdate = "UPDATE"
+	AuditActionDelete = "DELETE"
 )
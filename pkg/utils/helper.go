@@ -18,6 +18,26 @@ func ConvertFloat64ToDecimal(value float64) decimal.Decimal {
 	return decimalValue
 }
 
+// SplitCurrencies parses a comma separated currency list as stored on a merchant
+func SplitCurrencies(currencies string) []string {
+	var result []string
+	for _, currency := range strings.Split(currencies, ",") {
+		if currency = strings.ToUpper(strings.TrimSpace(currency)); currency != "" {
+			result = append(result, currency)
+		}
+	}
+	return result
+}
+
+// JoinCurrencies builds the comma separated currency list stored on a merchant
+func JoinCurrencies(currencies []string) string {
+	normalized := make([]string, 0, len(currencies))
+	for _, currency := range currencies {
+		normalized = append(normalized, strings.ToUpper(strings.TrimSpace(currency)))
+	}
+	return strings.Join(normalized, ",")
+}
+
 func IsCurrencyAllowed(currency string, allowedCurrencies []string) bool {
 	for _, allowedCurrency := range allowedCurrencies {
 		if strings.EqualFold(currency, allowedCurrency) {
@@ -26,3 +46,74 @@ func IsCurrencyAllowed(currency string, allowedCurrencies []string) bool {
 	}
 	return false
 }
+
+// ValidCardNumber reports whether a card number is 12 to 19 digits passing the Luhn check
+func ValidCardNumber(pan string) bool {
+	if len(pan) < 12 || len(pan) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(pan) - 1; i >= 0; i-- {
+		digit := int(pan[i] - '0')
+		if digit < 0 || digit > 9 {
+			return false
+		}
+		// Every second digit from the right is doubled
+		if (len(pan)-i)%2 == 0 {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+// ValidBankAccount reports whether an upper case account number is an IBAN with a valid check
+// digits or a domestic account number of 4 to 34 digits
+func ValidBankAccount(account string) bool {
+	if len(account) < 4 || len(account) > 34 {
+		return false
+	}
+	if isDigits(account) {
+		return true
+	}
+	if len(account) < 15 || !isUpper(account[0]) || !isUpper(account[1]) || !isDigits(account[2:4]) {
+		return false
+	}
+	// ISO 13616: move the country code and check digits to the end, letters count as 10 to 35,
+	// and the number must be 1 modulo 97
+	remainder := 0
+	for _, c := range []byte(account[4:] + account[:4]) {
+		switch {
+		case c >= '0' && c <= '9':
+			remainder = (remainder*10 + int(c-'0')) % 97
+		case isUpper(c):
+			remainder = (remainder*100 + int(c-'A') + 10) % 97
+		default:
+			return false
+		}
+	}
+	return remainder == 1
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isUpper(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
+// MaskPaymentSource hides all but the last four characters of a card number or account
+func MaskPaymentSource(paymentSource string) string {
+	if len(paymentSource) <= 4 {
+		return paymentSource
+	}
+	return strings.Repeat("*", len(paymentSource)-4) + paymentSource[len(paymentSource)-4:]
+}
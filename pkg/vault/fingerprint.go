@@ -0,0 +1,50 @@
+package vault
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Fingerprinter identifies payment sources without storing them, using HMAC-SHA256 so that a leaked
+// fingerprint cannot be reversed by hashing every possible card number.
+type Fingerprinter struct {
+	key []byte
+}
+
+// NewFingerprinter builds a Fingerprinter from a key of at least 32 bytes.
+func NewFingerprinter(key []byte) (*Fingerprinter, error) {
+	if len(key) < 32 {
+		return nil, fmt.Errorf("fingerprint key must be at least 32 bytes, got %d", len(key))
+	}
+	return &Fingerprinter{key: key}, nil
+}
+
+// NewFingerprinterFromHex builds a Fingerprinter from a hex encoded key, as stored in the environment.
+func NewFingerprinterFromHex(hexKey string) (*Fingerprinter, error) {
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, fmt.Errorf("fingerprint key is not valid hex: %w", err)
+	}
+	return NewFingerprinter(key)
+}
+
+// NewEphemeralFingerprinter builds a Fingerprinter from a random key. Its fingerprints do not match
+// those of another process, so payments made before a restart are not recognised.
+func NewEphemeralFingerprinter() *Fingerprinter {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("vault: reading random fingerprint key: %v", err))
+	}
+	return &Fingerprinter{key: key}
+}
+
+// Fingerprint returns the hex encoded fingerprint of a card number or bank account, ignoring spaces.
+func (f *Fingerprinter) Fingerprint(paymentSource string) string {
+	mac := hmac.New(sha256.New, f.key)
+	mac.Write([]byte(strings.ReplaceAll(paymentSource, " ", "")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,83 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Vault encrypts payment sources (card numbers, bank accounts) before they are stored,
+// using AES-256-GCM with a random nonce prepended to every ciphertext.
+type Vault struct {
+	aead cipher.AEAD
+}
+
+// New builds a Vault from a 32 byte key.
+func New(key []byte) (*Vault, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("vault key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Vault{aead: aead}, nil
+}
+
+// NewFromHex builds a Vault from a hex encoded 32 byte key, as stored in the environment.
+func NewFromHex(hexKey string) (*Vault, error) {
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, fmt.Errorf("vault key is not valid hex: %w", err)
+	}
+	return New(key)
+}
+
+// Seal encrypts plaintext and returns it base64 encoded.
+func (v *Vault) Seal(plaintext string) (string, error) {
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := v.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value produced by Seal.
+func (v *Vault) Open(ciphertext string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < v.aead.NonceSize() {
+		return "", errors.New("ciphertext is too short")
+	}
+	nonce, data := sealed[:v.aead.NonceSize()], sealed[v.aead.NonceSize():]
+	plaintext, err := v.aead.Open(nil, nonce, data, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Reseal moves a value sealed by previous under the key of v. Values v can already open are returned
+// unchanged, so an interrupted rotation can simply be run again.
+func (v *Vault) Reseal(previous *Vault, ciphertext string) (string, error) {
+	if _, err := v.Open(ciphertext); err == nil {
+		return ciphertext, nil
+	}
+	plaintext, err := previous.Open(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("value opens with neither key: %w", err)
+	}
+	return v.Seal(plaintext)
+}
@@ -0,0 +1,81 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func TestSealOpen(t *testing.T) {
+	v, err := NewFromHex(testKey)
+	assert.Nil(t, err)
+
+	sealed, err := v.Seal("4242424242424242")
+	assert.Nil(t, err)
+	assert.NotContains(t, sealed, "4242424242424242")
+
+	opened, err := v.Open(sealed)
+	assert.Nil(t, err)
+	assert.Equal(t, "4242424242424242", opened)
+}
+
+func TestOpenTampered(t *testing.T) {
+	v, _ := NewFromHex(testKey)
+	sealed, _ := v.Seal("4242424242424242")
+
+	_, err := v.Open(strings.Repeat("A", len(sealed)))
+	assert.NotNil(t, err)
+}
+
+func TestReseal(t *testing.T) {
+	previous, _ := NewFromHex(testKey)
+	v, _ := NewFromHex(strings.Repeat("ab", 32))
+	sealed, _ := previous.Seal("4242424242424242")
+
+	resealed, err := v.Reseal(previous, sealed)
+	assert.Nil(t, err)
+	opened, err := v.Open(resealed)
+	assert.Nil(t, err)
+	assert.Equal(t, "4242424242424242", opened)
+	_, err = previous.Open(resealed)
+	assert.NotNil(t, err)
+
+	// Already under the new key
+	again, err := v.Reseal(previous, resealed)
+	assert.Nil(t, err)
+	assert.Equal(t, resealed, again)
+
+	other, _ := NewFromHex(strings.Repeat("cd", 32))
+	foreign, _ := other.Seal("4242424242424242")
+	_, err = v.Reseal(previous, foreign)
+	assert.NotNil(t, err)
+}
+
+func TestNewInvalidKey(t *testing.T) {
+	_, err := NewFromHex("abcd")
+	assert.NotNil(t, err)
+
+	_, err = NewFromHex("not-hex")
+	assert.NotNil(t, err)
+}
+
+func TestFingerprint(t *testing.T) {
+	f, err := NewFingerprinterFromHex(testKey)
+	assert.Nil(t, err)
+	other, _ := NewFingerprinterFromHex(strings.Repeat("ff", 32))
+
+	assert.Equal(t, f.Fingerprint("4242424242424242"), f.Fingerprint("4242 4242 4242 4242"))
+	assert.NotEqual(t, f.Fingerprint("4242424242424242"), f.Fingerprint("4000056655665556"))
+	// Keyed, so the fingerprint differs between keys and from a plain SHA-256 of the number
+	assert.NotEqual(t, f.Fingerprint("4242424242424242"), other.Fingerprint("4242424242424242"))
+	plain := sha256.Sum256([]byte("4242424242424242"))
+	assert.NotEqual(t, hex.EncodeToString(plain[:]), f.Fingerprint("4242424242424242"))
+
+	_, err = NewFingerprinterFromHex("0011")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,91 @@
+package velocity
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store drops expired counters and blocks
+const sweepInterval = time.Minute
+
+type window struct {
+	events []time.Time
+	length time.Duration
+}
+
+// MemoryStore keeps counters and blocks in process memory.
+type MemoryStore struct {
+	mu        sync.Mutex
+	windows   map[string]*window
+	blocks    map[string]time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{windows: make(map[string]*window), blocks: make(map[string]time.Time)}
+}
+
+func (s *MemoryStore) Add(_ context.Context, key string, at time.Time, length time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(at)
+
+	w, ok := s.windows[key]
+	if !ok {
+		w = &window{}
+		s.windows[key] = w
+	}
+	w.length = length
+	w.events = append(w.events, at)
+	w.trim(at)
+	return len(w.events), nil
+}
+
+func (s *MemoryStore) Block(_ context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if until.After(s.blocks[key]) {
+		s.blocks[key] = until
+	}
+	return nil
+}
+
+func (s *MemoryStore) BlockedUntil(_ context.Context, key string, at time.Time) (time.Time, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	until, ok := s.blocks[key]
+	if !ok || !at.Before(until) {
+		return time.Time{}, false, nil
+	}
+	return until, true, nil
+}
+
+// trim drops the events that fell out of the window ending at now
+func (w *window) trim(now time.Time) {
+	start := now.Add(-w.length)
+	i := 0
+	for i < len(w.events) && !w.events[i].After(start) {
+		i++
+	}
+	w.events = w.events[i:]
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, w := range s.windows {
+		if w.trim(now); len(w.events) == 0 {
+			delete(s.windows, key)
+		}
+	}
+	for key, until := range s.blocks {
+		if !now.Before(until) {
+			delete(s.blocks, key)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package velocity
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Dimension constants name what a decline counter is kept for
+const (
+	DimensionCard     = "card"
+	DimensionCustomer = "customer"
+	DimensionMerchant = "merchant"
+)
+
+var dimensions = []string{DimensionCard, DimensionCustomer, DimensionMerchant}
+
+// Store keeps sliding-window event counters and temporary blocks. Implementations must be safe
+// for concurrent use.
+type Store interface {
+	// Add records an event for key at the given time and returns the number of events in the window ending then.
+	Add(ctx context.Context, key string, at time.Time, window time.Duration) (int, error)
+	// Block blocks key until the given time.
+	Block(ctx context.Context, key string, until time.Time) error
+	// BlockedUntil returns when the block of key ends, if it is blocked at the given time.
+	BlockedUntil(ctx context.Context, key string, at time.Time) (time.Time, bool, error)
+}
+
+// Config sets how many declines within Window block a card, customer or merchant, and for how
+// long. A zero threshold disables the dimension.
+type Config struct {
+	Window                 time.Duration
+	BlockDuration          time.Duration
+	MaxDeclinesPerCard     int
+	MaxDeclinesPerCustomer int
+	MaxDeclinesPerMerchant int
+}
+
+// Subject identifies who a payment attempt counts against.
+type Subject struct {
+	CardFingerprint string
+	CustomerID      uint
+	MerchantID      uint
+}
+
+// Block is an active temporary block.
+type Block struct {
+	Dimension string
+	Until     time.Time
+}
+
+// Metrics counts declines and the attempts stopped by blocks, by dimension.
+type Metrics struct {
+	DeclinesRecorded int64            `json:"declines_recorded"`
+	BlocksTriggered  map[string]int64 `json:"blocks_triggered"`
+	BlockedAttempts  map[string]int64 `json:"blocked_attempts"`
+}
+
+// Guard counts declines and blocks further attempts once a threshold is exceeded.
+type Guard struct {
+	store  Store
+	config Config
+	now    func() time.Time
+
+	declinesRecorded atomic.Int64
+	blocksTriggered  map[string]*atomic.Int64
+	blockedAttempts  map[string]*atomic.Int64
+}
+
+func NewGuard(store Store, config Config) *Guard {
+	if config.Window == 0 {
+		config.Window = 10 * time.Minute
+	}
+	if config.BlockDuration == 0 {
+		config.BlockDuration = 30 * time.Minute
+	}
+	guard := &Guard{store: store, config: config, now: time.Now,
+		blocksTriggered: make(map[string]*atomic.Int64), blockedAttempts: make(map[string]*atomic.Int64)}
+	for _, dimension := range dimensions {
+		guard.blocksTriggered[dimension] = &atomic.Int64{}
+		guard.blockedAttempts[dimension] = &atomic.Int64{}
+	}
+	return guard
+}
+
+// Check returns the block stopping an attempt by the subject, or nil if none applies.
+func (g *Guard) Check(ctx context.Context, subject Subject) (*Block, error) {
+	now := g.now()
+	for _, dimension := range dimensions {
+		key, ok := g.key(dimension, subject)
+		if !ok {
+			continue
+		}
+		until, blocked, err := g.store.BlockedUntil(ctx, key, now)
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			g.blockedAttempts[dimension].Add(1)
+			return &Block{Dimension: dimension, Until: until}, nil
+		}
+	}
+	return nil, nil
+}
+
+// RecordDecline counts a declined attempt and blocks every dimension whose threshold it reaches.
+// It returns the blocks it started.
+func (g *Guard) RecordDecline(ctx context.Context, subject Subject) ([]Block, error) {
+	g.declinesRecorded.Add(1)
+	now := g.now()
+	var blocks []Block
+	for _, dimension := range dimensions {
+		key, ok := g.key(dimension, subject)
+		if !ok {
+			continue
+		}
+		count, err := g.store.Add(ctx, key, now, g.config.Window)
+		if err != nil {
+			return blocks, err
+		}
+		if count < g.threshold(dimension) {
+			continue
+		}
+		until := now.Add(g.config.BlockDuration)
+		if err := g.store.Block(ctx, key, until); err != nil {
+			return blocks, err
+		}
+		g.blocksTriggered[dimension].Add(1)
+		blocks = append(blocks, Block{Dimension: dimension, Until: until})
+	}
+	return blocks, nil
+}
+
+// Metrics returns a snapshot of the counters.
+func (g *Guard) Metrics() Metrics {
+	metrics := Metrics{DeclinesRecorded: g.declinesRecorded.Load(),
+		BlocksTriggered: make(map[string]int64), BlockedAttempts: make(map[string]int64)}
+	for _, dimension := range dimensions {
+		metrics.BlocksTriggered[dimension] = g.blocksTriggered[dimension].Load()
+		metrics.BlockedAttempts[dimension] = g.blockedAttempts[dimension].Load()
+	}
+	return metrics
+}
+
+func (g *Guard) threshold(dimension string) int {
+	switch dimension {
+	case DimensionCard:
+		return g.config.MaxDeclinesPerCard
+	case DimensionCustomer:
+		return g.config.MaxDeclinesPerCustomer
+	default:
+		return g.config.MaxDeclinesPerMerchant
+	}
+}
+
+// key returns the counter key of the subject for the dimension, if the dimension is enabled and known
+func (g *Guard) key(dimension string, subject Subject) (string, bool) {
+	if g.threshold(dimension) <= 0 {
+		return "", false
+	}
+	switch dimension {
+	case DimensionCard:
+		return "card:" + subject.CardFingerprint, subject.CardFingerprint != ""
+	case DimensionCustomer:
+		return fmt.Sprintf("customer:%d", subject.CustomerID), subject.CustomerID != 0
+	default:
+		return fmt.Sprintf("merchant:%d", subject.MerchantID), subject.MerchantID != 0
+	}
+}
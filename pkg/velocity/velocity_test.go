@@ -0,0 +1,81 @@
+package velocity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestGuard(now *time.Time) *Guard {
+	guard := NewGuard(NewMemoryStore(), Config{
+		Window:                 10 * time.Minute,
+		BlockDuration:          30 * time.Minute,
+		MaxDeclinesPerCard:     3,
+		MaxDeclinesPerCustomer: 5,
+		MaxDeclinesPerMerchant: 100,
+	})
+	guard.now = func() time.Time { return *now }
+	return guard
+}
+
+func TestGuardBlocksCardAfterRepeatedDeclines(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	guard := newTestGuard(&now)
+	ctx := context.Background()
+	card := Subject{CardFingerprint: "fp", CustomerID: 1, MerchantID: 1}
+
+	for i := 0; i < 2; i++ {
+		blocks, err := guard.RecordDecline(ctx, card)
+		assert.NoError(t, err)
+		assert.Empty(t, blocks)
+	}
+	block, _ := guard.Check(ctx, card)
+	assert.Nil(t, block)
+
+	blocks, _ := guard.RecordDecline(ctx, card)
+	assert.Equal(t, []Block{{Dimension: DimensionCard, Until: now.Add(30 * time.Minute)}}, blocks)
+
+	block, _ = guard.Check(ctx, card)
+	assert.Equal(t, DimensionCard, block.Dimension)
+
+	// Another card of the same customer is not blocked yet
+	block, _ = guard.Check(ctx, Subject{CardFingerprint: "other", CustomerID: 1, MerchantID: 1})
+	assert.Nil(t, block)
+
+	// The block expires
+	now = now.Add(31 * time.Minute)
+	block, _ = guard.Check(ctx, card)
+	assert.Nil(t, block)
+
+	metrics := guard.Metrics()
+	assert.Equal(t, int64(3), metrics.DeclinesRecorded)
+	assert.Equal(t, int64(1), metrics.BlocksTriggered[DimensionCard])
+	assert.Equal(t, int64(1), metrics.BlockedAttempts[DimensionCard])
+}
+
+func TestGuardWindowSlides(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	guard := newTestGuard(&now)
+	ctx := context.Background()
+	card := Subject{CardFingerprint: "fp"}
+
+	_, _ = guard.RecordDecline(ctx, card)
+	_, _ = guard.RecordDecline(ctx, card)
+	now = now.Add(11 * time.Minute)
+	blocks, _ := guard.RecordDecline(ctx, card)
+	assert.Empty(t, blocks)
+}
+
+func TestGuardBlocksCustomerAcrossCards(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	guard := newTestGuard(&now)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		_, _ = guard.RecordDecline(ctx, Subject{CardFingerprint: string(rune('a' + i)), CustomerID: 7})
+	}
+	block, _ := guard.Check(ctx, Subject{CardFingerprint: "new", CustomerID: 7})
+	assert.Equal(t, DimensionCustomer, block.Dimension)
+}
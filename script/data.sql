@@ -2,7 +2,24 @@ CREATE TABLE merchant (
    id SERIAL PRIMARY KEY,
    merchant_name VARCHAR(255) NOT NULL,
    merchant_code VARCHAR(50) UNIQUE NOT NULL,
-   allowed_currency VARCHAR(10) NOT NULL,
+   allowed_currency VARCHAR(255) NOT NULL,
+   merchant_status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (merchant_status IN ('active', 'suspended')),
+   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+   created_by VARCHAR(255),
+   last_updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+   last_updated_by VARCHAR(255),
+   is_active BOOLEAN DEFAULT TRUE
+);
+
+CREATE TABLE api_key (
+   id SERIAL PRIMARY KEY,
+   merchant_id INT NOT NULL REFERENCES merchant(id) ON DELETE CASCADE,
+   name VARCHAR(255),
+   prefix VARCHAR(32) UNIQUE NOT NULL,
+   key_hash VARCHAR(64) NOT NULL,
+   mode VARCHAR(4) NOT NULL CHECK (mode IN ('test', 'live')),
+   last_used_at TIMESTAMP,
+   revoked_at TIMESTAMP,
    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
    created_by VARCHAR(255),
    last_updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -14,6 +31,7 @@ CREATE TABLE customer (
    id SERIAL PRIMARY KEY,
    customer_name VARCHAR(255) NOT NULL,
    customer_email VARCHAR(255) UNIQUE NOT NULL,
+   customer_address TEXT,
    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
    created_by VARCHAR(255),
    last_updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -21,6 +39,25 @@ CREATE TABLE customer (
    is_active BOOLEAN DEFAULT TRUE
 );
 
+CREATE TABLE payment_method (
+  id SERIAL PRIMARY KEY,
+  customer_id INT NOT NULL REFERENCES customer(id) ON DELETE CASCADE,
+  method_type VARCHAR(100) NOT NULL,
+  encrypted_source TEXT NOT NULL,
+  fingerprint VARCHAR(64) NOT NULL,
+  last4 VARCHAR(4) NOT NULL,
+  holder_name VARCHAR(255),
+  expiry VARCHAR(7),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  created_by VARCHAR(255),
+  last_updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  last_updated_by VARCHAR(255),
+  is_active BOOLEAN DEFAULT TRUE
+);
+
+CREATE INDEX idx_payment_method_customer_id ON payment_method (customer_id);
+CREATE INDEX idx_customer_name ON customer (LOWER(customer_name));
+
 CREATE TABLE invoice (
   id SERIAL PRIMARY KEY,
   merchant_id INT NOT NULL REFERENCES merchant(id) ON DELETE CASCADE,
@@ -28,6 +65,8 @@ CREATE TABLE invoice (
   amount NUMERIC(18,2) NOT NULL CHECK (amount > 0),
   currency VARCHAR(10) NOT NULL,
   optional_description TEXT,
+  invoice_status VARCHAR(50) NOT NULL DEFAULT 'PENDING',
+  mode VARCHAR(4) NOT NULL DEFAULT 'live',
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   created_by VARCHAR(255),
   last_updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -44,6 +83,15 @@ CREATE TABLE payment (
   payment_status VARCHAR(50) NOT NULL,
   payment_method VARCHAR(100) NOT NULL,
   payment_source VARCHAR(255),
+  payment_method_id INT REFERENCES payment_method(id) ON DELETE SET NULL,
+  reference_id VARCHAR(36),
+  provider_payment_id VARCHAR(36),
+  source_fingerprint VARCHAR(64),
+  client_ip VARCHAR(45),
+  risk_decision VARCHAR(10),
+  risk_score INT,
+  risk_reasons TEXT,
+  review_note VARCHAR(500),
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   created_by VARCHAR(255),
   last_updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -51,12 +99,111 @@ CREATE TABLE payment (
   is_active BOOLEAN DEFAULT TRUE
 );
 
+CREATE TABLE dispute (
+  id SERIAL PRIMARY KEY,
+  payment_id INT NOT NULL REFERENCES payment(id) ON DELETE CASCADE,
+  invoice_id INT NOT NULL REFERENCES invoice(id) ON DELETE CASCADE,
+  merchant_id INT NOT NULL REFERENCES merchant(id) ON DELETE CASCADE,
+  amount NUMERIC(18,2) NOT NULL CHECK (amount > 0),
+  currency VARCHAR(10) NOT NULL,
+  reason_code VARCHAR(50) NOT NULL,
+  dispute_status VARCHAR(50) NOT NULL,
+  respond_by TIMESTAMP NOT NULL,
+  resolved_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  created_by VARCHAR(255),
+  last_updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  last_updated_by VARCHAR(255),
+  is_active BOOLEAN DEFAULT TRUE
+);
+
+CREATE TABLE dispute_evidence (
+  id SERIAL PRIMARY KEY,
+  dispute_id INT NOT NULL REFERENCES dispute(id) ON DELETE CASCADE,
+  evidence_type VARCHAR(50) NOT NULL,
+  description TEXT,
+  file_name VARCHAR(255) NOT NULL,
+  content_type VARCHAR(100),
+  content BYTEA NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  created_by VARCHAR(255),
+  last_updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  last_updated_by VARCHAR(255),
+  is_active BOOLEAN DEFAULT TRUE
+);
+
+CREATE TABLE ledger_entry (
+  id SERIAL PRIMARY KEY,
+  merchant_id INT NOT NULL REFERENCES merchant(id) ON DELETE CASCADE,
+  payment_id INT NOT NULL REFERENCES payment(id) ON DELETE CASCADE,
+  -- A dispute is charged back at most once
+  dispute_id INT UNIQUE REFERENCES dispute(id) ON DELETE SET NULL,
+  entry_type VARCHAR(50) NOT NULL,
+  amount NUMERIC(18,2) NOT NULL,
+  currency VARCHAR(10) NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  created_by VARCHAR(255),
+  last_updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  last_updated_by VARCHAR(255),
+  is_active BOOLEAN DEFAULT TRUE
+);
+
+-- Append-only, hash-chained record of state changes. Each hash covers the previous one, so
+-- rewriting history breaks the chain (see GET /audit-logs/verify).
+CREATE TABLE audit_log (
+  id SERIAL PRIMARY KEY,
+  entity_type VARCHAR(50) NOT NULL,
+  entity_id INT NOT NULL,
+  action VARCHAR(20) NOT NULL,
+  actor VARCHAR(255),
+  request_id VARCHAR(255),
+  changes TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  prev_hash VARCHAR(64) NOT NULL,
+  hash VARCHAR(64) NOT NULL UNIQUE
+);
+
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_update_delete
+  BEFORE UPDATE OR DELETE ON audit_log
+  FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate
+  BEFORE TRUNCATE ON audit_log
+  FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+CREATE INDEX idx_dispute_payment_id ON dispute (payment_id);
+-- A payment has at most one open dispute
+CREATE UNIQUE INDEX idx_dispute_open_payment_id ON dispute (payment_id)
+  WHERE dispute_status IN ('NEEDS_RESPONSE', 'UNDER_REVIEW');
+CREATE INDEX idx_audit_log_entity ON audit_log (entity_type, entity_id);
+CREATE INDEX idx_audit_log_request_id ON audit_log (request_id);
+CREATE INDEX idx_audit_log_actor ON audit_log (actor);
+-- Keyset pagination for GET /invoices: every listing orders by (sort column, id)
+CREATE INDEX idx_invoice_created_at_id ON invoice (created_at, id);
+CREATE INDEX idx_invoice_amount_id ON invoice (amount, id);
+CREATE INDEX idx_invoice_merchant_created_at ON invoice (merchant_id, created_at, id);
+CREATE INDEX idx_invoice_customer_created_at ON invoice (customer_id, created_at, id);
+CREATE INDEX idx_invoice_status ON invoice (invoice_status);
+CREATE INDEX idx_payment_provider_payment_id ON payment (provider_payment_id);
+CREATE INDEX idx_payment_created_at ON payment (created_at);
+-- Velocity lookups of the risk engine
+CREATE INDEX idx_payment_source_fingerprint_created_at ON payment (source_fingerprint, created_at);
+CREATE INDEX idx_payment_customer_created_at ON payment (customer_id, created_at);
+CREATE INDEX idx_payment_client_ip_created_at ON payment (client_ip, created_at);
+CREATE INDEX idx_payment_pending_review ON payment (id) WHERE payment_status = 'PENDING_REVIEW';
+
 -- inserts for validation purposes
 -- Insert sample merchant
 INSERT INTO merchant (merchant_name, merchant_code, allowed_currency, created_by, last_updated_by)
 VALUES
-    ('Amazon', 'AMZ123', ARRAY['USD', 'EUR'], 'admin', 'admin'),
-    ('eBay', 'EBY456', ARRAY['USD', 'GBP'], 'admin', 'admin');
+    ('Amazon', 'AMZ123', 'USD,EUR', 'admin', 'admin'),
+    ('eBay', 'EBY456', 'USD,GBP', 'admin', 'admin');
 
 -- Insert sample customer
 INSERT INTO customer (customer_name, customer_email, created_by, last_updated_by)